@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/helmcache"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func HelmMirrorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "helm-mirror",
+		Short:         "Serve the local Helm chart cache as a chart repository",
+		Long:          `Serve the Helm charts cached by previous pulls of helm:// upstreams as a read only, ChartMuseum compatible chart repository. Pass the address of the mirror with --repo to pull charts without access to the original repository.`,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			log := logger.NewLogger()
+			log.Initialize()
+
+			cache := helmcache.New(helmcache.DirForRoot(ExpandDir(v.GetString("rootdir"))))
+			charts, err := cache.ListCharts()
+			if err != nil {
+				return errors.Wrap(err, "failed to list cached charts")
+			}
+
+			address := fmt.Sprintf("%s:%d", v.GetString("address"), v.GetInt("port"))
+			log.Info("Serving %d cached charts from %s on http://%s", len(charts), cache.Dir, address)
+
+			if err := http.ListenAndServe(address, helmcache.NewMirrorHandler(cache, log)); err != nil {
+				return errors.Wrap(err, "failed to serve helm mirror")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("rootdir", homeDir(), "root directory that contains the helm cache")
+	cmd.Flags().String("address", "0.0.0.0", "address to listen on")
+	cmd.Flags().Int("port", 8879, "port to listen on")
+
+	return cmd
+}
@@ -33,6 +33,8 @@ func PullCmd() *cobra.Command {
 
 			pullOptions := pull.PullOptions{
 				HelmRepoURI:         v.GetString("repo"),
+				Checksum:            v.GetString("sha256"),
+				VersionConstraint:   v.GetString("version-constraint"),
 				RootDir:             ExpandDir(v.GetString("rootdir")),
 				Namespace:           v.GetString("namespace"),
 				Downstreams:         v.GetStringSlice("downstream"),
@@ -77,6 +79,8 @@ func PullCmd() *cobra.Command {
 
 	cmd.Flags().StringSlice("set", []string{}, "values to pass to helm when running helm template")
 	cmd.Flags().String("repo", "", "repo uri to use when downloading a helm chart")
+	cmd.Flags().String("version-constraint", "", "semver range the upstream version must satisfy, e.g. \"~1.4\" or \">=2.0 <3.0\"")
+	cmd.Flags().String("sha256", "", "expected sha256 checksum of the content downloaded from an http(s) upstream")
 	cmd.Flags().String("rootdir", homeDir(), "root directory that will be used to write the yaml to")
 	cmd.Flags().StringP("namespace", "n", "default", "namespace to render the upstream to in the base")
 	cmd.Flags().StringSlice("downstream", []string{}, "the list of any downstreams to create/update")
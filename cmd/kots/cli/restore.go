@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/auth"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// restoreRequest matches the restore options that the admin console accepts
+type restoreRequest struct {
+	Namespaces       []string          `json:"namespaces,omitempty"`
+	LabelSelector    string            `json:"labelSelector,omitempty"`
+	Resources        []string          `json:"resources,omitempty"`
+	NamespaceMapping map[string]string `json:"namespaceMapping,omitempty"`
+	VolumesOnly      bool              `json:"volumesOnly,omitempty"`
+	KotsadmOnly      bool              `json:"kotsadmOnly,omitempty"`
+}
+
+func RestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "restore [snapshot name]",
+		Short:         "Restore an application or the admin console from a snapshot",
+		Long:          "Restore an application or the admin console from a snapshot, all of it or only the selected resources",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			if len(args) == 0 {
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			namespaceMapping, err := parseNamespaceMappings(v.GetStringSlice("namespace-mappings"))
+			if err != nil {
+				return errors.Wrap(err, "failed to parse namespace mappings")
+			}
+
+			restoreReq := restoreRequest{
+				Namespaces:       v.GetStringSlice("include-namespaces"),
+				LabelSelector:    v.GetString("selector"),
+				Resources:        v.GetStringSlice("include-resources"),
+				NamespaceMapping: namespaceMapping,
+				VolumesOnly:      v.GetBool("volumes-only"),
+				KotsadmOnly:      v.GetBool("kotsadm-only"),
+			}
+
+			log := logger.NewLogger()
+			log.ActionWithSpinner("Starting restore of snapshot %s", args[0])
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			clientset, err := k8sutil.GetClientset(kubernetesConfigFlags)
+			if err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to get clientset")
+			}
+
+			podName, err := k8sutil.FindKotsadm(clientset, v.GetString("namespace"))
+			if err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to find kotsadm pod")
+			}
+
+			localPort, errChan, err := k8sutil.PortForward(kubernetesConfigFlags, 0, 3000, v.GetString("namespace"), podName, false, stopCh, log)
+			if err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to start port forwarding")
+			}
+
+			go func() {
+				select {
+				case err := <-errChan:
+					if err != nil {
+						log.Error(err)
+					}
+				case <-stopCh:
+				}
+			}()
+
+			authSlug, err := auth.GetOrCreateAuthSlug(kubernetesConfigFlags, v.GetString("namespace"))
+			if err != nil {
+				log.FinishSpinnerWithError()
+				log.Info("Unable to authenticate to the Admin Console running in the %s namespace. Ensure you have read access to secrets in this namespace and try again.", v.GetString("namespace"))
+				if v.GetBool("debug") {
+					return errors.Wrap(err, "failed to get kotsadm auth slug")
+				}
+				os.Exit(2) // not returning error here as we don't want to show the entire stack trace to normal users
+			}
+
+			reqBody, err := json.Marshal(restoreReq)
+			if err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to marshal restore request")
+			}
+
+			restoreURI := fmt.Sprintf("http://localhost:%d/api/v1/snapshot/%s/restore", localPort, args[0])
+			newReq, err := http.NewRequest("POST", restoreURI, bytes.NewReader(reqBody))
+			if err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to create restore request")
+			}
+			newReq.Header.Add("Content-Type", "application/json")
+			newReq.Header.Add("Authorization", authSlug)
+			resp, err := http.DefaultClient.Do(newReq)
+			if err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to start restore")
+			}
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to read server response")
+			}
+
+			type restoreResponse struct {
+				Success bool   `json:"success"`
+				Error   string `json:"error"`
+			}
+			rr := restoreResponse{}
+			if err := json.Unmarshal(b, &rr); err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrapf(err, "failed to parse response: %d", resp.StatusCode)
+			}
+
+			if !rr.Success {
+				log.FinishSpinnerWithError()
+				if rr.Error != "" {
+					return errors.Errorf("Failed to restore snapshot %s: %s", args[0], rr.Error)
+				}
+				return errors.Errorf("Unexpected response from the API: %d", resp.StatusCode)
+			}
+
+			log.FinishSpinner()
+
+			log.ActionWithoutSpinner("")
+			if restoreReq.KotsadmOnly {
+				log.ActionWithoutSpinner("The Admin Console is being restored, it will be unavailable until the restore completes")
+			} else {
+				log.ActionWithoutSpinner("The restore has started, follow its progress in the Admin Console")
+			}
+			log.ActionWithoutSpinner("To access the Admin Console, run kubectl kots admin-console --namespace %s", v.GetString("namespace"))
+			log.ActionWithoutSpinner("")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSlice("include-namespaces", nil, "restore only the resources in these namespaces")
+	cmd.Flags().StringP("selector", "l", "", "restore only the resources with matching labels, e.g. \"app=postgres\"")
+	cmd.Flags().StringSlice("include-resources", nil, "restore only these kinds of resources, e.g. \"configmaps,deployments.apps\"")
+	cmd.Flags().StringSlice("namespace-mappings", nil, "restore the resources of a namespace into another one, in the form src1:dst1,src2:dst2, leaving the deployed application in place")
+	cmd.Flags().Bool("volumes-only", false, "restore only the persistent volume claims and their volumes")
+	cmd.Flags().Bool("kotsadm-only", false, "restore only the admin console")
+
+	cmd.Flags().Bool("debug", false, "when set, log full error traces in some cases where we provide a pretty message")
+	cmd.Flags().MarkHidden("debug")
+
+	return cmd
+}
+
+// parseNamespaceMappings parses mappings in the form src:dst
+func parseNamespaceMappings(mappings []string) (map[string]string, error) {
+	if len(mappings) == 0 {
+		return nil, nil
+	}
+
+	namespaceMapping := map[string]string{}
+	for _, mapping := range mappings {
+		parts := strings.Split(mapping, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid namespace mapping %q, expected src:dst", mapping)
+		}
+		if _, ok := namespaceMapping[parts[0]]; ok {
+			return nil, errors.Errorf("namespace %s is mapped more than once", parts[0])
+		}
+		namespaceMapping[parts[0]] = parts[1]
+	}
+
+	return namespaceMapping, nil
+}
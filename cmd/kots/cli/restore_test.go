@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func TestParseNamespaceMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "none",
+			input: nil,
+			want:  nil,
+		},
+		{
+			name:  "two namespaces",
+			input: []string{"app:app-restored", "db:db-restored"},
+			want: map[string]string{
+				"app": "app-restored",
+				"db":  "db-restored",
+			},
+		},
+		{
+			name:    "missing destination",
+			input:   []string{"app:"},
+			wantErr: true,
+		},
+		{
+			name:    "not a mapping",
+			input:   []string{"app"},
+			wantErr: true,
+		},
+		{
+			name:    "mapped twice",
+			input:   []string{"app:a", "app:b"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+			got, err := parseNamespaceMappings(tt.input)
+			if tt.wantErr {
+				req.Error(err)
+				return
+			}
+			req.NoError(err)
+			req.Equal(tt.want, got)
+		})
+	}
+}
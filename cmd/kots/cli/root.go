@@ -29,8 +29,10 @@ func RootCmd() *cobra.Command {
 	cmd.AddCommand(UploadCmd())
 	cmd.AddCommand(DownloadCmd())
 	cmd.AddCommand(UpstreamCmd())
+	cmd.AddCommand(HelmMirrorCmd())
 	cmd.AddCommand(AdminConsoleCmd())
 	cmd.AddCommand(ResetPasswordCmd())
+	cmd.AddCommand(RestoreCmd())
 	cmd.AddCommand(VersionCmd())
 
 	viper.BindPFlags(cmd.Flags())
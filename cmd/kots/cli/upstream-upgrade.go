@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 
@@ -69,9 +70,16 @@ func UpstreamUpgradeCmd() *cobra.Command {
 			}()
 
 			appSlug := args[0]
-			updateCheckURI := fmt.Sprintf("http://localhost:%d/api/v1/app/%s/updatecheck", localPort, appSlug)
+			urlVals := url.Values{}
 			if viper.GetBool("deploy") {
-				updateCheckURI = fmt.Sprintf("%s?deploy=true", updateCheckURI)
+				urlVals.Set("deploy", "true")
+			}
+			if constraint := v.GetString("version-constraint"); constraint != "" {
+				urlVals.Set("versionConstraint", constraint)
+			}
+			updateCheckURI := fmt.Sprintf("http://localhost:%d/api/v1/app/%s/updatecheck", localPort, appSlug)
+			if len(urlVals) > 0 {
+				updateCheckURI = fmt.Sprintf("%s?%s", updateCheckURI, urlVals.Encode())
 			}
 
 			authSlug, err := auth.GetOrCreateAuthSlug(kubernetesConfigFlags, v.GetString("namespace"))
@@ -154,6 +162,7 @@ func UpstreamUpgradeCmd() *cobra.Command {
 	}
 
 	cmd.Flags().Bool("deploy", false, "when set, automatically deploy the latest version downloads")
+	cmd.Flags().String("version-constraint", "", "only download updates with a semver version in this range, e.g. \"~1.4\" or \">=2.0 <3.0\"")
 
 	cmd.Flags().Bool("debug", false, "when set, log full error traces in some cases where we provide a pretty message")
 	cmd.Flags().MarkHidden("debug")
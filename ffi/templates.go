@@ -84,8 +84,9 @@ func RenderFile(socket string, filePath string, archivePath string, registryJson
 		if values != nil {
 			for k, v := range values.Spec.Values {
 				templateContextValues[k] = template.ItemValue{
-					Value:   v.Value,
-					Default: v.Default,
+					Value:      v.Value,
+					Default:    v.Default,
+					MultiValue: v.MultiValue,
 				}
 			}
 		}
@@ -36,6 +36,11 @@ export class DeployAPI {
         stderr: body.apply_stderr,
         stdout: body.apply_stdout,
       },
+      dryRunResults: body.dryrun_results,
+      applyResults: body.apply_results,
+      rolledBack: !!body.rolled_back,
+      rollbackReason: body.rollback_reason,
+      canaryResult: body.canary,
     };
 
     // TODO: what is this next line used for?
@@ -96,6 +101,7 @@ export class DeployAPI {
           const applicationManifests = {
             "app_id": app.id,
             kubectl_version: kotsAppSpec ? kotsAppSpec.kubectlVersion : "",
+            applier: kotsAppSpec ? kotsAppSpec.applier : "",
             namespace: desiredNamespace,
             manifests: b.toString("base64"),
           };
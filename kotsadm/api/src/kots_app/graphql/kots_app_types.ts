@@ -22,6 +22,7 @@ const KotsApp = `
     allowSnapshots: Boolean
     licenseType: String
     updateCheckerSpec: String
+    updateCheckerVersionConstraint: String
   }
 `;
 
@@ -80,6 +81,14 @@ const InstallationYamlError = `
   }
 `
 
+const PreUpgradeSnapshot = `
+  type PreUpgradeSnapshot {
+    backupName: String
+    status: String!
+    error: String
+  }
+`
+
 const KotsVersion = `
   type KotsVersion {
     title: String!
@@ -96,6 +105,7 @@ const KotsVersion = `
     commitUrl: String
     gitDeployable: Boolean
     yamlErrors: [InstallationYamlError]
+    preUpgradeSnapshot: PreUpgradeSnapshot
   }
 `;
 
@@ -248,6 +258,11 @@ const KotsDownstreamOutput = `
     dryrunStderr: String
     applyStdout: String
     applyStderr: String
+    dryrunResults: String
+    applyResults: String
+    rolledBack: Boolean
+    rollbackReason: String
+    canaryResult: String
     renderError: String
   }
 `;
@@ -283,6 +298,7 @@ export default [
   KotsAppLink,
   KotsDownstream,
   KotsVersion,
+  PreUpgradeSnapshot,
   KotsAppVersion,
   InstallationYamlError,
   KotsDownstreamOutput,
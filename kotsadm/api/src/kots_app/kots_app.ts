@@ -36,10 +36,10 @@ export class KotsApp {
   hasPreflight: boolean;
   isConfigurable: boolean;
   snapshotTTL?: string;
-  snapshotSchedule?: string;
   restoreInProgressName?: string;
   restoreUndeployStatus?: string;
   updateCheckerSpec?: string;
+  updateCheckerVersionConstraint?: string;
 
   // Version Methods
   public async getCurrentAppVersion(stores: Stores): Promise<KotsAppVersion | undefined> {
@@ -611,6 +611,14 @@ export interface KotsVersion {
   commitUrl?: string;
   gitDeployable?: boolean;
   yamlErrors?: InstallationYAMLError[];
+  preUpgradeSnapshot?: PreUpgradeSnapshot;
+}
+
+// the snapshot of the deployed version that was taken before this version was deployed
+export interface PreUpgradeSnapshot {
+  backupName: string;
+  status: string;
+  error: string;
 }
 
 export interface AppRegistryDetails {
@@ -672,6 +680,14 @@ export interface KotsDownstreamOutput {
   dryrunStderr: string;
   applyStdout: string;
   applyStderr: string;
+  // JSON encoded per object results, only set when the app uses the server-side applier
+  dryrunResults: string;
+  applyResults: string;
+  // the operator re-applied the previous version because this one failed
+  rolledBack: boolean;
+  rollbackReason: string;
+  // JSON encoded result of the canary that ran before the deploy
+  canaryResult: string;
   renderError: string | null;
 }
 
@@ -8,10 +8,17 @@ export interface ApplicationSpec {
   statusInformers?: string[];
   graphs?: MetricGraph[];
   kubectlVersion?: string;
+  applier?: string;
+  autoRollback?: AutoRollback;
   kustomizeVersion?: string;
   additionalNamespaces?: string[];
 }
 
+export interface AutoRollback {
+  enabled?: boolean;
+  readyTimeout?: string;
+}
+
 export interface ApplicationPort {
   serviceName: string;
   servicePort: number;
@@ -7,8 +7,10 @@ import _ from "lodash";
 export class KotsAppStatusStore {
   constructor(private readonly pool: pg.Pool, private readonly params: Params) {}
 
+  // the app's status combines its resources in every cluster it is deployed to
   async getKotsAppStatus(appId: string): Promise<KotsAppStatus> {
-    const q = `select resource_states, updated_at from app_status where app_id = $1`;
+    const q = `select resource_states, updated_at from app_status
+      where app_id = $1 and cluster_id in (select cluster_id from app_downstream where app_id = $1)`;
     const v = [
       appId,
     ];
@@ -21,19 +23,20 @@ export class KotsAppStatusStore {
 
     const kotsAppStatus = new KotsAppStatus();
     kotsAppStatus.appId = appId;
-    kotsAppStatus.updatedAt = result.rows[0].updated_at;
-    kotsAppStatus.resourceStates = JSON.parse(result.rows[0].resource_states);
+    kotsAppStatus.updatedAt = _.max(_.map(result.rows, "updated_at"));
+    kotsAppStatus.resourceStates = _.flatMap(result.rows, row => JSON.parse(row.resource_states));
 
     return kotsAppStatus;
   }
 
-  async setKotsAppStatus(appId: string, resourceStates: any, updatedAt: Date): Promise<void> {
+  async setKotsAppStatus(appId: string, clusterId: string, resourceStates: any, updatedAt: Date): Promise<void> {
     const q = `
-    insert into app_status (app_id, resource_states, updated_at) values ($1, $2, $3)
-    on conflict (app_id) do update set resource_states = $2, updated_at = $3
+    insert into app_status (app_id, cluster_id, resource_states, updated_at) values ($1, $2, $3, $4)
+    on conflict (app_id, cluster_id) do update set resource_states = $3, updated_at = $4
     `;
     const v = [
       appId,
+      clusterId,
       JSON.stringify(resourceStates),
       updatedAt,
     ];
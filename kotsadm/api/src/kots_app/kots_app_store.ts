@@ -1,6 +1,6 @@
 import pg from "pg";
 import { Params } from "../server/params";
-import { KotsApp, KotsVersion, KotsAppRegistryDetails, KotsDownstreamOutput, ConfigData } from "./";
+import { KotsApp, KotsVersion, PreUpgradeSnapshot, KotsAppRegistryDetails, KotsDownstreamOutput, ConfigData } from "./";
 import { ReplicatedError } from "../server/errors";
 import randomstring from "randomstring";
 import slugify from "slugify";
@@ -464,9 +464,11 @@ export class KotsAppStore {
       return;
     }
 
-    q = `insert into app_downstream_output (app_id, cluster_id, downstream_sequence, is_error, dryrun_stdout, dryrun_stderr, apply_stdout, apply_stderr)
-      values ($1, $2, $3, $4, $5, $6, $7, $8) on conflict (app_id, cluster_id, downstream_sequence) do update set is_error = EXCLUDED.is_error,
-        dryrun_stdout = EXCLUDED.dryrun_stdout, dryrun_stderr = EXCLUDED.dryrun_stderr, apply_stdout = EXCLUDED.apply_stdout, apply_stderr = EXCLUDED.apply_stderr`;
+    q = `insert into app_downstream_output (app_id, cluster_id, downstream_sequence, is_error, dryrun_stdout, dryrun_stderr, apply_stdout, apply_stderr, dryrun_results, apply_results, rolled_back, rollback_reason, canary_result)
+      values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) on conflict (app_id, cluster_id, downstream_sequence) do update set is_error = EXCLUDED.is_error,
+        dryrun_stdout = EXCLUDED.dryrun_stdout, dryrun_stderr = EXCLUDED.dryrun_stderr, apply_stdout = EXCLUDED.apply_stdout, apply_stderr = EXCLUDED.apply_stderr,
+        dryrun_results = EXCLUDED.dryrun_results, apply_results = EXCLUDED.apply_results, rolled_back = EXCLUDED.rolled_back, rollback_reason = EXCLUDED.rollback_reason,
+        canary_result = EXCLUDED.canary_result`;
     v = [
       appId,
       clusterId,
@@ -476,6 +478,11 @@ export class KotsAppStore {
       output.dryRun.stderr,
       output.apply.stdout,
       output.apply.stderr,
+      output.dryRunResults ? JSON.stringify(output.dryRunResults) : null,
+      output.applyResults ? JSON.stringify(output.applyResults) : null,
+      !!output.rolledBack,
+      output.rollbackReason || null,
+      output.canaryResult ? JSON.stringify(output.canaryResult) : null,
     ];
 
     await this.pool.query(q, v);
@@ -483,7 +490,7 @@ export class KotsAppStore {
 
   async getDownstreamOutput(appId: string, clusterId: string, sequence: number): Promise<KotsDownstreamOutput> {
     const q = `
-      select adv.status, adv.status_info, ado.dryrun_stdout, ado.dryrun_stderr, ado.apply_stdout, ado.apply_stderr
+      select adv.status, adv.status_info, ado.dryrun_stdout, ado.dryrun_stderr, ado.apply_stdout, ado.apply_stderr, ado.dryrun_results, ado.apply_results, ado.rolled_back, ado.rollback_reason, ado.canary_result
       from app_downstream_version adv LEFT JOIN app_downstream_output ado
         ON adv.app_id = ado.app_id AND adv.cluster_id = ado.cluster_id AND adv.sequence = ado.downstream_sequence
       where adv.app_id = $1 and adv.cluster_id = $2 and adv.sequence = $3
@@ -501,6 +508,11 @@ export class KotsAppStore {
         dryrunStderr: "",
         applyStdout: "",
         applyStderr: "",
+        dryrunResults: "",
+        applyResults: "",
+        rolledBack: false,
+        rollbackReason: "",
+        canaryResult: "",
         renderError: ""
       };
     };
@@ -519,6 +531,11 @@ export class KotsAppStore {
       dryrunStderr: base64Decode(row.dryrun_stderr),
       applyStdout: base64Decode(row.apply_stdout),
       applyStderr: base64Decode(row.apply_stderr),
+      dryrunResults: row.dryrun_results || "",
+      applyResults: row.apply_results || "",
+      rolledBack: !!row.rolled_back,
+      rollbackReason: row.rollback_reason || "",
+      canaryResult: row.canary_result || "",
       renderError: renderError,
     };
   }
@@ -711,13 +728,20 @@ export class KotsAppStore {
          adv.git_commit_url,
          adv.git_deployable,
          ado.is_error AS has_error,
-         av.kots_installation_spec
+         av.kots_installation_spec,
+         pus.backup_name AS pre_upgrade_backup_name,
+         pus.status AS pre_upgrade_snapshot_status,
+         pus.error AS pre_upgrade_snapshot_error
         FROM
           app_downstream_version AS adv
         LEFT JOIN
           app_version AS av
         ON
           adv.app_id = av.app_id AND adv.sequence = av.sequence
+        LEFT JOIN
+          pre_upgrade_snapshot AS pus
+        ON
+          adv.app_id = pus.app_id AND adv.sequence = pus.sequence
         LEFT JOIN
           app_downstream_output AS ado
         ON
@@ -754,7 +778,8 @@ export class KotsAppStore {
         preflightResult: row.preflight_result,
         preflightResultCreatedAt: row.preflight_result_created_at,
         commitUrl: row.git_commit_url || "",
-        gitDeployable: row.git_deployable
+        gitDeployable: row.git_deployable,
+        preUpgradeSnapshot: this.mapPreUpgradeSnapshot(row),
       };
       if (row.kots_installation_spec) {
         try {
@@ -770,6 +795,17 @@ export class KotsAppStore {
     return versionItems;
   }
 
+  private mapPreUpgradeSnapshot(row: any): PreUpgradeSnapshot | undefined {
+    if (!row.pre_upgrade_snapshot_status) {
+      return;
+    }
+    return {
+      backupName: row.pre_upgrade_backup_name || "",
+      status: row.pre_upgrade_snapshot_status,
+      error: row.pre_upgrade_snapshot_error || "",
+    };
+  }
+
   async listPendingVersions(appId: string, clusterId: string): Promise<KotsVersion[]> {
     let q = `select current_sequence from app_downstream where app_id = $1 and cluster_id = $2`;
     let v = [
@@ -790,9 +826,10 @@ export class KotsAppStore {
 
     q = `select adv.created_at, adv.version_label, adv.status, adv.sequence, adv.parent_sequence,
 adv.applied_at, adv.source, adv.diff_summary, adv.preflight_result, adv.preflight_result_created_at, adv.git_commit_url, adv.git_deployable,
-av.kots_installation_spec
+av.kots_installation_spec, pus.backup_name as pre_upgrade_backup_name, pus.status as pre_upgrade_snapshot_status, pus.error as pre_upgrade_snapshot_error
 from app_downstream_version as adv
 left join app_version as av on adv.app_id = av.app_id and adv.sequence = av.sequence
+left join pre_upgrade_snapshot as pus on adv.app_id = pus.app_id and adv.sequence = pus.sequence
 where adv.app_id = $1 and adv.cluster_id = $3 and adv.sequence > $2
 order by adv.sequence desc`;
 
@@ -821,7 +858,8 @@ order by adv.sequence desc`;
         preflightResult: row.preflight_result,
         preflightResultCreatedAt: row.preflight_result_created_at,
         commitUrl: row.git_commit_url || "",
-        gitDeployable: row.git_deployable
+        gitDeployable: row.git_deployable,
+        preUpgradeSnapshot: this.mapPreUpgradeSnapshot(row),
       };
       if (row.kots_installation_spec) {
         try {
@@ -955,11 +993,12 @@ order by adv.sequence desc`;
     q = `select adv.created_at, adv.version_label, adv.status, adv.sequence,
 adv.parent_sequence, adv.applied_at, adv.source, adv.diff_summary, adv.preflight_result,
 adv.preflight_result_created_at, adv.git_commit_url, adv.git_deployable, ado.is_error AS has_error,
-av.kots_installation_spec
+av.kots_installation_spec, pus.backup_name as pre_upgrade_backup_name, pus.status as pre_upgrade_snapshot_status, pus.error as pre_upgrade_snapshot_error
 from app_downstream_version as adv
 left join app_version as av on adv.app_id = av.app_id and adv.sequence = av.sequence
 left join app_downstream_output as ado
 on adv.app_id = ado.app_id and adv.cluster_id = ado.cluster_id and adv.sequence = ado.downstream_sequence
+left join pre_upgrade_snapshot as pus on adv.app_id = pus.app_id and adv.sequence = pus.sequence
 where adv.app_id = $1 and adv.cluster_id = $3 and adv.sequence = $2
 order by adv.sequence desc`;
 
@@ -991,7 +1030,8 @@ order by adv.sequence desc`;
       preflightResult: row.preflight_result,
       preflightResultCreatedAt: row.preflight_result_created_at,
       commitUrl: row.git_commit_url || "",
-      gitDeployable: row.git_deployable
+      gitDeployable: row.git_deployable,
+      preUpgradeSnapshot: this.mapPreUpgradeSnapshot(row),
     };
     if (row.kots_installation_spec) {
       try {
@@ -1232,7 +1272,14 @@ where app_id = $1 and sequence = $2`;
     return true;
   }
 
-  async deployVersion(appId: string, sequence: number): Promise<void> {
+  // restored versions are redeployed with skipPreUpgradeSnapshot, there is nothing to back up before them
+  async deployVersion(appId: string, sequence: number, skipPreUpgradeSnapshot: boolean = false): Promise<void> {
+    if (skipPreUpgradeSnapshot) {
+      await this.pool.query(`delete from pre_upgrade_snapshot where app_id = $1 and sequence = $2`, [appId, sequence]);
+    } else {
+      await this.recordPendingPreUpgradeSnapshot(appId, sequence);
+    }
+
     const q = `update app_downstream set current_sequence = $1 where app_id = $2`;
     const v = [
       sequence,
@@ -1253,6 +1300,34 @@ where app_id = $1 and sequence = $2`;
     await this.pool.query(qq, vv);
   }
 
+  // the operator takes the snapshot, or removes the record, before the version is deployed.
+  // deploying a version whose snapshot failed again retries the snapshot.
+  async recordPendingPreUpgradeSnapshot(appId: string, sequence: number): Promise<void> {
+    const q = `select max(current_sequence) as deployed_sequence from app_downstream where app_id = $1`;
+    const result = await this.pool.query(q, [appId]);
+    const deployedSequence = result.rows[0].deployed_sequence;
+
+    if (deployedSequence !== null && deployedSequence !== sequence) {
+      const qq = `insert into pre_upgrade_snapshot (app_id, sequence, previous_sequence, status, started_at)
+        values ($1, $2, $3, 'pending', $4)
+        on conflict (app_id, sequence) do update set
+          previous_sequence = EXCLUDED.previous_sequence,
+          backup_name = null,
+          status = EXCLUDED.status,
+          error = null,
+          on_failure = null,
+          started_at = EXCLUDED.started_at,
+          deadline = null,
+          finished_at = null`;
+      await this.pool.query(qq, [appId, sequence, deployedSequence, new Date()]);
+      return;
+    }
+
+    const qq = `update pre_upgrade_snapshot set backup_name = null, status = 'pending', error = null, on_failure = null, started_at = $3, deadline = null, finished_at = null
+      where app_id = $1 and sequence = $2 and status = 'failed'`;
+    await this.pool.query(qq, [appId, sequence, new Date()]);
+  }
+
   async getAppRegistryDetails(appId: string, maskPassword?: boolean): Promise<KotsAppRegistryDetails> {
     const q = `select registry_hostname, registry_username, registry_password, registry_password_enc, namespace, last_registry_sync from app where id = $1`;
     const v = [
@@ -1453,7 +1528,7 @@ where app_id = $1 and sequence = $2`;
   }
 
   async getApp(id: string): Promise<KotsApp> {
-    const q = `select id, name, license, upstream_uri, icon_uri, created_at, updated_at, slug, current_sequence, last_update_check_at, is_airgap, snapshot_ttl_new, snapshot_schedule, restore_in_progress_name, restore_undeploy_status, update_checker_spec from app where id = $1`;
+    const q = `select id, name, license, upstream_uri, icon_uri, created_at, updated_at, slug, current_sequence, last_update_check_at, is_airgap, snapshot_ttl_new, restore_in_progress_name, restore_undeploy_status, update_checker_spec, update_checker_version_constraint from app where id = $1`;
     const v = [id];
 
     const result = await this.pool.query(q, v);
@@ -1490,10 +1565,10 @@ where app_id = $1 and sequence = $2`;
     kotsApp.hasPreflight = !!rr.rows[0] && !!rr.rows[0].preflight_spec;
     kotsApp.isConfigurable = !!rr.rows[0] && !!rr.rows[0].config_spec;
     kotsApp.snapshotTTL = row.snapshot_ttl_new;
-    kotsApp.snapshotSchedule = row.snapshot_schedule;
     kotsApp.restoreInProgressName = row.restore_in_progress_name;
     kotsApp.restoreUndeployStatus = row.restore_undeploy_status;
     kotsApp.updateCheckerSpec = row.update_checker_spec;
+    kotsApp.updateCheckerVersionConstraint = row.update_checker_version_constraint;
 
     return kotsApp;
   }
@@ -1683,12 +1758,6 @@ where app_id = $1 and sequence = $2`;
     await this.pool.query(q, v);
   }
 
-  async updateAppSnapshotSchedule(appId: string, snapshotSchedule: string|null): Promise<void> {
-    const q = `update app set snapshot_schedule = $1 where id = $2`;
-    const v = [snapshotSchedule, appId];
-    await this.pool.query(q, v);
-  }
-
   async updateAppRestoreInProgressName(appId: string, restoreInProgressName: string): Promise<void> {
     const q = `update app set restore_in_progress_name = $1 where id = $2`;
     const v = [restoreInProgressName, appId];
@@ -1702,7 +1771,7 @@ where app_id = $1 and sequence = $2`;
   }
 
   async updateAppRestoreReset(appId): Promise<void> {
-    const q = `update app set restore_in_progress_name = NULL, restore_undeploy_status = '' where id = $1`;
+    const q = `update app set restore_in_progress_name = NULL, restore_undeploy_status = '', restore_options = NULL where id = $1`;
     const v = [appId];
     await this.pool.query(q, v);
   }
@@ -31,7 +31,7 @@ type Mutation {
   setPrometheusAddress(value: String!): Boolean
   deletePrometheusAddress: Boolean
 
-  saveSnapshotConfig(appId: String!, inputValue: Int!, inputTimeUnit: String!, schedule: String!, autoEnabled: Boolean!): Boolean
+  saveSnapshotConfig(appId: String!, inputValue: Int!, inputTimeUnit: String!): Boolean
   deleteSnapshot(snapshotName: String!): Boolean
   cancelRestore(appId: String!): Boolean
 }
@@ -6,7 +6,6 @@ import { NextFunction, Request, Response } from "express";
 import path from "path";
 import Sigsci from "sigsci-module-nodejs";
 import { ServerLoader, ServerSettings } from "@tsed/common";
-import "@tsed/socketio";
 import { $log } from "ts-log-debug";
 import { createBugsnagClient } from "./bugsnagClient";
 import { ShipClusterSchema } from "../schema";
@@ -35,14 +34,12 @@ import { ReplicatedError } from "./errors";
 import { MetricStore } from "../monitoring/metric_store";
 import { ParamsStore } from "../params/params_store";
 import { ensureBucket } from "../util/s3";
-import { SnapshotScheduler } from "../snapshots/schedule";
 
 let mount = {
   "/": "${rootDir}/../controllers/{*.*s,!(ship)/*.*s}"
 };
 let componentsScan = [
   "${rootDir}/../middlewares/**/*.ts",
-  "${rootDir}/../sockets/kots/*.ts",
 ];
 
 @ServerSettings({
@@ -58,7 +55,6 @@ let componentsScan = [
   multer: {
     dest: "${rootDir}/uploads"
   },
-  socketIO: {},
 })
 export class Server extends ServerLoader {
   async $onMountingMiddlewares(): Promise<void> {
@@ -143,9 +139,6 @@ export class Server extends ServerLoader {
       await stores.userStore.createAdminConsolePassword(process.env["SHARED_PASSWORD_BCRYPT"]!);
     }
 
-    const scheduler = new SnapshotScheduler(stores, pool);
-    scheduler.run();
-
     const setContext = async (req: Request, res: Response, next: NextFunction) => {
       let token = req.get("Authorization") || "";
 
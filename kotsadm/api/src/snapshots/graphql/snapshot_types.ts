@@ -1,7 +1,5 @@
 const SnapshotConfig = `
   type SnapshotConfig {
-    autoEnabled: Boolean
-    autoSchedule: SnapshotSchedule
     ttl: SnapshotTTl
   }
 `;
@@ -12,12 +10,6 @@ const SnapshotSettings = `
   }
 `;
 
-const SnapshotSchedule = `
-  type SnapshotSchedule {
-    schedule: String
-  }
-`;
-
 const SnapshotTTl = `
   type SnapshotTTl {
     inputValue: String
@@ -171,7 +163,6 @@ export default [
   SnapshotConfig,
   SnapshotSettings,
   SnapshotStore,
-  SnapshotSchedule,
   SnapshotTTl,
   SnapshotStoreS3AWS,
   SnapshotStoreS3Compatible,
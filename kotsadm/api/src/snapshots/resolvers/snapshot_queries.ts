@@ -44,8 +44,6 @@ export function SnapshotQueries(stores: Stores, params: Params) {
       }
 
       return {
-        autoEnabled: !!app.snapshotSchedule,
-        autoSchedule: app.snapshotSchedule ? { schedule: app.snapshotSchedule } : { schedule: "0 0 * * MON" },
         ttl
       };
     },
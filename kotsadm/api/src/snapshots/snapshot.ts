@@ -98,11 +98,3 @@ export interface RestoreVolume {
   started? : string;
   finished?: string;
 }
-
-export interface ScheduledSnapshot {
-  id: string;
-  appId: string;
-  scheduledTimestamp: string;
-  // name of Backup CR will be set once scheduled
-  backupName?: string;
-}
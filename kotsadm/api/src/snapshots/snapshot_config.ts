@@ -1,6 +1,4 @@
 export interface SnapshotConfig {
-  autoEnabled: boolean;
-  autoSchedule: SnapshotSchedule | null;
   ttl: SnapshotTTl;
 }
 
@@ -8,10 +6,6 @@ export interface SnapshotSettings {
   store: SnapshotStore|null;
 }
 
-export interface SnapshotSchedule {
-  schedule: string;
-}
-
 export interface SnapshotTTl {
   inputValue: string;
   inputTimeUnit: string;
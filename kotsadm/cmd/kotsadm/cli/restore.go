@@ -6,6 +6,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot"
+	snapshottypes "github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
 	"github.com/replicatedhq/kots/pkg/kotsadm"
 	kotsadmtypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
 	"github.com/spf13/cobra"
@@ -34,7 +35,10 @@ func RestoreCmd() *cobra.Command {
 				return errors.Wrap(err, "failed to delete restore")
 			}
 
-			if err := snapshot.CreateRestore(args[0]); err != nil {
+			options := snapshottypes.RestoreOptions{
+				KotsadmOnly: viper.GetBool("kotsadm-only"),
+			}
+			if err := snapshot.CreateRestore(args[0], options); err != nil {
 				return errors.Wrap(err, "failed to create restore")
 			}
 
@@ -42,6 +46,8 @@ func RestoreCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Bool("kotsadm-only", false, "restore only the admin console from a snapshot that includes other resources")
+
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 
 	return cmd
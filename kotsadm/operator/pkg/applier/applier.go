@@ -0,0 +1,87 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Applier applies and removes the manifests of an app
+type Applier interface {
+	Apply(targetNamespace string, slug string, yamlDoc []byte, dryRun bool, wait bool, annotateSlug bool) (*Result, error)
+	Remove(targetNamespace string, yamlDoc []byte, wait bool) (*Result, error)
+}
+
+// Result is the output of an apply or remove. Objects is only set by appliers that
+// report on each object, Stdout and Stderr are always set for display.
+type Result struct {
+	Stdout  []byte
+	Stderr  []byte
+	Objects []ObjectResult
+}
+
+type ObjectResult struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Name       string          `json:"name"`
+	Action     string          `json:"action,omitempty"`
+	DryRun     bool            `json:"dryRun,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Conflicts  []FieldConflict `json:"conflicts,omitempty"`
+}
+
+// FieldConflict is a field that is owned by another field manager
+type FieldConflict struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+const (
+	ActionCreated    = "created"
+	ActionConfigured = "configured"
+	ActionUnchanged  = "unchanged"
+	ActionDeleted    = "deleted"
+)
+
+// String formats the result the same way kubectl does, "deployment.apps/name configured"
+func (r ObjectResult) String() string {
+	resource := strings.ToLower(r.Kind)
+	if group := strings.Split(r.APIVersion, "/"); len(group) == 2 {
+		resource = fmt.Sprintf("%s.%s", resource, group[0])
+	}
+
+	if r.Error != "" {
+		return fmt.Sprintf("%s/%s: %s", resource, r.Name, r.Error)
+	}
+
+	action := r.Action
+	if r.DryRun {
+		action = fmt.Sprintf("%s (server dry run)", action)
+	}
+	return fmt.Sprintf("%s/%s %s", resource, r.Name, action)
+}
+
+func newObjectsResult(objects []ObjectResult) *Result {
+	var stdout, stderr []string
+	for _, object := range objects {
+		if object.Error != "" {
+			stderr = append(stderr, object.String())
+			for _, conflict := range object.Conflicts {
+				stderr = append(stderr, fmt.Sprintf("  conflict: %s", conflict.Message))
+			}
+		} else {
+			stdout = append(stdout, object.String())
+		}
+	}
+
+	result := &Result{
+		Objects: objects,
+	}
+	if len(stdout) > 0 {
+		result.Stdout = []byte(strings.Join(stdout, "\n") + "\n")
+	}
+	if len(stderr) > 0 {
+		result.Stderr = []byte(strings.Join(stderr, "\n") + "\n")
+	}
+	return result
+}
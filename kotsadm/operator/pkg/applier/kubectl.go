@@ -96,7 +96,7 @@ func (c *Kubectl) Preflight(preflightURI string, ignorePermissions bool) error {
 	return nil
 }
 
-func (c *Kubectl) Remove(targetNamespace string, yamlDoc []byte, wait bool) ([]byte, []byte, error) {
+func (c *Kubectl) Remove(targetNamespace string, yamlDoc []byte, wait bool) (*Result, error) {
 	args := []string{
 		"delete",
 		fmt.Sprintf("--wait=%t", wait),
@@ -118,13 +118,13 @@ func (c *Kubectl) Remove(targetNamespace string, yamlDoc []byte, wait bool) ([]b
 	cmd.Stdin = bytes.NewReader(yamlDoc)
 
 	stdout, stderr, err := Run(cmd)
-	return stdout, stderr, errors.Wrap(err, "failed to run kubectl delete")
+	return &Result{Stdout: stdout, Stderr: stderr}, errors.Wrap(err, "failed to run kubectl delete")
 }
 
-func (c *Kubectl) Apply(targetNamespace string, slug string, yamlDoc []byte, dryRun bool, wait bool, annotateSlug bool) ([]byte, []byte, error) {
+func (c *Kubectl) Apply(targetNamespace string, slug string, yamlDoc []byte, dryRun bool, wait bool, annotateSlug bool) (*Result, error) {
 	tmp, err := ioutil.TempDir("", "")
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to create temp directory")
+		return nil, errors.Wrap(err, "failed to create temp directory")
 	}
 	defer os.Remove(tmp)
 
@@ -150,7 +150,7 @@ func (c *Kubectl) Apply(targetNamespace string, slug string, yamlDoc []byte, dry
 
 	yamlPath := filepath.Join(tmp, "doc.yaml")
 	if err := ioutil.WriteFile(yamlPath, yamlDoc, 0644); err != nil {
-		return nil, nil, errors.Wrapf(err, "failed to write %s", yamlPath)
+		return nil, errors.Wrapf(err, "failed to write %s", yamlPath)
 	}
 
 	kustomizationPath := filepath.Join(tmp, "kustomization.yaml")
@@ -168,13 +168,13 @@ commonAnnotations:
 `, slug)
 	}
 	if err := ioutil.WriteFile(kustomizationPath, []byte(kustomizationYaml), 0644); err != nil {
-		return nil, nil, errors.Wrapf(err, "failed to write %s", kustomizationPath)
+		return nil, errors.Wrapf(err, "failed to write %s", kustomizationPath)
 	}
 
 	cmd := c.kubectlCommand(args...)
 
 	stdout, stderr, err := Run(cmd)
-	return stdout, stderr, errors.Wrap(err, "failed to run kubectl apply")
+	return &Result{Stdout: stdout, Stderr: stderr}, errors.Wrap(err, "failed to run kubectl apply")
 }
 
 func (c *Kubectl) kubectlCommand(args ...string) *exec.Cmd {
@@ -0,0 +1,330 @@
+package applier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+const (
+	// ServerSideName selects this applier in the kots Application spec
+	ServerSideName = "server-side"
+
+	// FieldManager is the field manager of all fields applied by kots
+	FieldManager = "kots"
+
+	applyWaitTimeout  = 5 * time.Minute
+	removeWaitTimeout = 5 * time.Minute
+)
+
+// ServerSide applies manifests with server-side apply using the dynamic client, without kubectl
+type ServerSide struct {
+	// Force takes over fields that are owned by another field manager instead of reporting conflicts
+	Force bool
+
+	dynamicClient    dynamic.Interface
+	mapper           meta.ResettableRESTMapper
+	defaultNamespace string
+}
+
+func NewServerSide(config *rest.Config, defaultNamespace string) (*ServerSide, error) {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create discovery client")
+	}
+
+	return &ServerSide{
+		dynamicClient:    dynamicClient,
+		mapper:           restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		defaultNamespace: defaultNamespace,
+	}, nil
+}
+
+// Apply applies each object in yamlDoc. Objects that fail do not stop the others from being applied.
+// Unless Force is set, fields owned by another field manager are not taken over, and are reported as conflicts.
+// When wait is true, it returns once the controllers have observed the applied objects.
+func (s *ServerSide) Apply(targetNamespace string, slug string, yamlDoc []byte, dryRun bool, wait bool, annotateSlug bool) (*Result, error) {
+	objs, err := DecodeObjects(yamlDoc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode manifests")
+	}
+
+	// CRDs applied in an earlier call may not be in the cached discovery yet
+	s.mapper.Reset()
+
+	hasErr := false
+	results := []ObjectResult{}
+	for _, obj := range objs {
+		if annotateSlug {
+			annotations := obj.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations["kots.io/app-slug"] = slug
+			obj.SetAnnotations(annotations)
+		}
+
+		result := s.applyObject(obj, targetNamespace, dryRun, wait && !dryRun)
+		if result.Error != "" {
+			hasErr = true
+		}
+		results = append(results, result)
+	}
+
+	if hasErr {
+		return newObjectsResult(results), errors.New("failed to apply one or more objects")
+	}
+	return newObjectsResult(results), nil
+}
+
+// Remove deletes each object in yamlDoc. When wait is true, it returns once the objects are gone.
+func (s *ServerSide) Remove(targetNamespace string, yamlDoc []byte, wait bool) (*Result, error) {
+	objs, err := DecodeObjects(yamlDoc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode manifests")
+	}
+
+	s.mapper.Reset()
+
+	hasErr := false
+	results := []ObjectResult{}
+	for _, obj := range objs {
+		result := s.removeObject(obj, targetNamespace, wait)
+		if result.Error != "" {
+			hasErr = true
+		}
+		results = append(results, result)
+	}
+
+	if hasErr {
+		return newObjectsResult(results), errors.New("failed to delete one or more objects")
+	}
+	return newObjectsResult(results), nil
+}
+
+func (s *ServerSide) applyObject(obj *unstructured.Unstructured, targetNamespace string, dryRun bool, waitForApply bool) ObjectResult {
+	result := newObjectResult(obj)
+	result.DryRun = dryRun
+
+	resource, namespace, err := s.resourceFor(obj, targetNamespace)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Namespace = namespace
+
+	// server-side apply doesn't say if anything changed, so compare resource versions
+	previousVersion := ""
+	existing, err := resource.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		result.Error = errors.Wrap(err, "failed to get object").Error()
+		return result
+	} else if err == nil {
+		previousVersion = existing.GetResourceVersion()
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		result.Error = errors.Wrap(err, "failed to marshal object").Error()
+		return result
+	}
+
+	force := s.Force
+	opts := metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+	}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	applied, err := resource.Patch(context.TODO(), obj.GetName(), types.ApplyPatchType, data, opts)
+	if err != nil {
+		result.Error = err.Error()
+		result.Conflicts = conflictsFromError(err)
+		return result
+	}
+
+	switch {
+	case previousVersion == "":
+		result.Action = ActionCreated
+	case previousVersion == applied.GetResourceVersion():
+		result.Action = ActionUnchanged
+	default:
+		result.Action = ActionConfigured
+	}
+
+	if waitForApply {
+		err := wait.PollImmediate(time.Second, applyWaitTimeout, func() (bool, error) {
+			current, err := resource.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			return isObserved(current), nil
+		})
+		if err != nil {
+			result.Error = errors.Wrap(err, "failed to wait for apply").Error()
+			return result
+		}
+	}
+
+	return result
+}
+
+// isObserved returns true once the object's controller has seen its latest generation.
+// Objects without a status.observedGeneration are not reconciled by a controller that reports it.
+func isObserved(obj *unstructured.Unstructured) bool {
+	observedGeneration, found, err := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if err != nil || !found {
+		return true
+	}
+	return observedGeneration >= obj.GetGeneration()
+}
+
+func (s *ServerSide) removeObject(obj *unstructured.Unstructured, targetNamespace string, waitForDelete bool) ObjectResult {
+	result := newObjectResult(obj)
+
+	resource, namespace, err := s.resourceFor(obj, targetNamespace)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Namespace = namespace
+
+	propagation := metav1.DeletePropagationBackground
+	if waitForDelete {
+		propagation = metav1.DeletePropagationForeground
+	}
+	err = resource.Delete(context.TODO(), obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if kuberneteserrors.IsNotFound(err) {
+		result.Action = ActionDeleted
+		return result
+	} else if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if waitForDelete {
+		err := wait.PollImmediate(time.Second, removeWaitTimeout, func() (bool, error) {
+			_, err := resource.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+			if kuberneteserrors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		})
+		if err != nil {
+			result.Error = errors.Wrap(err, "failed to wait for delete").Error()
+			return result
+		}
+	}
+
+	result.Action = ActionDeleted
+	return result
+}
+
+// resourceFor returns the client for the object's resource, and the namespace it belongs in
+func (s *ServerSide) resourceFor(obj *unstructured.Unstructured, targetNamespace string) (dynamic.ResourceInterface, string, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := s.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to find resource for %s", gvk.String())
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		obj.SetNamespace("")
+		return s.dynamicClient.Resource(mapping.Resource), "", nil
+	}
+
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = targetNamespace
+	}
+	if namespace == "" {
+		namespace = s.defaultNamespace
+	}
+	obj.SetNamespace(namespace)
+
+	return s.dynamicClient.Resource(mapping.Resource).Namespace(namespace), namespace, nil
+}
+
+func newObjectResult(obj *unstructured.Unstructured) ObjectResult {
+	return ObjectResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// conflictsFromError returns the fields that another field manager owns, if err is an apply conflict
+func conflictsFromError(err error) []FieldConflict {
+	if !kuberneteserrors.IsConflict(err) {
+		return nil
+	}
+
+	status, ok := err.(kuberneteserrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return nil
+	}
+
+	conflicts := []FieldConflict{}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		conflicts = append(conflicts, FieldConflict{
+			Field:   cause.Field,
+			Message: cause.Message,
+		})
+	}
+	return conflicts
+}
+
+// DecodeObjects decodes the objects in a multi-document yaml, skipping empty documents
+func DecodeObjects(yamlDoc []byte) ([]*unstructured.Unstructured, error) {
+	objs := []*unstructured.Unstructured{}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(yamlDoc), 4096)
+	for {
+		obj := map[string]interface{}{}
+		if err := decoder.Decode(&obj); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "failed to decode object")
+		}
+		if len(obj) == 0 {
+			continue
+		}
+
+		u := &unstructured.Unstructured{Object: obj}
+		if u.GetKind() == "" || u.GetName() == "" {
+			log.Printf("skipping object without a kind or name")
+			continue
+		}
+		objs = append(objs, u)
+	}
+
+	return objs, nil
+}
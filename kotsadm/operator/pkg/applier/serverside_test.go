@@ -0,0 +1,105 @@
+package applier
+
+import (
+	"reflect"
+	"testing"
+
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_conflictsFromError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []FieldConflict
+	}{
+		{
+			name: "not a conflict",
+			err:  kuberneteserrors.NewBadRequest("bad request"),
+			want: nil,
+		},
+		{
+			name: "apply conflict",
+			err: kuberneteserrors.NewApplyConflict([]metav1.StatusCause{
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "kubectl" using apps/v1 at 2020-06-01T00:00:00Z`,
+					Field:   ".spec.replicas",
+				},
+			}, "Apply failed with 1 conflict"),
+			want: []FieldConflict{
+				{
+					Field:   ".spec.replicas",
+					Message: `conflict with "kubectl" using apps/v1 at 2020-06-01T00:00:00Z`,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conflictsFromError(tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("conflictsFromError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newObjectsResult(t *testing.T) {
+	result := newObjectsResult([]ObjectResult{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Action: ActionConfigured},
+		{APIVersion: "v1", Kind: "Service", Name: "web", Action: ActionCreated, DryRun: true},
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "settings", Error: "conflict", Conflicts: []FieldConflict{{Field: ".data.key", Message: `conflict with "helm"`}}},
+	})
+
+	wantStdout := "deployment.apps/web configured\nservice/web created (server dry run)\n"
+	if string(result.Stdout) != wantStdout {
+		t.Errorf("stdout = %q, want %q", result.Stdout, wantStdout)
+	}
+
+	wantStderr := "configmap/settings: conflict\n  conflict: conflict with \"helm\"\n"
+	if string(result.Stderr) != wantStderr {
+		t.Errorf("stderr = %q, want %q", result.Stderr, wantStderr)
+	}
+}
+
+func Test_isObserved(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  map[string]interface{}
+		want bool
+	}{
+		{
+			name: "no status",
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "settings"},
+			},
+			want: true,
+		},
+		{
+			name: "previous generation",
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "web", "generation": int64(3)},
+				"status":   map[string]interface{}{"observedGeneration": int64(2)},
+			},
+			want: false,
+		},
+		{
+			name: "current generation",
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "web", "generation": int64(3)},
+				"status":   map[string]interface{}{"observedGeneration": int64(3)},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isObserved(&unstructured.Unstructured{Object: tt.obj}); got != tt.want {
+				t.Errorf("isObserved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,13 +8,16 @@ import (
 
 	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
 
 type Monitor struct {
 	clientset       kubernetes.Interface
+	dynamicClient   dynamic.Interface
 	targetNamespace string
 	appInformersCh  chan appInformer
 	appStatusCh     chan types.AppStatus
@@ -32,13 +35,14 @@ type appInformer struct {
 	informers []types.StatusInformer
 }
 
-func NewMonitor(clientset kubernetes.Interface, targetNamespace string) *Monitor {
+func NewMonitor(clientset kubernetes.Interface, dynamicClient dynamic.Interface, targetNamespace string) *Monitor {
 	if targetNamespace == "" {
 		targetNamespace = corev1.NamespaceDefault
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	m := &Monitor{
 		clientset:       clientset,
+		dynamicClient:   dynamicClient,
 		targetNamespace: targetNamespace,
 		appInformersCh:  make(chan appInformer),
 		appStatusCh:     make(chan types.AppStatus),
@@ -86,7 +90,7 @@ func (m *Monitor) run(ctx context.Context) {
 		case appInformer := <-m.appInformersCh:
 			appMonitor, ok := appMonitors[appInformer.appID]
 			if !ok {
-				appMonitor = NewAppMonitor(m.clientset, m.targetNamespace, appInformer.appID)
+				appMonitor = NewAppMonitor(m.clientset, m.dynamicClient, m.targetNamespace, appInformer.appID)
 				go func() {
 					for appStatus := range appMonitor.AppStatusChan() {
 						m.appStatusCh <- appStatus
@@ -101,6 +105,7 @@ func (m *Monitor) run(ctx context.Context) {
 
 type AppMonitor struct {
 	clientset       kubernetes.Interface
+	dynamicClient   dynamic.Interface
 	targetNamespace string
 	appID           string
 	informersCh     chan []types.StatusInformer
@@ -108,11 +113,12 @@ type AppMonitor struct {
 	cancel          context.CancelFunc
 }
 
-func NewAppMonitor(clientset kubernetes.Interface, targetNamespace, appID string) *AppMonitor {
+func NewAppMonitor(clientset kubernetes.Interface, dynamicClient dynamic.Interface, targetNamespace, appID string) *AppMonitor {
 	ctx, cancel := context.WithCancel(context.Background())
 	m := &AppMonitor{
 		appID:           appID,
 		clientset:       clientset,
+		dynamicClient:   dynamicClient,
 		targetNamespace: targetNamespace,
 		informersCh:     make(chan []types.StatusInformer),
 		appStatusCh:     make(chan types.AppStatus),
@@ -186,7 +192,18 @@ func (m *AppMonitor) runInformers(ctx context.Context, informers []types.StatusI
 
 	// Collect namespace/kind pairs
 	namespaceKinds := make(map[string]map[string][]types.StatusInformer)
+	namespaceCustomResourceKinds := make(map[string]map[schema.GroupVersionKind][]types.StatusInformer)
 	for _, informer := range informers {
+		if informer.IsCustomResource() {
+			gvk := customResourceGroupVersionKind(informer)
+			kindsInNs, ok := namespaceCustomResourceKinds[informer.Namespace]
+			if !ok {
+				kindsInNs = make(map[schema.GroupVersionKind][]types.StatusInformer)
+			}
+			kindsInNs[gvk] = append(kindsInNs[gvk], informer)
+			namespaceCustomResourceKinds[informer.Namespace] = kindsInNs
+			continue
+		}
 		kindsInNs, ok := namespaceKinds[informer.Namespace]
 		if !ok {
 			kindsInNs = make(map[string][]types.StatusInformer)
@@ -204,8 +221,11 @@ func (m *AppMonitor) runInformers(ctx context.Context, informers []types.StatusI
 	}
 
 	kindImpls := map[string]runControllerFunc{
+		CronJobResourceKind:               runCronJobController,
+		DaemonSetResourceKind:             runDaemonSetController,
 		DeploymentResourceKind:            runDeploymentController,
 		IngressResourceKind:               runIngressController,
+		JobResourceKind:                   runJobController,
 		PersistentVolumeClaimResourceKind: runPersistentVolumeClaimController,
 		ServiceResourceKind:               runServiceController,
 		StatefulSetResourceKind:           runStatefulSetController,
@@ -219,14 +239,26 @@ func (m *AppMonitor) runInformers(ctx context.Context, informers []types.StatusI
 			}
 		}
 	}
+	for namespace, kinds := range namespaceCustomResourceKinds {
+		for gvk, informers := range kinds {
+			goRun(newCustomResourceController(m.dynamicClient, gvk), namespace, informers)
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case resourceState := <-resourceStateCh:
-			appStatus.ResourceStates, _ = resourceStatesApplyNew(appStatus.ResourceStates, informers, resourceState)
+			var didChange bool
+			appStatus.ResourceStates, didChange = resourceStatesApplyNew(appStatus.ResourceStates, informers, resourceState)
 			appStatus.UpdatedAt = time.Now() // TODO: this should come from the informer
+			appStatus.Transitions = nil
+			if didChange {
+				appStatus.Transitions = []types.ResourceStateTransition{
+					{ResourceState: resourceState, TransitionedAt: appStatus.UpdatedAt},
+				}
+			}
 			m.appStatusCh <- appStatus
 		}
 	}
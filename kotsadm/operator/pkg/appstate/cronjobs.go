@@ -0,0 +1,146 @@
+package appstate
+
+import (
+	"context"
+	"time"
+
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	CronJobResourceKind = "cronjob"
+)
+
+func init() {
+	registerResourceKindNames(CronJobResourceKind, "cronjobs", "cj")
+}
+
+func runCronJobController(
+	ctx context.Context, clientset kubernetes.Interface, targetNamespace string,
+	informers []types.StatusInformer, resourceStateCh chan<- types.ResourceState,
+) {
+	listwatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return clientset.BatchV1beta1().CronJobs(targetNamespace).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return clientset.BatchV1beta1().CronJobs(targetNamespace).Watch(context.TODO(), options)
+		},
+	}
+	informer := cache.NewSharedInformer(
+		listwatch,
+		&batchv1beta1.CronJob{},
+		// NOTE: cron jobs rely on the status of their jobs as well so unless we add additional
+		// informers, we have to resync more frequently.
+		10*time.Second,
+	)
+
+	eventHandler := NewCronJobEventHandler(
+		clientset,
+		filterStatusInformersByResourceKind(informers, CronJobResourceKind),
+		resourceStateCh,
+	)
+
+	runInformer(ctx, informer, eventHandler)
+	return
+}
+
+type cronJobEventHandler struct {
+	clientset       kubernetes.Interface
+	informers       []types.StatusInformer
+	resourceStateCh chan<- types.ResourceState
+}
+
+func NewCronJobEventHandler(clientset kubernetes.Interface, informers []types.StatusInformer, resourceStateCh chan<- types.ResourceState) *cronJobEventHandler {
+	return &cronJobEventHandler{
+		clientset:       clientset,
+		informers:       informers,
+		resourceStateCh: resourceStateCh,
+	}
+}
+
+func (h *cronJobEventHandler) ObjectCreated(obj interface{}) {
+	r := h.cast(obj)
+	if _, ok := h.getInformer(r); !ok {
+		return
+	}
+	h.resourceStateCh <- makeCronJobResourceState(r, calculateCronJobState(h.clientset, r))
+}
+
+func (h *cronJobEventHandler) ObjectUpdated(obj interface{}) {
+	r := h.cast(obj)
+	if _, ok := h.getInformer(r); !ok {
+		return
+	}
+	h.resourceStateCh <- makeCronJobResourceState(r, calculateCronJobState(h.clientset, r))
+}
+
+func (h *cronJobEventHandler) ObjectDeleted(obj interface{}) {
+	r := h.cast(obj)
+	if _, ok := h.getInformer(r); !ok {
+		return
+	}
+	h.resourceStateCh <- makeCronJobResourceState(r, types.StateMissing)
+}
+
+func (h *cronJobEventHandler) cast(obj interface{}) *batchv1beta1.CronJob {
+	r, _ := obj.(*batchv1beta1.CronJob)
+	return r
+}
+
+func (h *cronJobEventHandler) getInformer(r *batchv1beta1.CronJob) (types.StatusInformer, bool) {
+	if r != nil {
+		for _, informer := range h.informers {
+			if r.Namespace == informer.Namespace && r.Name == informer.Name {
+				return informer, true
+			}
+		}
+	}
+	return types.StatusInformer{}, false
+}
+
+func makeCronJobResourceState(r *batchv1beta1.CronJob, state types.State) types.ResourceState {
+	return types.ResourceState{
+		Kind:      CronJobResourceKind,
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		State:     state,
+	}
+}
+
+// calculateCronJobState is based on the most recent finished job of the cron job. A cron job that
+// hasn't finished a job yet is ready.
+func calculateCronJobState(clientset kubernetes.Interface, r *batchv1beta1.CronJob) types.State {
+	jobs, err := clientset.BatchV1().Jobs(r.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return types.StateUnavailable
+	}
+	return cronJobGetStateFromJobs(r, jobs.Items)
+}
+
+func cronJobGetStateFromJobs(r *batchv1beta1.CronJob, jobs []batchv1.Job) types.State {
+	var lastJob *batchv1.Job
+	for i := range jobs {
+		job := &jobs[i]
+		if !metav1.IsControlledBy(job, r) || jobFinishedConditionType(job) == "" {
+			continue
+		}
+		if lastJob == nil || lastJob.CreationTimestamp.Before(&job.CreationTimestamp) {
+			lastJob = job
+		}
+	}
+	if lastJob == nil {
+		return types.StateReady
+	}
+	if jobFinishedConditionType(lastJob) == batchv1.JobFailed {
+		return types.StateDegraded
+	}
+	return types.StateReady
+}
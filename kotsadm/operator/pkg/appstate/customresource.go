@@ -0,0 +1,223 @@
+package appstate
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+var (
+	// CustomResourceMappingInterval is how often the controller looks for the resource of a custom
+	// resource kind, which may not exist until its CRD is deployed
+	CustomResourceMappingInterval = time.Second * 10
+)
+
+func customResourceGroupVersionKind(informer types.StatusInformer) schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   informer.Group,
+		Version: informer.Version,
+		Kind:    informer.Kind,
+	}
+}
+
+// newCustomResourceController returns a controller for the informers of a single custom resource kind
+func newCustomResourceController(dynamicClient dynamic.Interface, gvk schema.GroupVersionKind) runControllerFunc {
+	return func(
+		ctx context.Context, clientset kubernetes.Interface, targetNamespace string,
+		informers []types.StatusInformer, resourceStateCh chan<- types.ResourceState,
+	) {
+		mapping, err := waitForCustomResourceMapping(ctx, clientset, gvk)
+		if err != nil {
+			log.Printf("Failed to find resource for custom resource kind %s: %v", gvk, err)
+			return
+		}
+
+		namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		resourceClient := func() dynamic.ResourceInterface {
+			if namespaced {
+				return dynamicClient.Resource(mapping.Resource).Namespace(targetNamespace)
+			}
+			return dynamicClient.Resource(mapping.Resource)
+		}
+
+		listwatch := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return resourceClient().List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return resourceClient().Watch(context.TODO(), options)
+			},
+		}
+		informer := cache.NewSharedInformer(
+			listwatch,
+			&unstructured.Unstructured{},
+			time.Minute,
+		)
+
+		eventHandler := NewCustomResourceEventHandler(
+			namespaced,
+			informers,
+			resourceStateCh,
+		)
+
+		runInformer(ctx, informer, eventHandler)
+		return
+	}
+}
+
+// waitForCustomResourceMapping polls discovery until the custom resource kind is served by the api
+func waitForCustomResourceMapping(ctx context.Context, clientset kubernetes.Interface, gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+
+	var mapping *meta.RESTMapping
+	err := wait.PollImmediateUntil(CustomResourceMappingInterval, func() (bool, error) {
+		m, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			mapper.Reset()
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		mapping = m
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+type customResourceEventHandler struct {
+	namespaced      bool
+	informers       []types.StatusInformer
+	resourceStateCh chan<- types.ResourceState
+}
+
+func NewCustomResourceEventHandler(namespaced bool, informers []types.StatusInformer, resourceStateCh chan<- types.ResourceState) *customResourceEventHandler {
+	return &customResourceEventHandler{
+		namespaced:      namespaced,
+		informers:       informers,
+		resourceStateCh: resourceStateCh,
+	}
+}
+
+func (h *customResourceEventHandler) ObjectCreated(obj interface{}) {
+	r := h.cast(obj)
+	informer, ok := h.getInformer(r)
+	if !ok {
+		return
+	}
+	h.resourceStateCh <- makeCustomResourceResourceState(informer, calculateCustomResourceState(r, informer))
+}
+
+func (h *customResourceEventHandler) ObjectUpdated(obj interface{}) {
+	r := h.cast(obj)
+	informer, ok := h.getInformer(r)
+	if !ok {
+		return
+	}
+	h.resourceStateCh <- makeCustomResourceResourceState(informer, calculateCustomResourceState(r, informer))
+}
+
+func (h *customResourceEventHandler) ObjectDeleted(obj interface{}) {
+	r := h.cast(obj)
+	informer, ok := h.getInformer(r)
+	if !ok {
+		return
+	}
+	h.resourceStateCh <- makeCustomResourceResourceState(informer, types.StateMissing)
+}
+
+func (h *customResourceEventHandler) cast(obj interface{}) *unstructured.Unstructured {
+	r, _ := obj.(*unstructured.Unstructured)
+	return r
+}
+
+func (h *customResourceEventHandler) getInformer(r *unstructured.Unstructured) (types.StatusInformer, bool) {
+	if r != nil {
+		for _, informer := range h.informers {
+			if (!h.namespaced || r.GetNamespace() == informer.Namespace) && r.GetName() == informer.Name {
+				return informer, true
+			}
+		}
+	}
+	return types.StatusInformer{}, false
+}
+
+// makeCustomResourceResourceState uses the informer rather than the resource so that the state
+// matches the resource states built from the informers, including for cluster scoped resources
+func makeCustomResourceResourceState(informer types.StatusInformer, state types.State) types.ResourceState {
+	return types.ResourceState{
+		Kind:      informer.Kind,
+		Name:      informer.Name,
+		Namespace: informer.Namespace,
+		State:     state,
+	}
+}
+
+// calculateCustomResourceState returns ready if the readiness rule of the informer matches. A custom
+// resource is either ready or unavailable, as there is no general way to tell if it is degraded.
+func calculateCustomResourceState(r *unstructured.Unstructured, informer types.StatusInformer) types.State {
+	if informer.ReadyJSONPath != "" {
+		value, err := customResourceJSONPathValue(r, informer.ReadyJSONPath)
+		if err != nil {
+			log.Printf("Failed to evaluate jsonpath %s for %s %s: %v", informer.ReadyJSONPath, informer.Kind, informer.Name, err)
+			return types.StateUnavailable
+		}
+		readyValue := informer.ReadyValue
+		if readyValue == "" {
+			readyValue = "true"
+		}
+		if value == readyValue {
+			return types.StateReady
+		}
+		return types.StateUnavailable
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(r.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if conditionType, _ := condition["type"].(string); conditionType != informer.ReadyCondition {
+			continue
+		}
+		if status, _ := condition["status"].(string); strings.EqualFold(status, "True") {
+			return types.StateReady
+		}
+		return types.StateUnavailable
+	}
+	return types.StateUnavailable
+}
+
+func customResourceJSONPathValue(r *unstructured.Unstructured, path string) (string, error) {
+	jp := jsonpath.New("ready")
+	jp.AllowMissingKeys(true)
+	if err := jp.Parse(path); err != nil {
+		return "", errors.Wrap(err, "failed to parse jsonpath")
+	}
+	var buf bytes.Buffer
+	if err := jp.Execute(&buf, r.Object); err != nil {
+		return "", errors.Wrap(err, "failed to execute jsonpath")
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
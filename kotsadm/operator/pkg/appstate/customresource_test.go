@@ -0,0 +1,78 @@
+package appstate
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_calculateCustomResourceState(t *testing.T) {
+	postgresql := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "acid.zalan.do/v1",
+			"kind":       "postgresql",
+			"metadata": map[string]interface{}{
+				"name":      "main",
+				"namespace": "default",
+			},
+			"status": map[string]interface{}{
+				"PostgresClusterStatus": "Running",
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True"},
+					map[string]interface{}{"type": "Upgrading", "status": "False"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		informer types.StatusInformer
+		want     types.State
+	}{
+		{
+			name:     "condition true",
+			informer: types.StatusInformer{ReadyCondition: "Ready"},
+			want:     types.StateReady,
+		},
+		{
+			name:     "condition false",
+			informer: types.StatusInformer{ReadyCondition: "Upgrading"},
+			want:     types.StateUnavailable,
+		},
+		{
+			name:     "condition missing",
+			informer: types.StatusInformer{ReadyCondition: "Available"},
+			want:     types.StateUnavailable,
+		},
+		{
+			name:     "jsonpath matches",
+			informer: types.StatusInformer{ReadyJSONPath: "{.status.PostgresClusterStatus}", ReadyValue: "Running"},
+			want:     types.StateReady,
+		},
+		{
+			name:     "jsonpath does not match",
+			informer: types.StatusInformer{ReadyJSONPath: "{.status.PostgresClusterStatus}", ReadyValue: "Failed"},
+			want:     types.StateUnavailable,
+		},
+		{
+			name:     "jsonpath missing",
+			informer: types.StatusInformer{ReadyJSONPath: "{.status.ready}"},
+			want:     types.StateUnavailable,
+		},
+		{
+			name:     "jsonpath invalid",
+			informer: types.StatusInformer{ReadyJSONPath: "{.status["},
+			want:     types.StateUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateCustomResourceState(postgresql, tt.informer); got != tt.want {
+				t.Errorf("calculateCustomResourceState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
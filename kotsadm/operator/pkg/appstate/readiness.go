@@ -0,0 +1,127 @@
+package appstate
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	ReadyPollInterval = time.Second * 2
+)
+
+// IsReadinessSupported returns true if GetResourceState can calculate the state of the informer's resource
+func IsReadinessSupported(informer types.StatusInformer) bool {
+	if informer.IsCustomResource() {
+		return false
+	}
+	switch getResourceKindCommonName(informer.Kind) {
+	case DeploymentResourceKind, StatefulSetResourceKind, DaemonSetResourceKind, JobResourceKind, CronJobResourceKind,
+		ServiceResourceKind, IngressResourceKind, PersistentVolumeClaimResourceKind:
+		return true
+	}
+	return false
+}
+
+// GetResourceState returns the current state of a single resource, using the same calculations as the
+// status informers. Resources that don't exist are missing.
+func GetResourceState(clientset kubernetes.Interface, informer types.StatusInformer) (types.State, error) {
+	var err error
+	state := types.StateMissing
+
+	if informer.IsCustomResource() {
+		return types.StateMissing, errors.Errorf("unsupported custom resource kind %q", informer.Kind)
+	}
+
+	switch getResourceKindCommonName(informer.Kind) {
+	case DeploymentResourceKind:
+		r, getErr := clientset.AppsV1().Deployments(informer.Namespace).Get(context.TODO(), informer.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			state = calculateDeploymentState(r)
+		}
+	case StatefulSetResourceKind:
+		r, getErr := clientset.AppsV1().StatefulSets(informer.Namespace).Get(context.TODO(), informer.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			state = calculateStatefulSetState(r)
+		}
+	case DaemonSetResourceKind:
+		r, getErr := clientset.AppsV1().DaemonSets(informer.Namespace).Get(context.TODO(), informer.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			state = calculateDaemonSetState(r)
+		}
+	case JobResourceKind:
+		r, getErr := clientset.BatchV1().Jobs(informer.Namespace).Get(context.TODO(), informer.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			state = calculateJobState(r)
+		}
+	case CronJobResourceKind:
+		r, getErr := clientset.BatchV1beta1().CronJobs(informer.Namespace).Get(context.TODO(), informer.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			state = calculateCronJobState(clientset, r)
+		}
+	case ServiceResourceKind:
+		r, getErr := clientset.CoreV1().Services(informer.Namespace).Get(context.TODO(), informer.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			state = calculateServiceState(clientset, r)
+		}
+	case IngressResourceKind:
+		r, getErr := clientset.ExtensionsV1beta1().Ingresses(informer.Namespace).Get(context.TODO(), informer.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			state = calculateIngressState(clientset, r)
+		}
+	case PersistentVolumeClaimResourceKind:
+		r, getErr := clientset.CoreV1().PersistentVolumeClaims(informer.Namespace).Get(context.TODO(), informer.Name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			state = calculatePersistentVolumeClaimState(r)
+		}
+	default:
+		return types.StateMissing, errors.Errorf("unsupported resource kind %q", informer.Kind)
+	}
+
+	if kuberneteserrors.IsNotFound(err) {
+		return types.StateMissing, nil
+	} else if err != nil {
+		return types.StateMissing, errors.Wrapf(err, "failed to get %s %s", informer.Kind, informer.Name)
+	}
+
+	return state, nil
+}
+
+// WaitForReady polls the resources until they are all ready, or ctx is done. The last states
+// are always returned so that callers can report which resources were not ready.
+func WaitForReady(ctx context.Context, clientset kubernetes.Interface, informers []types.StatusInformer) (types.ResourceStates, error) {
+	for {
+		resourceStates := types.ResourceStates{}
+		allReady := true
+		for _, informer := range informers {
+			state, err := GetResourceState(clientset, informer)
+			if err != nil {
+				return resourceStates, err
+			}
+			if state != types.StateReady {
+				allReady = false
+			}
+			resourceStates = append(resourceStates, types.ResourceState{
+				Kind:      getResourceKindCommonName(informer.Kind),
+				Name:      informer.Name,
+				Namespace: informer.Namespace,
+				State:     state,
+			})
+		}
+
+		if allReady {
+			return resourceStates, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return resourceStates, errors.Wrap(ctx.Err(), "resources are not ready")
+		case <-time.After(ReadyPollInterval):
+		}
+	}
+}
@@ -2,7 +2,9 @@ package types
 
 import (
 	"errors"
+	"net/url"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -13,6 +15,8 @@ var (
 	StateMissing     State = "missing"
 
 	StatusInformerRegexp = regexp.MustCompile(`^(?:([^\/]+)\/)?([^\/]+)\/([^\/]+)$`)
+
+	DefaultReadyCondition = "Ready"
 )
 
 type StatusInformerString string
@@ -21,17 +25,73 @@ type StatusInformer struct {
 	Kind      string
 	Name      string
 	Namespace string
+
+	// Custom resources are declared as Kind.version.group, for example
+	// postgres/Postgresql.v1.acid.zalan.do/main?condition=Ready or
+	// KafkaTopic.v1beta1.kafka.strimzi.io/events?jsonpath={.status.phase}&value=Ready
+	Group          string
+	Version        string
+	ReadyCondition string
+	ReadyJSONPath  string
+	ReadyValue     string
+}
+
+// IsCustomResource returns true if the informer is for a custom resource, which is ready according
+// to its readiness rule rather than a built in state calculation
+func (i StatusInformer) IsCustomResource() bool {
+	return i.Version != ""
 }
 
 func (s StatusInformerString) Parse() (i StatusInformer, err error) {
-	matches := StatusInformerRegexp.FindStringSubmatch(string(s))
+	str, query := string(s), ""
+	if idx := strings.Index(str, "?"); idx != -1 {
+		str, query = str[:idx], str[idx+1:]
+	}
+
+	matches := StatusInformerRegexp.FindStringSubmatch(str)
 	if len(matches) != 4 {
 		err = errors.New("status informer format string incorrect")
 		return
 	}
-	i.Namespace = matches[1]
-	i.Kind = matches[2]
-	i.Name = matches[3]
+
+	next := StatusInformer{
+		Namespace: matches[1],
+		Kind:      matches[2],
+		Name:      matches[3],
+	}
+
+	if parts := strings.SplitN(next.Kind, ".", 3); len(parts) > 1 {
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			err = errors.New("custom resource status informer kind must be Kind.version.group")
+			return
+		}
+		next.Kind, next.Version, next.Group = parts[0], parts[1], parts[2]
+	}
+
+	if query != "" {
+		if !next.IsCustomResource() {
+			err = errors.New("readiness rules are only supported for custom resources")
+			return
+		}
+		values, parseErr := url.ParseQuery(query)
+		if parseErr != nil {
+			err = errors.New("status informer readiness rule incorrect")
+			return
+		}
+		next.ReadyCondition = values.Get("condition")
+		next.ReadyJSONPath = values.Get("jsonpath")
+		next.ReadyValue = values.Get("value")
+		if next.ReadyCondition != "" && next.ReadyJSONPath != "" {
+			err = errors.New("status informer readiness rule must be either a condition or a jsonpath")
+			return
+		}
+	}
+
+	if next.IsCustomResource() && next.ReadyCondition == "" && next.ReadyJSONPath == "" {
+		next.ReadyCondition = DefaultReadyCondition
+	}
+
+	i = next
 	return
 }
 
@@ -39,6 +99,9 @@ type AppStatus struct {
 	AppID          string         `json:"app_id"`
 	ResourceStates ResourceStates `json:"resource_states" hash:"set"`
 	UpdatedAt      time.Time      `json:"updated_at" hash:"ignore"`
+
+	// Transitions are the changes in resource states since the last app status that was sent
+	Transitions []ResourceStateTransition `json:"transitions,omitempty" hash:"ignore"`
 }
 
 type ResourceStates []ResourceState
@@ -50,6 +113,12 @@ type ResourceState struct {
 	State     State  `json:"state"`
 }
 
+// ResourceStateTransition is a resource changing to State at TransitionedAt
+type ResourceStateTransition struct {
+	ResourceState
+	TransitionedAt time.Time `json:"transitioned_at"`
+}
+
 type State string
 
 func MinState(ss ...State) (min State) {
@@ -31,6 +31,51 @@ func TestStatusInformerString_Parse(t *testing.T) {
 				Name:      "sentry-web",
 			},
 		},
+		{
+			name: "custom resource",
+			str:  "postgres/Postgresql.v1.acid.zalan.do/main",
+			want: StatusInformer{
+				Namespace:      "postgres",
+				Kind:           "Postgresql",
+				Name:           "main",
+				Group:          "acid.zalan.do",
+				Version:        "v1",
+				ReadyCondition: "Ready",
+			},
+		},
+		{
+			name: "custom resource condition",
+			str:  "Kafka.v1beta1.kafka.strimzi.io/events?condition=Available",
+			want: StatusInformer{
+				Kind:           "Kafka",
+				Name:           "events",
+				Group:          "kafka.strimzi.io",
+				Version:        "v1beta1",
+				ReadyCondition: "Available",
+			},
+		},
+		{
+			name: "custom resource jsonpath",
+			str:  "Postgresql.v1.acid.zalan.do/main?jsonpath={.status.PostgresClusterStatus}&value=Running",
+			want: StatusInformer{
+				Kind:          "Postgresql",
+				Name:          "main",
+				Group:         "acid.zalan.do",
+				Version:       "v1",
+				ReadyJSONPath: "{.status.PostgresClusterStatus}",
+				ReadyValue:    "Running",
+			},
+		},
+		{
+			name:    "custom resource without group",
+			str:     "Postgresql.v1/main",
+			wantErr: true,
+		},
+		{
+			name:    "rule for built in kind",
+			str:     "deploy/sentry-web?condition=Available",
+			wantErr: true,
+		},
 		{
 			name:    "no match",
 			str:     "sentry-web",
@@ -0,0 +1,540 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/applier"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	RolloutStrategyCanary = "canary"
+
+	// CanaryLabel is added to the labels, selector and pod template of canary Deployments
+	CanaryLabel = "kots.io/canary"
+)
+
+var (
+	DefaultCanaryBakePeriod = time.Minute * 5
+	// CanaryReadyTimeout is how long the canary Deployments have to become available before
+	// the bake period starts
+	CanaryReadyTimeout  = time.Minute * 10
+	CanaryPollInterval  = time.Second * 2
+	CanaryCheckInterval = time.Second * 30
+)
+
+// CanaryCheck is a Prometheus query whose values must stay within Min and Max
+type CanaryCheck struct {
+	Name  string   `json:"name"`
+	Query string   `json:"query"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// canaryResult is reported with the deploy result
+type canaryResult struct {
+	Promoted    bool     `json:"promoted"`
+	AbortReason string   `json:"abort_reason,omitempty"`
+	Deployments []string `json:"deployments"`
+	// ConfigMaps and Secrets are the copies of the new version's ConfigMaps and Secrets that the
+	// canary Deployments used
+	ConfigMaps []string  `json:"config_maps"`
+	Secrets    []string  `json:"secrets"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// canaryDependencies are copies of the ConfigMaps and Secrets in the manifests that the canary
+// Deployments use. The canaries run with the new version's config, and the deployed version keeps
+// using its own. Other objects that the new version adds or changes are not applied until the
+// canary is promoted.
+type canaryDependencies struct {
+	configMaps map[string]*corev1.ConfigMap
+	secrets    map[string]*corev1.Secret
+}
+
+// runCanary runs the new version of every Deployment that changed in canary Deployments, and
+// returns whether the new version can be promoted. The canary Deployments and the copies of their
+// ConfigMaps and Secrets are removed before returning. Nothing else is changed, so the previous
+// version keeps running when it's aborted.
+func (c *Client) runCanary(applicationManifests ApplicationManifests) (*canaryResult, error) {
+	result := &canaryResult{
+		Deployments: []string{},
+		ConfigMaps:  []string{},
+		Secrets:     []string{},
+		StartedAt:   time.Now(),
+	}
+
+	restconfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get in cluster config")
+	}
+	clientset, err := kubernetes.NewForConfig(restconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get new kubernetes client")
+	}
+
+	canaries, dependencies, err := c.canaryDeployments(clientset, applicationManifests)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find changed deployments")
+	}
+	if len(canaries) == 0 {
+		log.Printf("no deployments changed, skipping canary")
+		result.Promoted = true
+		result.FinishedAt = time.Now()
+		return result, nil
+	}
+
+	// the canaries are deleted before the config maps and secrets they use
+	defer deleteCanaryDependencies(clientset, dependencies)
+	defer deleteCanaries(clientset, canaries)
+
+	for _, configMap := range dependencies.configMaps {
+		log.Printf("creating canary config map %s/%s", configMap.Namespace, configMap.Name)
+		if err := createCanaryConfigMap(clientset, configMap); err != nil {
+			return nil, errors.Wrapf(err, "failed to create canary config map %s", configMap.Name)
+		}
+		result.ConfigMaps = append(result.ConfigMaps, fmt.Sprintf("%s/%s", configMap.Namespace, configMap.Name))
+	}
+	for _, secret := range dependencies.secrets {
+		log.Printf("creating canary secret %s/%s", secret.Namespace, secret.Name)
+		if err := createCanarySecret(clientset, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to create canary secret %s", secret.Name)
+		}
+		result.Secrets = append(result.Secrets, fmt.Sprintf("%s/%s", secret.Namespace, secret.Name))
+	}
+
+	for _, canary := range canaries {
+		log.Printf("creating canary deployment %s/%s", canary.Namespace, canary.Name)
+		if err := createCanary(clientset, canary); err != nil {
+			return nil, errors.Wrapf(err, "failed to create canary deployment %s", canary.Name)
+		}
+		result.Deployments = append(result.Deployments, fmt.Sprintf("%s/%s", canary.Namespace, canary.Name))
+	}
+
+	result.AbortReason = c.bakeCanary(clientset, applicationManifests, canaries)
+	result.Promoted = result.AbortReason == ""
+	result.FinishedAt = time.Now()
+
+	return result, nil
+}
+
+// canaryDeployments returns a canary for each Deployment in the manifests whose pod template
+// differs from the live Deployment, and copies of the ConfigMaps and Secrets in the manifests that
+// the canaries use. Deployments that don't exist yet have nothing to compare to.
+func (c *Client) canaryDeployments(clientset kubernetes.Interface, applicationManifests ApplicationManifests) ([]*appsv1.Deployment, *canaryDependencies, error) {
+	targetNamespace := c.TargetNamespace
+	if applicationManifests.Namespace != "." && applicationManifests.Namespace != "" {
+		targetNamespace = applicationManifests.Namespace
+	}
+	if targetNamespace == "" {
+		targetNamespace = corev1.NamespaceDefault
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(applicationManifests.Manifests)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode manifests")
+	}
+
+	objs, err := applier.DecodeObjects(decoded)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode objects")
+	}
+
+	replicas := applicationManifests.CanaryReplicas
+	if replicas <= 0 {
+		replicas = 1
+	}
+
+	configMaps := map[string]*corev1.ConfigMap{}
+	secrets := map[string]*corev1.Secret{}
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if gvk.Group != "" || (gvk.Kind != "ConfigMap" && gvk.Kind != "Secret") {
+			continue
+		}
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = targetNamespace
+		}
+		key := fmt.Sprintf("%s/%s", namespace, obj.GetName())
+
+		if gvk.Kind == "ConfigMap" {
+			configMap := &corev1.ConfigMap{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, configMap); err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to convert config map %s", obj.GetName())
+			}
+			configMap.Namespace = namespace
+			configMaps[key] = configMap
+		} else {
+			secret := &corev1.Secret{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, secret); err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to convert secret %s", obj.GetName())
+			}
+			secret.Namespace = namespace
+			secrets[key] = secret
+		}
+	}
+
+	canaries := []*appsv1.Deployment{}
+	dependencies := &canaryDependencies{
+		configMaps: map[string]*corev1.ConfigMap{},
+		secrets:    map[string]*corev1.Secret{},
+	}
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if gvk.Group != "apps" || gvk.Kind != "Deployment" {
+			continue
+		}
+
+		desired := appsv1.Deployment{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &desired); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to convert deployment %s", obj.GetName())
+		}
+		if desired.Namespace == "" {
+			desired.Namespace = targetNamespace
+		}
+
+		live, err := clientset.AppsV1().Deployments(desired.Namespace).Get(context.TODO(), desired.Name, metav1.GetOptions{})
+		if kuberneteserrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to get deployment %s", desired.Name)
+		}
+
+		// fields that the manifest doesn't set are defaulted in the live deployment
+		if equality.Semantic.DeepDerivative(desired.Spec.Template, live.Spec.Template) {
+			continue
+		}
+
+		canary := newCanaryDeployment(&desired, replicas)
+		useCanaryDependencies(canary, configMaps, secrets, dependencies)
+		canaries = append(canaries, canary)
+	}
+
+	return canaries, dependencies, nil
+}
+
+func newCanaryDeployment(desired *appsv1.Deployment, replicas int32) *appsv1.Deployment {
+	canary := desired.DeepCopy()
+
+	canary.ObjectMeta = newCanaryObjectMeta(desired.ObjectMeta)
+
+	canary.Spec.Replicas = &replicas
+
+	// the canary pods keep all of the desired pod labels, so they match the selectors of the
+	// Services and of the Deployment that they are a canary for. The extra label keeps the canary's
+	// own selector from matching the Deployment's pods, and pods are only managed by the ReplicaSet
+	// that owns them.
+	if canary.Spec.Selector == nil {
+		canary.Spec.Selector = &metav1.LabelSelector{}
+	}
+	if canary.Spec.Selector.MatchLabels == nil {
+		canary.Spec.Selector.MatchLabels = map[string]string{}
+	}
+	canary.Spec.Selector.MatchLabels[CanaryLabel] = "true"
+	if canary.Spec.Template.Labels == nil {
+		canary.Spec.Template.Labels = map[string]string{}
+	}
+	canary.Spec.Template.Labels[CanaryLabel] = "true"
+
+	canary.Status = appsv1.DeploymentStatus{}
+
+	return canary
+}
+
+// useCanaryDependencies points the canary at copies of the ConfigMaps and Secrets in the manifests
+// that its pods use, and adds the copies to dependencies. ConfigMaps and Secrets that aren't in the
+// manifests are used as they are.
+func useCanaryDependencies(canary *appsv1.Deployment, configMaps map[string]*corev1.ConfigMap, secrets map[string]*corev1.Secret, dependencies *canaryDependencies) {
+	configMapName := func(name string) string {
+		configMap, ok := configMaps[fmt.Sprintf("%s/%s", canary.Namespace, name)]
+		if !ok {
+			return name
+		}
+		canaryConfigMap := configMap.DeepCopy()
+		canaryConfigMap.ObjectMeta = newCanaryObjectMeta(configMap.ObjectMeta)
+		dependencies.configMaps[canaryConfigMap.Name] = canaryConfigMap
+		return canaryConfigMap.Name
+	}
+	secretName := func(name string) string {
+		secret, ok := secrets[fmt.Sprintf("%s/%s", canary.Namespace, name)]
+		if !ok {
+			return name
+		}
+		canarySecret := secret.DeepCopy()
+		canarySecret.ObjectMeta = newCanaryObjectMeta(secret.ObjectMeta)
+		dependencies.secrets[canarySecret.Name] = canarySecret
+		return canarySecret.Name
+	}
+
+	podSpec := &canary.Spec.Template.Spec
+	for i := range podSpec.Volumes {
+		volume := &podSpec.Volumes[i]
+		if volume.ConfigMap != nil {
+			volume.ConfigMap.Name = configMapName(volume.ConfigMap.Name)
+		}
+		if volume.Secret != nil {
+			volume.Secret.SecretName = secretName(volume.Secret.SecretName)
+		}
+		if volume.Projected != nil {
+			for j := range volume.Projected.Sources {
+				source := &volume.Projected.Sources[j]
+				if source.ConfigMap != nil {
+					source.ConfigMap.Name = configMapName(source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					source.Secret.Name = secretName(source.Secret.Name)
+				}
+			}
+		}
+	}
+
+	containers := []*corev1.Container{}
+	for i := range podSpec.InitContainers {
+		containers = append(containers, &podSpec.InitContainers[i])
+	}
+	for i := range podSpec.Containers {
+		containers = append(containers, &podSpec.Containers[i])
+	}
+	for _, container := range containers {
+		for i := range container.EnvFrom {
+			envFrom := &container.EnvFrom[i]
+			if envFrom.ConfigMapRef != nil {
+				envFrom.ConfigMapRef.Name = configMapName(envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				envFrom.SecretRef.Name = secretName(envFrom.SecretRef.Name)
+			}
+		}
+		for i := range container.Env {
+			valueFrom := container.Env[i].ValueFrom
+			if valueFrom == nil {
+				continue
+			}
+			if valueFrom.ConfigMapKeyRef != nil {
+				valueFrom.ConfigMapKeyRef.Name = configMapName(valueFrom.ConfigMapKeyRef.Name)
+			}
+			if valueFrom.SecretKeyRef != nil {
+				valueFrom.SecretKeyRef.Name = secretName(valueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+}
+
+func newCanaryObjectMeta(objectMeta metav1.ObjectMeta) metav1.ObjectMeta {
+	canaryObjectMeta := metav1.ObjectMeta{
+		Name:        fmt.Sprintf("%s-canary", objectMeta.Name),
+		Namespace:   objectMeta.Namespace,
+		Labels:      map[string]string{},
+		Annotations: objectMeta.Annotations,
+	}
+	for k, v := range objectMeta.Labels {
+		canaryObjectMeta.Labels[k] = v
+	}
+	canaryObjectMeta.Labels[CanaryLabel] = "true"
+	return canaryObjectMeta
+}
+
+// createCanaryConfigMap replaces a canary config map that was left behind
+func createCanaryConfigMap(clientset kubernetes.Interface, configMap *corev1.ConfigMap) error {
+	configMaps := clientset.CoreV1().ConfigMaps(configMap.Namespace)
+	if err := configMaps.Delete(context.TODO(), configMap.Name, metav1.DeleteOptions{}); err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete previous canary config map")
+	}
+	if _, err := configMaps.Create(context.TODO(), configMap, metav1.CreateOptions{}); err != nil {
+		return errors.Wrap(err, "failed to create")
+	}
+	return nil
+}
+
+// createCanarySecret replaces a canary secret that was left behind
+func createCanarySecret(clientset kubernetes.Interface, secret *corev1.Secret) error {
+	secrets := clientset.CoreV1().Secrets(secret.Namespace)
+	if err := secrets.Delete(context.TODO(), secret.Name, metav1.DeleteOptions{}); err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete previous canary secret")
+	}
+	if _, err := secrets.Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+		return errors.Wrap(err, "failed to create")
+	}
+	return nil
+}
+
+func deleteCanaryDependencies(clientset kubernetes.Interface, dependencies *canaryDependencies) {
+	for _, configMap := range dependencies.configMaps {
+		log.Printf("deleting canary config map %s/%s", configMap.Namespace, configMap.Name)
+		err := clientset.CoreV1().ConfigMaps(configMap.Namespace).Delete(context.TODO(), configMap.Name, metav1.DeleteOptions{})
+		if err != nil && !kuberneteserrors.IsNotFound(err) {
+			log.Printf("error deleting canary config map %s: %s", configMap.Name, err.Error())
+		}
+	}
+	for _, secret := range dependencies.secrets {
+		log.Printf("deleting canary secret %s/%s", secret.Namespace, secret.Name)
+		err := clientset.CoreV1().Secrets(secret.Namespace).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{})
+		if err != nil && !kuberneteserrors.IsNotFound(err) {
+			log.Printf("error deleting canary secret %s: %s", secret.Name, err.Error())
+		}
+	}
+}
+
+// createCanary replaces a canary that was left behind, such as when the operator restarted
+func createCanary(clientset kubernetes.Interface, canary *appsv1.Deployment) error {
+	deployments := clientset.AppsV1().Deployments(canary.Namespace)
+
+	propagationPolicy := metav1.DeletePropagationForeground
+	err := deployments.Delete(context.TODO(), canary.Name, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete previous canary")
+	}
+	if err == nil {
+		err := wait.PollImmediate(CanaryPollInterval, CanaryReadyTimeout, func() (bool, error) {
+			_, err := deployments.Get(context.TODO(), canary.Name, metav1.GetOptions{})
+			if kuberneteserrors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to wait for previous canary to be deleted")
+		}
+	}
+
+	if _, err := deployments.Create(context.TODO(), canary, metav1.CreateOptions{}); err != nil {
+		return errors.Wrap(err, "failed to create")
+	}
+
+	return nil
+}
+
+func deleteCanaries(clientset kubernetes.Interface, canaries []*appsv1.Deployment) {
+	propagationPolicy := metav1.DeletePropagationBackground
+	for _, canary := range canaries {
+		log.Printf("deleting canary deployment %s/%s", canary.Namespace, canary.Name)
+		err := clientset.AppsV1().Deployments(canary.Namespace).Delete(context.TODO(), canary.Name, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
+		if err != nil && !kuberneteserrors.IsNotFound(err) {
+			log.Printf("error deleting canary deployment %s: %s", canary.Name, err.Error())
+		}
+	}
+}
+
+// bakeCanary returns why the canary was aborted, or an empty string if the canary Deployments
+// became available and they, the status informers and the checks stayed healthy for the bake period
+func (c *Client) bakeCanary(clientset kubernetes.Interface, applicationManifests ApplicationManifests, canaries []*appsv1.Deployment) string {
+	var notAvailable error
+	err := wait.PollImmediate(CanaryPollInterval, CanaryReadyTimeout, func() (bool, error) {
+		notAvailable = canariesAvailable(clientset, canaries)
+		return notAvailable == nil, nil
+	})
+	if err != nil {
+		if notAvailable != nil {
+			err = notAvailable
+		}
+		return fmt.Sprintf("canary was not available within %s: %s", CanaryReadyTimeout, err.Error())
+	}
+
+	bakePeriod := DefaultCanaryBakePeriod
+	if applicationManifests.CanaryBakePeriod != "" {
+		d, err := time.ParseDuration(applicationManifests.CanaryBakePeriod)
+		if err != nil {
+			log.Printf("invalid canary bake period %q, using %s", applicationManifests.CanaryBakePeriod, bakePeriod)
+		} else {
+			bakePeriod = d
+		}
+	}
+
+	log.Printf("canary is available, baking for %s", bakePeriod)
+	deadline := time.Now().Add(bakePeriod)
+	for {
+		if err := canariesAvailable(clientset, canaries); err != nil {
+			return fmt.Sprintf("canary is no longer available: %s", err.Error())
+		}
+		if err := c.waitForStatusInformers(applicationManifests, CanaryCheckInterval); err != nil {
+			return fmt.Sprintf("app is not ready: %s", err.Error())
+		}
+		if err := evaluateCanaryChecks(applicationManifests.PrometheusAddress, applicationManifests.CanaryChecks); err != nil {
+			return err.Error()
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return ""
+		}
+		if remaining > CanaryCheckInterval {
+			remaining = CanaryCheckInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+func canariesAvailable(clientset kubernetes.Interface, canaries []*appsv1.Deployment) error {
+	notAvailable := []string{}
+	for _, canary := range canaries {
+		live, err := clientset.AppsV1().Deployments(canary.Namespace).Get(context.TODO(), canary.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get %s", canary.Name)
+		}
+
+		replicas := int32(1)
+		if live.Spec.Replicas != nil {
+			replicas = *live.Spec.Replicas
+		}
+		if live.Status.ObservedGeneration < live.Generation || live.Status.UpdatedReplicas < replicas || live.Status.AvailableReplicas < replicas {
+			notAvailable = append(notAvailable, fmt.Sprintf("%s has %d of %d replicas available", live.Name, live.Status.AvailableReplicas, replicas))
+		}
+	}
+
+	if len(notAvailable) > 0 {
+		return errors.New(strings.Join(notAvailable, ", "))
+	}
+	return nil
+}
+
+func evaluateCanaryChecks(prometheusAddress string, checks []CanaryCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	if prometheusAddress == "" {
+		return errors.New("canary checks require a prometheus address")
+	}
+
+	for _, check := range checks {
+		values, err := prometheus.Query(prometheusAddress, check.Query)
+		if err != nil {
+			return errors.Wrapf(err, "check %q failed", check.Name)
+		}
+		if err := checkCanaryValues(check, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkCanaryValues returns an error for the first value that is out of bounds. A query without
+// results passes, as there is no data to fail it.
+func checkCanaryValues(check CanaryCheck, values []float64) error {
+	for _, value := range values {
+		if check.Min != nil && value < *check.Min {
+			return errors.Errorf("check %q value %g is below the minimum of %g", check.Name, value, *check.Min)
+		}
+		if check.Max != nil && value > *check.Max {
+			return errors.Errorf("check %q value %g is above the maximum of %g", check.Name, value, *check.Max)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_newCanaryDeployment(t *testing.T) {
+	replicas := int32(3)
+	desired := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{"example.com/owner": "team"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "web"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Image: "nginx:1.19"}},
+				},
+			},
+		},
+	}
+
+	canaryReplicas := int32(1)
+	want := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web-canary",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "web", CanaryLabel: "true"},
+			Annotations: map[string]string{"example.com/owner": "team"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &canaryReplicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web", CanaryLabel: "true"},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "web", CanaryLabel: "true"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Image: "nginx:1.19"}},
+				},
+			},
+		},
+	}
+
+	got := newCanaryDeployment(desired, canaryReplicas)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newCanaryDeployment() = %#v, want %#v", got, want)
+	}
+	if _, ok := desired.Spec.Selector.MatchLabels[CanaryLabel]; ok {
+		t.Errorf("newCanaryDeployment() changed the desired deployment's selector")
+	}
+}
+
+func Test_useCanaryDependencies(t *testing.T) {
+	configMaps := map[string]*corev1.ConfigMap{
+		"default/web-config": {
+			ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Data:       map[string]string{"level": "debug"},
+		},
+	}
+	secrets := map[string]*corev1.Secret{
+		"default/web-password": {
+			ObjectMeta: metav1.ObjectMeta{Name: "web-password", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("new")},
+		},
+	}
+
+	canary := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-canary", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{
+						{
+							Name: "config",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}},
+							},
+						},
+						{
+							Name: "tls",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{SecretName: "web-tls"},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "web",
+							Image: "nginx:1.19",
+							Env: []corev1.EnvVar{
+								{
+									Name: "PASSWORD",
+									ValueFrom: &corev1.EnvVarSource{
+										SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "web-password"}, Key: "password"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	dependencies := &canaryDependencies{
+		configMaps: map[string]*corev1.ConfigMap{},
+		secrets:    map[string]*corev1.Secret{},
+	}
+	useCanaryDependencies(canary, configMaps, secrets, dependencies)
+
+	podSpec := canary.Spec.Template.Spec
+	if name := podSpec.Volumes[0].ConfigMap.Name; name != "web-config-canary" {
+		t.Errorf("config map volume = %q, want %q", name, "web-config-canary")
+	}
+	if name := podSpec.Volumes[1].Secret.SecretName; name != "web-tls" {
+		t.Errorf("secret volume that isn't in the manifests = %q, want %q", name, "web-tls")
+	}
+	if name := podSpec.Containers[0].Env[0].ValueFrom.SecretKeyRef.Name; name != "web-password-canary" {
+		t.Errorf("secret env = %q, want %q", name, "web-password-canary")
+	}
+
+	wantConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-config-canary", Namespace: "default", Labels: map[string]string{"app": "web", CanaryLabel: "true"}},
+		Data:       map[string]string{"level": "debug"},
+	}
+	if got := dependencies.configMaps["web-config-canary"]; !reflect.DeepEqual(got, wantConfigMap) {
+		t.Errorf("canary config map = %#v, want %#v", got, wantConfigMap)
+	}
+	if _, ok := dependencies.secrets["web-password-canary"]; !ok || len(dependencies.secrets) != 1 {
+		t.Errorf("canary secrets = %#v, want web-password-canary", dependencies.secrets)
+	}
+	if configMaps["default/web-config"].Name != "web-config" {
+		t.Errorf("useCanaryDependencies() changed the config map in the manifests")
+	}
+}
+
+func Test_checkCanaryValues(t *testing.T) {
+	min := float64(0)
+	max := float64(0.05)
+
+	tests := []struct {
+		name    string
+		check   CanaryCheck
+		values  []float64
+		wantErr bool
+	}{
+		{
+			name:   "no results",
+			check:  CanaryCheck{Name: "errors", Max: &max},
+			values: []float64{},
+		},
+		{
+			name:   "within bounds",
+			check:  CanaryCheck{Name: "errors", Min: &min, Max: &max},
+			values: []float64{0, 0.01, 0.05},
+		},
+		{
+			name:    "above max",
+			check:   CanaryCheck{Name: "errors", Max: &max},
+			values:  []float64{0.01, 0.2},
+			wantErr: true,
+		},
+		{
+			name:    "below min",
+			check:   CanaryCheck{Name: "throughput", Min: &min},
+			values:  []float64{-1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCanaryValues(tt.check, tt.values); (err != nil) != tt.wantErr {
+				t.Errorf("checkCanaryValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
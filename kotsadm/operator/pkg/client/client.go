@@ -6,9 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"net/url"
 	"os/exec"
-	"strconv"
 	"sync"
 	"time"
 
@@ -17,21 +15,27 @@ import (
 	"github.com/replicatedhq/kots/kotsadm/operator/pkg/applier"
 	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate"
 	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
-	"github.com/replicatedhq/kots/kotsadm/operator/pkg/socket"
-	"github.com/replicatedhq/kots/kotsadm/operator/pkg/socket/transport"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/protocol"
 	"github.com/replicatedhq/kots/kotsadm/operator/pkg/util"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 var (
-	PollInterval = time.Second * 10
+	PollInterval      = time.Second * 10
+	DriftScanInterval = time.Minute * 5
+)
+
+const (
+	messageQueueSize = 100
 )
 
 type ApplicationManifests struct {
 	AppID                string   `json:"app_id"`
 	AppSlug              string   `json:"app_slug"`
 	KubectlVersion       string   `json:"kubectl_version"`
+	Applier              string   `json:"applier"`
 	AdditionalNamespaces []string `json:"additional_namespaces"`
 	ImagePullSecret      string   `json:"image_pull_secret"`
 	Namespace            string   `json:"namespace"`
@@ -41,7 +45,28 @@ type ApplicationManifests struct {
 	ResultCallback       string   `json:"result_callback"`
 	ClearNamespaces      []string `json:"clear_namespaces"`
 	ClearPVCs            bool     `json:"clear_pvcs"`
+	RestoreNamespaces    []string `json:"restore_namespaces"`
 	AnnotateSlug         bool     `json:"annotate_slug"`
+
+	AutoRollback             bool                         `json:"auto_rollback"`
+	AutoRollbackReadyTimeout string                       `json:"auto_rollback_ready_timeout"`
+	StatusInformers          []types.StatusInformerString `json:"status_informers"`
+
+	DriftReapply bool `json:"drift_reapply"`
+
+	RolloutStrategy   string        `json:"rollout_strategy"`
+	CanaryReplicas    int32         `json:"canary_replicas"`
+	CanaryBakePeriod  string        `json:"canary_bake_period"`
+	CanaryChecks      []CanaryCheck `json:"canary_checks"`
+	PrometheusAddress string        `json:"prometheus_address"`
+
+	// isRollback is set when re-applying the previous manifests, which doesn't run hooks
+	isRollback bool
+	// isReapply is set when re-applying the manifests to revert drift, which doesn't run hooks
+	// and takes over fields that were changed by hand
+	isReapply bool
+	// canary is the result of the canary that ran before the deploy, which is reported with the deploy result
+	canary *canaryResult
 }
 
 // DesiredState is what we receive from the kotsadm-api server
@@ -76,6 +101,15 @@ type Client struct {
 	appStateMonitor   *appstate.Monitor
 	hookStopChans     []chan struct{}
 	namespaceStopChan chan struct{}
+
+	// deployMtx keeps drift from being re-applied while a deploy is in progress
+	deployMtx        sync.Mutex
+	appliedMtx       sync.Mutex
+	appliedManifests map[string]ApplicationManifests
+
+	// the session is resumed when reconnecting, so that messages are neither lost nor handled twice
+	sessionID     string
+	lastMessageID uint64
 }
 
 // Run is the main entrypoint of the operator when running in standard, normal operations
@@ -87,36 +121,65 @@ func (c *Client) Run() error {
 
 	defer c.shutdownNamespacesInformer()
 
+	restconfig, err := rest.InClusterConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get in cluster config")
+	}
+	clientset, err := kubernetes.NewForConfig(restconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to get new kubernetes client")
+	}
+	dynamicClient, err := dynamic.NewForConfig(restconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to get new dynamic client")
+	}
+
+	// the monitor outlives connections, as informers are not sent again when a session is resumed
+	c.appStateMonitor = appstate.NewMonitor(clientset, dynamicClient, c.TargetNamespace)
+	defer c.appStateMonitor.Shutdown()
+
+	go c.runAppStateMonitor()
+	go c.runDriftScanner()
+
+	backoff := protocol.NewBackoff()
 	for {
-		err := c.connect()
-		if err != nil {
-			// this needs a backoff
-			log.Println("unable to connect to api")
-			time.Sleep(time.Second * 2)
-			continue
+		if err := c.connect(backoff); err != nil {
+			log.Printf("disconnected from api: %s", err.Error())
 		}
 
-		// some easy backoff for now
-		time.Sleep(time.Second * 2)
+		delay := backoff.Next()
+		log.Printf("reconnecting to api in %s", delay)
+		time.Sleep(delay)
 	}
 }
 
 func (c *Client) runAppStateMonitor() error {
 	m := map[string]func(f func()){}
 	hash := map[string]uint64{}
+	// the throttle only sends the latest app status, so transitions are collected until they are sent
+	pendingTransitions := map[string][]types.ResourceStateTransition{}
 	var mtx sync.Mutex
 
 	for appStatus := range c.appStateMonitor.AppStatusChan() {
+		appStatus := appStatus
+
 		throttled, ok := m[appStatus.AppID]
 		if !ok {
 			throttled = util.NewThrottle(time.Second)
 			m[appStatus.AppID] = throttled
 		}
+
+		mtx.Lock()
+		pendingTransitions[appStatus.AppID] = append(pendingTransitions[appStatus.AppID], appStatus.Transitions...)
+		mtx.Unlock()
+
 		throttled(func() {
 			mtx.Lock()
 			lastHash := hash[appStatus.AppID]
 			nextHash, _ := hashstructure.Hash(appStatus, nil)
 			hash[appStatus.AppID] = nextHash
+			appStatus.Transitions = pendingTransitions[appStatus.AppID]
+			delete(pendingTransitions, appStatus.AppID)
 			mtx.Unlock()
 			if lastHash != nextHash {
 				b, _ := json.Marshal(appStatus)
@@ -124,6 +187,10 @@ func (c *Client) runAppStateMonitor() error {
 			}
 			if err := c.sendAppStatus(appStatus); err != nil {
 				log.Printf("error sending app status: %v", err)
+				// send the transitions with the next app status instead
+				mtx.Lock()
+				pendingTransitions[appStatus.AppID] = append(appStatus.Transitions, pendingTransitions[appStatus.AppID]...)
+				mtx.Unlock()
 			}
 		})
 	}
@@ -131,189 +198,240 @@ func (c *Client) runAppStateMonitor() error {
 	return errors.New("app state monitor shutdown")
 }
 
-// connect will return an error on a fatal error, or nil if the server
-// disconnected us or a network problem disconnected us
-func (c *Client) connect() error {
-	u, err := url.Parse(c.APIEndpoint)
-	if err != nil {
-		return errors.Wrap(err, "failed to parse url")
-	}
-
-	port, err := strconv.Atoi(u.Port())
-	if err != nil {
-		return errors.Wrap(err, "failed to parse port")
-	}
-
-	hasConnected := false
-	isUnexpectedlyDisconnected := false
-
+// connect runs a session with the api until the connection is lost. The backoff is reset once connected.
+func (c *Client) connect(backoff *protocol.Backoff) error {
 	log.Println("connecting to api")
-	socketClient := socket.NewClient()
-
-	err = socketClient.On(socket.OnConnection, func(h *socket.Channel) {
-		log.Println("received a connection event")
-		hasConnected = true
-	})
-	if err != nil {
-		return errors.Wrap(err, "failed to register connected handler")
-	}
 
-	err = socketClient.On(socket.OnDisconnection, func(h *socket.Channel, args interface{}) {
-		log.Printf("received a disconnected event %#v", args)
-		isUnexpectedlyDisconnected = true
+	conn, err := protocol.Dial(c.APIEndpoint, c.Token, protocol.Hello{
+		MinVersion: protocol.MinVersion,
+		MaxVersion: protocol.Version,
+		SessionID:  c.sessionID,
+		LastID:     c.lastMessageID,
 	})
-	if err != nil {
-		return errors.Wrap(err, "failed to register disconnected handler")
-	}
-
-	if err := c.registerHandlers(socketClient); err != nil {
-		return errors.Wrap(err, "failed to register handlers")
-	}
-
-	err = socketClient.Dial(socket.GetUrl(u.Hostname(), port, c.Token, false), transport.GetDefaultWebsocketTransport())
 	if err != nil {
 		return errors.Wrap(err, "failed to connect")
 	}
-	defer socketClient.Close()
+	defer conn.Close()
 
-	restconfig, err := rest.InClusterConfig()
-	if err != nil {
-		return errors.Wrap(err, "failed to get in cluster config")
-	}
-	clientset, err := kubernetes.NewForConfig(restconfig)
-	if err != nil {
-		return errors.Wrap(err, "failed to get new kubernetes client")
+	backoff.Reset()
+
+	if conn.Welcome.SessionID == c.sessionID {
+		log.Printf("resumed session %s after message %d", c.sessionID, c.lastMessageID)
+	} else {
+		log.Printf("started session %s for cluster %s with protocol version %d", conn.Welcome.SessionID, conn.Welcome.ClusterID, conn.Welcome.Version)
+		c.sessionID = conn.Welcome.SessionID
+		c.lastMessageID = 0
 	}
 
-	c.appStateMonitor = appstate.NewMonitor(clientset, c.TargetNamespace)
-	defer c.appStateMonitor.Shutdown()
+	// messages are read in the background so that pings are answered while a message is handled.
+	// if the queue fills up, reading blocks until the connection times out, and unacknowledged
+	// messages are sent again when the session is resumed.
+	envelopes := make(chan protocol.Envelope, messageQueueSize)
+	readErrCh := make(chan error, 1)
+	go func() {
+		defer close(envelopes)
+		for {
+			envelope, err := conn.Next()
+			if err != nil {
+				readErrCh <- err
+				return
+			}
+			envelopes <- envelope
+		}
+	}()
 
-	go c.runAppStateMonitor()
+	for envelope := range envelopes {
+		if envelope.ID != 0 && envelope.ID <= c.lastMessageID {
+			// this was handled, but the ack was lost
+			if err := conn.Ack(envelope.ID); err != nil {
+				return errors.Wrap(err, "failed to ack message")
+			}
+			continue
+		}
 
-	// wait for a connection for at least 2 seconds
-	time.Sleep(time.Second * 2)
-	if !hasConnected {
-		log.Println("expected to be connected to the api by now, but it's not true. disappointing...  (will retry)")
-		return nil // allow another attempt
-	}
+		c.handleMessage(envelope)
 
-	for {
-		if isUnexpectedlyDisconnected {
-			log.Println("unexpectedly disconnected from api (will reconnect)")
-			return nil
+		if envelope.ID != 0 {
+			c.lastMessageID = envelope.ID
+			if err := conn.Ack(envelope.ID); err != nil {
+				return errors.Wrap(err, "failed to ack message")
+			}
 		}
-
-		time.Sleep(time.Second)
 	}
+
+	return <-readErrCh
 }
 
-func (c *Client) registerHandlers(socketClient *socket.Client) error {
+func (c *Client) handleMessage(envelope protocol.Envelope) {
 	var err error
-
-	err = socketClient.On("preflight", func(h *socket.Channel, args PreflightRequest) {
-		log.Printf("received a preflight event: %#v", args)
-		if err := runPreflight(args.URI, args.IgnorePermissions); err != nil {
-			log.Printf("error running preflight: %s", err.Error())
+	switch envelope.Type {
+	case protocol.MessageTypePreflight:
+		args := PreflightRequest{}
+		if err = envelope.Decode(&args); err == nil {
+			c.handlePreflight(args)
 		}
-	})
+	case protocol.MessageTypeDeploy:
+		args := ApplicationManifests{}
+		if err = envelope.Decode(&args); err == nil {
+			c.handleDeploy(args)
+		}
+	case protocol.MessageTypeSupportBundle:
+		args := SupportBundleRequest{}
+		if err = envelope.Decode(&args); err == nil {
+			c.handleSupportBundle(args)
+		}
+	case protocol.MessageTypeAppInformers:
+		args := InformRequest{}
+		if err = envelope.Decode(&args); err == nil {
+			c.handleAppInformers(args)
+		}
+	default:
+		log.Printf("ignoring unknown message type %q", envelope.Type)
+	}
 	if err != nil {
-		return errors.Wrap(err, "failed to add preflight handler")
-	}
-
-	err = socketClient.On("deploy", func(h *socket.Channel, args ApplicationManifests) {
-		log.Println("received a deploy request")
-
-		var result *applyResult
-		var deployError error
-		defer func() {
-			if result != nil {
-				err := c.sendResult(
-					args, result.hasErr, []byte{}, []byte{},
-					bytes.Join(result.multiStdout, []byte("\n")), bytes.Join(result.multiStderr, []byte("\n")),
-				)
-				if err != nil {
-					log.Printf("failed to report result: %v", err)
-				}
-				return
-			}
+		log.Printf("error handling %s message: %s", envelope.Type, err.Error())
+	}
+}
 
-			if deployError != nil {
-				err := c.sendResult(
-					args, true, []byte{}, []byte{},
-					nil, []byte(deployError.Error()),
-				)
-				if err != nil {
-					log.Printf("failed to report result: %v", err)
-				}
-				return
-			}
-		}()
+func (c *Client) handlePreflight(args PreflightRequest) {
+	log.Printf("received a preflight event: %#v", args)
+	if err := runPreflight(args.URI, args.IgnorePermissions); err != nil {
+		log.Printf("error running preflight: %s", err.Error())
+	}
+}
 
-		if args.PreviousManifests != "" {
-			if deployError = c.diffAndRemovePreviousManifests(args); deployError != nil {
-				log.Printf("error diffing and removing previous manifests: %s", deployError.Error())
-				return
-			}
+func (c *Client) handleDeploy(args ApplicationManifests) {
+	log.Println("received a deploy request")
+
+	c.deployMtx.Lock()
+	defer c.deployMtx.Unlock()
+
+	var result *applyResult
+	var deployError error
+	var rolledBack bool
+	defer func() {
+		if rolledBack {
+			return
 		}
 
-		for _, additionalNamespace := range args.AdditionalNamespaces {
-			if additionalNamespace == "*" {
-				continue
+		if result != nil {
+			err := c.sendResult(
+				args, result.hasErr, result.dryrunStdout, result.dryrunStderr,
+				bytes.Join(result.multiStdout, []byte("\n")), bytes.Join(result.multiStderr, []byte("\n")),
+				result.dryrunObjects, result.objects,
+			)
+			if err != nil {
+				log.Printf("failed to report result: %v", err)
 			}
+			return
+		}
 
-			if deployError = c.ensureNamespacePresent(additionalNamespace); deployError != nil {
-				// we don't fail here...
-				log.Printf("error creating namespace: %s", deployError.Error())
+		if deployError != nil {
+			err := c.sendResult(
+				args, true, []byte{}, []byte{},
+				nil, []byte(deployError.Error()),
+				nil, nil,
+			)
+			if err != nil {
+				log.Printf("failed to report result: %v", err)
 			}
+			return
 		}
-		c.imagePullSecret = args.ImagePullSecret
-		c.watchedNamespaces = args.AdditionalNamespaces
+	}()
 
-		result, deployError = c.ensureResourcesPresent(args)
+	if args.RolloutStrategy == RolloutStrategyCanary && args.PreviousManifests != "" {
+		args.canary, deployError = c.runCanary(args)
 		if deployError != nil {
-			log.Printf("error deploying: %s", deployError.Error())
+			deployError = errors.Wrap(deployError, "failed to run canary")
+			log.Printf("error running canary: %s", deployError.Error())
 			return
 		}
+		if !args.canary.Promoted {
+			log.Printf("canary aborted: %s", args.canary.AbortReason)
+			deployError = errors.Errorf("canary aborted: %s", args.canary.AbortReason)
+			return
+		}
+		log.Printf("canary promoted")
+	}
 
-		c.shutdownNamespacesInformer()
-		c.runNamespacesInformer()
+	if args.PreviousManifests != "" {
+		if deployError = c.diffAndRemovePreviousManifests(args); deployError != nil {
+			log.Printf("error diffing and removing previous manifests: %s", deployError.Error())
+			return
+		}
+	}
 
-	})
-	if err != nil {
-		return errors.Wrap(err, "failed to add deploy handler")
+	for _, additionalNamespace := range args.AdditionalNamespaces {
+		if additionalNamespace == "*" {
+			continue
+		}
+
+		if deployError = c.ensureNamespacePresent(additionalNamespace); deployError != nil {
+			// we don't fail here...
+			log.Printf("error creating namespace: %s", deployError.Error())
+		}
 	}
+	c.imagePullSecret = args.ImagePullSecret
+	c.watchedNamespaces = args.AdditionalNamespaces
+
+	result, deployError = c.ensureResourcesPresent(args)
 
-	err = socketClient.On("supportbundle", func(h *socket.Channel, args SupportBundleRequest) {
-		log.Println("received a support bundle request")
-		go func() {
-			startTime := time.Now()
-			// This is in a goroutine because if we disconnect and reconnect to the
-			// websocket, we will want to report that it's completed...
-			err := runSupportBundle(args.URI)
-			log.Printf("support bundle run completed in %s", time.Since(startTime).String())
+	if args.AutoRollback && args.PreviousManifests != "" {
+		if reason := c.rollbackReason(args, result, deployError); reason != "" {
+			log.Printf("rolling back: %s", reason)
+			rollbackResult, err := c.rollback(args)
 			if err != nil {
-				log.Printf("error running support bundle: %s", err.Error())
+				log.Printf("error rolling back: %s", err.Error())
+				result = nil
+				deployError = errors.Wrapf(err, "failed to roll back after %s", reason)
+				return
 			}
-		}()
-	})
-	if err != nil {
-		return errors.Wrap(err, "failed to add support bundle handler")
-	}
 
-	err = socketClient.On("appInformers", func(h *socket.Channel, args InformRequest) {
-		log.Printf("received an inform event: %#v", args)
-		if err := c.applyAppInformers(args.AppID, args.Informers); err != nil {
-			log.Printf("error running informer: %s", err.Error())
+			rolledBack = true
+			if err := c.sendRolledBackResult(args, reason, rollbackResult); err != nil {
+				log.Printf("failed to report rolled back result: %v", err)
+			}
+			c.setAppliedManifests(args.AppID, args.PreviousManifests, args)
+			return
 		}
-	})
-	if err != nil {
-		return errors.Wrap(err, "failed to add inform handler")
 	}
 
-	return nil
+	if deployError != nil {
+		log.Printf("error deploying: %s", deployError.Error())
+		return
+	}
+
+	if result != nil && !result.hasErr {
+		c.setAppliedManifests(args.AppID, args.Manifests, args)
+	}
+
+	c.shutdownNamespacesInformer()
+	c.runNamespacesInformer()
 }
 
-func (c *Client) sendResult(applicationManifests ApplicationManifests, isError bool, dryrunStdout []byte, dryrunStderr []byte, applyStdout []byte, applyStderr []byte) error {
+func (c *Client) handleSupportBundle(args SupportBundleRequest) {
+	log.Println("received a support bundle request")
+	go func() {
+		startTime := time.Now()
+		// This is in a goroutine because if we disconnect and reconnect to the
+		// websocket, we will want to report that it's completed...
+		err := runSupportBundle(args.URI)
+		log.Printf("support bundle run completed in %s", time.Since(startTime).String())
+		if err != nil {
+			log.Printf("error running support bundle: %s", err.Error())
+		}
+	}()
+}
+
+func (c *Client) handleAppInformers(args InformRequest) {
+	log.Printf("received an inform event: %#v", args)
+	if err := c.applyAppInformers(args.AppID, args.Informers); err != nil {
+		log.Printf("error running informer: %s", err.Error())
+	}
+}
+
+// sendResult reports the outcome of a deploy. The objects are only set when the applier reports on each object.
+func (c *Client) sendResult(applicationManifests ApplicationManifests, isError bool, dryrunStdout []byte, dryrunStderr []byte, applyStdout []byte, applyStderr []byte, dryrunObjects []applier.ObjectResult, applyObjects []applier.ObjectResult) error {
 	if applicationManifests.ResultCallback == "" {
 		return nil
 	}
@@ -328,6 +446,11 @@ func (c *Client) sendResult(applicationManifests ApplicationManifests, isError b
 		DryrunStderr []byte `json:"dryrun_stderr"`
 		ApplyStdout  []byte `json:"apply_stdout"`
 		ApplyStderr  []byte `json:"apply_stderr"`
+
+		DryrunResults []applier.ObjectResult `json:"dryrun_results,omitempty"`
+		ApplyResults  []applier.ObjectResult `json:"apply_results,omitempty"`
+
+		Canary *canaryResult `json:"canary,omitempty"`
 	}{
 		applicationManifests.AppID,
 		isError,
@@ -335,8 +458,15 @@ func (c *Client) sendResult(applicationManifests ApplicationManifests, isError b
 		dryrunStderr,
 		applyStdout,
 		applyStderr,
+		dryrunObjects,
+		applyObjects,
+		applicationManifests.canary,
 	}
 
+	return c.putResult(uri, applyResult)
+}
+
+func (c *Client) putResult(uri string, applyResult interface{}) error {
 	b, err := json.Marshal(applyResult)
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal results")
@@ -451,8 +581,28 @@ func (c *Client) sendAppStatus(appStatus types.AppStatus) error {
 	return nil
 }
 
-func (c *Client) getApplier(kubectlVersion string) (*applier.Kubectl, error) {
-	kubectl, err := util.FindKubectlVersion(kubectlVersion)
+// getApplier returns the applier selected in the app's kots Application spec.
+// Server-side apply does not need kubectl.
+func (c *Client) getApplier(applicationManifests ApplicationManifests) (applier.Applier, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get in cluster config")
+	}
+
+	switch applicationManifests.Applier {
+	case applier.ServerSideName:
+		serverSide, err := applier.NewServerSide(config, c.TargetNamespace)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create server-side applier")
+		}
+		serverSide.Force = applicationManifests.isReapply
+		return serverSide, nil
+	case "", "kubectl":
+	default:
+		log.Printf("unknown applier %q, using kubectl", applicationManifests.Applier)
+	}
+
+	kubectl, err := util.FindKubectlVersion(applicationManifests.KubectlVersion)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to find kubectl")
 	}
@@ -469,10 +619,5 @@ func (c *Client) getApplier(kubectlVersion string) (*applier.Kubectl, error) {
 		supportBundle = localSupportBundle
 	}
 
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get in cluster config")
-	}
-
 	return applier.NewKubectl(kubectl, preflight, supportBundle, config), nil
 }
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"fmt"
@@ -10,7 +11,8 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/kotsadm/operator/pkg/applier"
-	"github.com/replicatedhq/kots/kotsadm/operator/pkg/util"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
@@ -32,9 +34,23 @@ import (
 var metadataAccessor = meta.NewAccessor()
 
 type applyResult struct {
-	hasErr      bool
-	multiStdout [][]byte
-	multiStderr [][]byte
+	hasErr bool
+	// notApplied is set when the dry run or the first apply failed, before anything else was applied
+	notApplied    bool
+	dryrunStdout  []byte
+	dryrunStderr  []byte
+	dryrunObjects []applier.ObjectResult
+	multiStdout   [][]byte
+	multiStderr   [][]byte
+	objects       []applier.ObjectResult
+}
+
+// stderr is the output of whichever of the dry run or the apply failed
+func (r *applyResult) stderr() []byte {
+	if r.notApplied && len(r.dryrunStderr) > 0 {
+		return r.dryrunStderr
+	}
+	return bytes.Join(r.multiStderr, []byte("\n"))
 }
 
 func (c *Client) diffAndRemovePreviousManifests(applicationManifests ApplicationManifests) error {
@@ -67,21 +83,28 @@ func (c *Client) diffAndRemovePreviousManifests(applicationManifests Application
 		decodedCurrentMap[GetGVKWithNameAndNs([]byte(decodedCurrentString), targetNamespace)] = decodedCurrentString
 	}
 
-	// now remove anything that's in previous but not in current
-	kubectl, err := util.FindKubectlVersion(applicationManifests.KubectlVersion)
-	if err != nil {
-		return errors.Wrap(err, "failed to find kubectl")
+	// the app is being removed, not upgraded
+	if len(decodedCurrent) == 0 {
+		hookNamespace := targetNamespace
+		if hookNamespace == "" {
+			hookNamespace = corev1.NamespaceDefault
+		}
+		hookJobs, _, err := splitHookJobs(decodedPrevious, hookNamespace)
+		if err != nil {
+			return errors.Wrap(err, "failed to split hook jobs from previous manifests")
+		}
+		if err := c.runHooks(hookJobs, HookPreDelete, applicationManifests.AppSlug, applicationManifests.AnnotateSlug); err != nil {
+			return errors.Wrapf(err, "failed to run %s hooks", HookPreDelete)
+		}
 	}
-	config, err := rest.InClusterConfig()
+
+	// now remove anything that's in previous but not in current
+	kubernetesApplier, err := c.getApplier(applicationManifests)
 	if err != nil {
-		return errors.Wrap(err, "failed to get in cluster config")
+		return errors.Wrap(err, "failed to get applier")
 	}
 
-	// this is pretty raw, and required kubectl...  we should
-	// consider some other options here?
-	kubernetesApplier := applier.NewKubectl(kubectl, "", "", config)
-
-	allPVCs := make([]string, 0)
+	allPVCs := map[string][]string{}
 	for k, oldContents := range decodedPreviousMap {
 		if _, ok := decodedCurrentMap[k]; ok {
 			continue
@@ -104,6 +127,10 @@ func (c *Client) diffAndRemovePreviousManifests(applicationManifests Application
 			name, _ = metadataAccessor.Name(obj)
 		}
 
+		if !isRestoredNamespace(namespace, applicationManifests.RestoreNamespaces) {
+			continue
+		}
+
 		if obj != nil && gvk != nil {
 			group = gvk.Group
 			kind = gvk.Kind
@@ -113,7 +140,7 @@ func (c *Client) diffAndRemovePreviousManifests(applicationManifests Application
 			if err != nil {
 				return errors.Wrap(err, "failed to list PVCs")
 			}
-			allPVCs = append(allPVCs, pvcs...)
+			allPVCs[namespace] = append(allPVCs[namespace], pvcs...)
 		}
 
 		wait := applicationManifests.Wait
@@ -123,10 +150,12 @@ func (c *Client) diffAndRemovePreviousManifests(applicationManifests Application
 			wait = false
 		}
 
-		stdout, stderr, err := kubernetesApplier.Remove(namespace, []byte(oldContents), wait)
+		removeResult, err := kubernetesApplier.Remove(namespace, []byte(oldContents), wait)
 		if err != nil {
-			log.Printf("stdout (delete) = %s", stdout)
-			log.Printf("stderr (delete) = %s", stderr)
+			if removeResult != nil {
+				log.Printf("stdout (delete) = %s", removeResult.Stdout)
+				log.Printf("stderr (delete) = %s", removeResult.Stderr)
+			}
 			log.Printf("error: %s", err.Error())
 		} else {
 			log.Printf("manifest(s) deleted: %s/%s/%s", group, kind, name)
@@ -134,11 +163,12 @@ func (c *Client) diffAndRemovePreviousManifests(applicationManifests Application
 	}
 
 	if applicationManifests.ClearPVCs {
-		log.Printf("deleting pvcs: %s", strings.Join(allPVCs, ","))
-		// TODO: multi-namespace support
-		err := deletePVCs(targetNamespace, allPVCs)
-		if err != nil {
-			return errors.Wrap(err, "failed to delete PVCs")
+		for namespace, pvcs := range allPVCs {
+			log.Printf("deleting pvcs in namespace %s: %s", namespace, strings.Join(pvcs, ","))
+			err := deletePVCs(namespace, pvcs)
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete PVCs in namespace %s", namespace)
+			}
 		}
 	}
 
@@ -204,7 +234,7 @@ func (c *Client) ensureResourcesPresent(applicationManifests ApplicationManifest
 		targetNamespace = applicationManifests.Namespace
 	}
 
-	kubernetesApplier, err := c.getApplier(applicationManifests.KubectlVersion)
+	kubernetesApplier, err := c.getApplier(applicationManifests)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get applier")
 	}
@@ -214,6 +244,25 @@ func (c *Client) ensureResourcesPresent(applicationManifests ApplicationManifest
 		return nil, errors.Wrap(err, "failed to decode manifests")
 	}
 
+	hookNamespace := targetNamespace
+	if hookNamespace == "" {
+		hookNamespace = corev1.NamespaceDefault
+	}
+	hookJobs, decoded, err := splitHookJobs(decoded, hookNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to split hook jobs from decoded")
+	}
+
+	if applicationManifests.isRollback || applicationManifests.isReapply {
+		// hook jobs are still left out of the apply, but are not run again
+		hookJobs = nil
+	}
+
+	preHook, postHook := HookPreInstall, HookPostInstall
+	if applicationManifests.PreviousManifests != "" {
+		preHook, postHook = HookPreUpgrade, HookPostUpgrade
+	}
+
 	firstApplyDocs, otherDocs, err := splitMutlidocYAMLIntoFirstApplyAndOthers(decoded)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to split decoded into crds and other")
@@ -234,42 +283,64 @@ func (c *Client) ensureResourcesPresent(applicationManifests ApplicationManifest
 			}
 
 			log.Printf("dry run applying manifests(s) in requested namespace: %s", requestedNamespace)
-			dryrunStdout, dryrunStderr, dryRunErr := kubernetesApplier.Apply(requestedNamespace, applicationManifests.AppSlug, docs, true, applicationManifests.Wait, applicationManifests.AnnotateSlug)
+			dryrunResult, dryRunErr := kubernetesApplier.Apply(requestedNamespace, applicationManifests.AppSlug, docs, true, applicationManifests.Wait, applicationManifests.AnnotateSlug)
+			if dryrunResult == nil {
+				dryrunResult = &applier.Result{}
+			}
 			if dryRunErr != nil {
-				log.Printf("stdout (dryrun) = %s", dryrunStdout)
-				log.Printf("stderr (dryrun) = %s", dryrunStderr)
+				log.Printf("stdout (dryrun) = %s", dryrunResult.Stdout)
+				log.Printf("stderr (dryrun) = %s", dryrunResult.Stderr)
 				log.Printf("error: %s", dryRunErr.Error())
 			} else {
 				log.Printf("dry run applied manifests(s) in requested namespace: %s", requestedNamespace)
 			}
 
 			if dryRunErr != nil {
-				if err := c.sendResult(applicationManifests, true, dryrunStdout, dryrunStderr, []byte{}, []byte{}); err != nil {
-					return nil, errors.Wrap(err, "failed to report dry run status")
+				// don't return an error because execution is proper, the result has the error
+				result := &applyResult{
+					hasErr:        true,
+					notApplied:    true,
+					dryrunStdout:  dryrunResult.Stdout,
+					dryrunStderr:  dryrunResult.Stderr,
+					dryrunObjects: dryrunResult.Objects,
 				}
-
-				return nil, nil // don't return an error because execution is proper, the api now has the error
+				return result, nil
 			}
 		}
 
 	}
 
+	if err := c.runHooks(hookJobs, preHook, applicationManifests.AppSlug, applicationManifests.AnnotateSlug); err != nil {
+		log.Printf("error running %s hooks: %s", preHook, err.Error())
+		result := &applyResult{
+			hasErr:      true,
+			multiStderr: [][]byte{[]byte(err.Error())},
+		}
+		return result, nil
+	}
+
 	if len(firstApplyDocs) > 0 {
 		log.Println("applying first apply docs (CRDs, Namespaces)")
 
 		// CRDs don't have namespaces, so we can skip splitting
 
-		applyStdout, applyStderr, applyErr := kubernetesApplier.Apply("", applicationManifests.AppSlug, firstApplyDocs, false, applicationManifests.Wait, applicationManifests.AnnotateSlug)
+		firstApplyResult, applyErr := kubernetesApplier.Apply("", applicationManifests.AppSlug, firstApplyDocs, false, applicationManifests.Wait, applicationManifests.AnnotateSlug)
+		if firstApplyResult == nil {
+			firstApplyResult = &applier.Result{}
+		}
 		if applyErr != nil {
-			log.Printf("stdout (first apply) = %s", applyStdout)
-			log.Printf("stderr (first apply) = %s", applyStderr)
+			log.Printf("stdout (first apply) = %s", firstApplyResult.Stdout)
+			log.Printf("stderr (first apply) = %s", firstApplyResult.Stderr)
 			log.Printf("error (CRDS): %s", applyErr.Error())
 
-			if err := c.sendResult(applicationManifests, applyErr != nil, []byte{}, []byte{}, applyStdout, applyStderr); err != nil {
-				return nil, errors.Wrap(err, "failed to report crd status")
+			result := &applyResult{
+				hasErr:      true,
+				notApplied:  true,
+				multiStdout: [][]byte{firstApplyResult.Stdout},
+				multiStderr: [][]byte{firstApplyResult.Stderr},
+				objects:     firstApplyResult.Objects,
 			}
-
-			return nil, nil
+			return result, nil
 		} else {
 			log.Println("custom resource definition(s) applied")
 		}
@@ -278,44 +349,123 @@ func (c *Client) ensureResourcesPresent(applicationManifests ApplicationManifest
 		time.Sleep(time.Second * 5)
 	}
 
-	byNamespace, err := docsByNamespace(otherDocs, targetNamespace)
+	phases, err := splitMultidocYAMLIntoPhases(otherDocs)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get docs by requested namespace")
+		return nil, errors.Wrap(err, "failed to split decoded into deploy phases")
 	}
 
-	var hasErr bool
-	var multiStdout, multiStderr [][]byte
-	for requestedNamespace, docs := range byNamespace {
-		if len(docs) == 0 {
-			continue
+	result := &applyResult{}
+	for i, phase := range phases {
+		if len(phases) > 1 {
+			log.Printf("applying deploy phase %s", phase)
 		}
 
-		log.Printf("applying manifest(s) in namespace %s", requestedNamespace)
-		applyStdout, applyStderr, applyErr := kubernetesApplier.Apply(requestedNamespace, applicationManifests.AppSlug, docs, false, applicationManifests.Wait, applicationManifests.AnnotateSlug)
-		if applyErr != nil {
-			log.Printf("stdout (apply) = %s", applyStdout)
-			log.Printf("stderr (apply) = %s", applyStderr)
-			log.Printf("error: %s", applyErr.Error())
-			hasErr = true
-		} else {
-			log.Printf("manifest(s) applied in namespace %s", requestedNamespace)
+		byNamespace, err := docsByNamespace(phase.docs, targetNamespace)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get docs by requested namespace")
+		}
+
+		for requestedNamespace, docs := range byNamespace {
+			if len(docs) == 0 {
+				continue
+			}
+
+			log.Printf("applying manifest(s) in namespace %s", requestedNamespace)
+			nsApplyResult, applyErr := kubernetesApplier.Apply(requestedNamespace, applicationManifests.AppSlug, docs, false, applicationManifests.Wait, applicationManifests.AnnotateSlug)
+			if nsApplyResult == nil {
+				nsApplyResult = &applier.Result{}
+			}
+			if applyErr != nil {
+				log.Printf("stdout (apply) = %s", nsApplyResult.Stdout)
+				log.Printf("stderr (apply) = %s", nsApplyResult.Stderr)
+				log.Printf("error: %s", applyErr.Error())
+				result.hasErr = true
+			} else {
+				log.Printf("manifest(s) applied in namespace %s", requestedNamespace)
+			}
+			if len(nsApplyResult.Stdout) > 0 {
+				result.multiStdout = append(result.multiStdout, nsApplyResult.Stdout)
+			}
+			if len(nsApplyResult.Stderr) > 0 {
+				result.multiStderr = append(result.multiStderr, nsApplyResult.Stderr)
+			}
+			result.objects = append(result.objects, nsApplyResult.Objects...)
 		}
-		if len(applyStdout) > 0 {
-			multiStdout = append(multiStdout, applyStdout)
+
+		// the last phase has nothing to wait for
+		if i == len(phases)-1 {
+			break
+		}
+
+		if result.hasErr {
+			result.multiStderr = append(result.multiStderr, []byte(fmt.Sprintf("deploy phase %s failed to apply, later phases were not applied", phase)))
+			break
 		}
-		if len(applyStderr) > 0 {
-			multiStderr = append(multiStderr, applyStderr)
+
+		if err := c.waitForPhase(phase, targetNamespace); err != nil {
+			log.Printf("error waiting for deploy phase %s: %s", phase, err.Error())
+			result.hasErr = true
+			result.multiStderr = append(result.multiStderr, []byte(fmt.Sprintf("deploy phase %s did not become ready, later phases were not applied: %s", phase, err.Error())))
+			break
 		}
 	}
 
-	result := &applyResult{
-		hasErr:      hasErr,
-		multiStderr: multiStderr,
-		multiStdout: multiStdout,
+	if result.hasErr {
+		return result, nil
+	}
+
+	if err := c.runHooks(hookJobs, postHook, applicationManifests.AppSlug, applicationManifests.AnnotateSlug); err != nil {
+		log.Printf("error running %s hooks: %s", postHook, err.Error())
+		result.hasErr = true
+		result.multiStderr = append(result.multiStderr, []byte(err.Error()))
 	}
+
 	return result, nil
 }
 
+// waitForPhase waits for the workloads in the phase to be ready
+func (c *Client) waitForPhase(phase deployPhase, targetNamespace string) error {
+	if targetNamespace == "" {
+		targetNamespace = corev1.NamespaceDefault
+	}
+
+	workloads := phaseWorkloads(phase.docs, targetNamespace)
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	restconfig, err := rest.InClusterConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get in cluster config")
+	}
+	clientset, err := kubernetes.NewForConfig(restconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to get new kubernetes client")
+	}
+
+	log.Printf("waiting for %d workload(s) in deploy phase %s to be ready", len(workloads), phase)
+
+	ctx, cancel := context.WithTimeout(context.Background(), PhaseReadyTimeout)
+	defer cancel()
+
+	resourceStates, err := appstate.WaitForReady(ctx, clientset, workloads)
+	if err != nil {
+		notReady := []string{}
+		for _, resourceState := range resourceStates {
+			if resourceState.State != types.StateReady {
+				notReady = append(notReady, fmt.Sprintf("%s/%s is %s", resourceState.Kind, resourceState.Name, resourceState.State))
+			}
+		}
+		if len(notReady) > 0 {
+			return errors.Wrap(err, strings.Join(notReady, ", "))
+		}
+		return err
+	}
+
+	log.Printf("deploy phase %s is ready", phase)
+	return nil
+}
+
 func (c *Client) clearNamespace(slug string, namespace string) (bool, error) {
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -449,6 +599,20 @@ func getPVCs(targetNamespace string, obj k8sruntime.Object, gvk *k8sschema.Group
 	return pvcs, nil
 }
 
+// isRestoredNamespace returns true if the objects in namespace are removed for a restore. All of them
+// are when the restore isn't limited to some namespaces.
+func isRestoredNamespace(namespace string, restoreNamespaces []string) bool {
+	if len(restoreNamespaces) == 0 {
+		return true
+	}
+	for _, restoreNamespace := range restoreNamespaces {
+		if restoreNamespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
 func deletePVCs(namespace string, pvcs []string) error {
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/drift"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+type driftReport struct {
+	AppID        string              `json:"app_id"`
+	ScannedAt    time.Time           `json:"scanned_at"`
+	Objects      []drift.ObjectDrift `json:"objects"`
+	Reapplied    bool                `json:"reapplied"`
+	ReapplyError string              `json:"reapply_error,omitempty"`
+}
+
+// setAppliedManifests records the manifests that were last applied for the app, which drift is
+// compared against. Nothing is compared once the app is undeployed.
+func (c *Client) setAppliedManifests(appID string, manifests string, args ApplicationManifests) {
+	c.appliedMtx.Lock()
+	defer c.appliedMtx.Unlock()
+
+	if c.appliedManifests == nil {
+		c.appliedManifests = map[string]ApplicationManifests{}
+	}
+
+	if manifests == "" {
+		delete(c.appliedManifests, appID)
+		return
+	}
+
+	applied := args
+	applied.Manifests = manifests
+	applied.PreviousManifests = ""
+	applied.ResultCallback = ""
+	applied.AutoRollback = false
+	applied.isRollback = false
+	applied.canary = nil
+	c.appliedManifests[appID] = applied
+}
+
+func (c *Client) getAppliedManifests() []ApplicationManifests {
+	c.appliedMtx.Lock()
+	defer c.appliedMtx.Unlock()
+
+	applied := []ApplicationManifests{}
+	for _, applicationManifests := range c.appliedManifests {
+		applied = append(applied, applicationManifests)
+	}
+	return applied
+}
+
+func (c *Client) runDriftScanner() {
+	ticker := time.NewTicker(DriftScanInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, applicationManifests := range c.getAppliedManifests() {
+			if err := c.scanForDrift(applicationManifests); err != nil {
+				log.Printf("error scanning app %s for drift: %s", applicationManifests.AppSlug, err.Error())
+			}
+		}
+	}
+}
+
+// scanForDrift reports the objects of the app that changed since they were applied, and re-applies
+// the manifests when the app asks for it
+func (c *Client) scanForDrift(applicationManifests ApplicationManifests) error {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get in cluster config")
+	}
+
+	scanner, err := drift.NewScanner(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create drift scanner")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(applicationManifests.Manifests)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode manifests")
+	}
+
+	targetNamespace := c.TargetNamespace
+	if applicationManifests.Namespace != "." {
+		targetNamespace = applicationManifests.Namespace
+	}
+	if targetNamespace == "" {
+		targetNamespace = corev1.NamespaceDefault
+	}
+
+	// hook jobs are deleted after they run, so they would always be missing
+	_, decoded, err = splitHookJobs(decoded, targetNamespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to split hook jobs from manifests")
+	}
+
+	report := driftReport{
+		AppID:     applicationManifests.AppID,
+		ScannedAt: time.Now(),
+	}
+	report.Objects, err = scanner.Scan(decoded, targetNamespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to scan")
+	}
+
+	if len(report.Objects) > 0 {
+		log.Printf("found %d drifted object(s) in app %s", len(report.Objects), applicationManifests.AppSlug)
+		if applicationManifests.DriftReapply {
+			report.Reapplied = true
+			if err := c.reapply(applicationManifests); err != nil {
+				log.Printf("error re-applying app %s: %s", applicationManifests.AppSlug, err.Error())
+				report.ReapplyError = err.Error()
+			}
+		}
+	}
+
+	uri := fmt.Sprintf("%s/api/v1/drift", c.APIEndpoint)
+	if err := c.putResult(uri, report); err != nil {
+		return errors.Wrap(err, "failed to report drift")
+	}
+
+	return nil
+}
+
+// reapply applies the manifests again, unless a deploy has replaced them since they were scanned
+func (c *Client) reapply(applicationManifests ApplicationManifests) error {
+	c.deployMtx.Lock()
+	defer c.deployMtx.Unlock()
+
+	c.appliedMtx.Lock()
+	current, ok := c.appliedManifests[applicationManifests.AppID]
+	c.appliedMtx.Unlock()
+	if !ok || current.Manifests != applicationManifests.Manifests {
+		return errors.New("manifests changed since the drift scan")
+	}
+
+	reapplyManifests := applicationManifests
+	reapplyManifests.isReapply = true
+
+	result, err := c.ensureResourcesPresent(reapplyManifests)
+	if err != nil {
+		return errors.Wrap(err, "failed to apply manifests")
+	}
+	if result.hasErr {
+		return errors.Errorf("failed to apply manifests: %s", result.stderr())
+	}
+
+	return nil
+}
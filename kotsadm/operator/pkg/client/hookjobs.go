@@ -0,0 +1,361 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Hooks are Jobs that run at a point in the lifecycle of an app, with the same semantics as Helm hooks.
+// The kots.io annotations take precedence over the helm.sh annotations, which are kept when a chart is rendered.
+const (
+	HookPreInstall  = "pre-install"
+	HookPostInstall = "post-install"
+	HookPreUpgrade  = "pre-upgrade"
+	HookPostUpgrade = "post-upgrade"
+	HookPreDelete   = "pre-delete"
+
+	HookDeletePolicyBeforeCreation = "before-hook-creation"
+	HookDeletePolicySucceeded      = "hook-succeeded"
+	HookDeletePolicyFailed         = "hook-failed"
+
+	hookLogTailLines = int64(100)
+)
+
+var (
+	HookTimeout      = time.Minute * 5
+	HookPollInterval = time.Second * 2
+
+	supportedHooks = []string{HookPreInstall, HookPostInstall, HookPreUpgrade, HookPostUpgrade, HookPreDelete}
+)
+
+type hookJob struct {
+	name           string
+	namespace      string
+	hooks          []string
+	weight         int
+	deletePolicies []string
+	doc            []byte
+}
+
+func (h hookJob) hasHook(hook string) bool {
+	for _, jobHook := range h.hooks {
+		if jobHook == hook {
+			return true
+		}
+	}
+	return false
+}
+
+func (h hookJob) hasDeletePolicy(policy string) bool {
+	for _, p := range h.deletePolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// HookError is returned when a hook job fails, and includes the logs of its pods
+type HookError struct {
+	Hook string
+	Job  string
+	Err  error
+	Logs string
+}
+
+func (e HookError) Error() string {
+	msg := fmt.Sprintf("%s hook job %s failed: %s", e.Hook, e.Job, e.Err.Error())
+	if e.Logs != "" {
+		msg = fmt.Sprintf("%s\n%s", msg, e.Logs)
+	}
+	return msg
+}
+
+// splitHookJobs removes the hook jobs from multidoc. Hook jobs are run by runHooks and are not applied with the rest of the app.
+func splitHookJobs(multidoc []byte, defaultNamespace string) ([]hookJob, []byte, error) {
+	hookJobs := []hookJob{}
+	other := []string{}
+
+	docs := strings.Split(string(multidoc), "\n---\n")
+	for _, doc := range docs {
+		o := OverlySimpleGVKWithName{}
+		if err := yaml.Unmarshal([]byte(doc), &o); err != nil {
+			other = append(other, doc)
+			continue
+		}
+
+		hooks := hookAnnotation(o.Metadata.Annotations, "hook")
+		if o.APIVersion != "batch/v1" || o.Kind != "Job" || hooks == "" {
+			other = append(other, doc)
+			continue
+		}
+
+		job := hookJob{
+			name:      o.Metadata.Name,
+			namespace: o.Metadata.Namespace,
+			doc:       []byte(doc),
+		}
+		if job.namespace == "" {
+			job.namespace = defaultNamespace
+		}
+
+		job.hooks = parseSupportedHooks(hooks)
+		if len(job.hooks) == 0 {
+			// jobs for hooks that kots doesn't run are deployed with the rest of the app, as they always have been
+			other = append(other, doc)
+			continue
+		}
+
+		if weight := hookAnnotation(o.Metadata.Annotations, "hook-weight"); weight != "" {
+			w, err := strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil {
+				return nil, nil, errors.Errorf("invalid hook weight %q on job %s", weight, job.name)
+			}
+			job.weight = w
+		}
+
+		deletePolicy := hookAnnotation(o.Metadata.Annotations, "hook-delete-policy")
+		if deletePolicy == "" {
+			deletePolicy = HookDeletePolicyBeforeCreation
+		}
+		for _, policy := range strings.Split(deletePolicy, ",") {
+			job.deletePolicies = append(job.deletePolicies, strings.TrimSpace(policy))
+		}
+
+		hookJobs = append(hookJobs, job)
+	}
+
+	return hookJobs, []byte(strings.Join(other, "\n---\n")), nil
+}
+
+func parseSupportedHooks(hooks string) []string {
+	supported := []string{}
+	for _, hook := range strings.Split(hooks, ",") {
+		hook = strings.TrimSpace(hook)
+		for _, supportedHook := range supportedHooks {
+			if hook == supportedHook {
+				supported = append(supported, hook)
+			}
+		}
+	}
+	return supported
+}
+
+// isRunAsHook returns true if the operator runs the job as a hook, and handles its delete policy
+func isRunAsHook(annotations map[string]string) bool {
+	return len(parseSupportedHooks(hookAnnotation(annotations, "hook"))) > 0
+}
+
+func hookAnnotation(annotations map[string]string, name string) string {
+	if value, ok := annotations[fmt.Sprintf("kots.io/%s", name)]; ok {
+		return value
+	}
+	return annotations[fmt.Sprintf("helm.sh/%s", name)]
+}
+
+// hookJobsFor returns the jobs for the hook in the order they run, by weight and then name
+func hookJobsFor(hookJobs []hookJob, hook string) []hookJob {
+	matching := []hookJob{}
+	for _, job := range hookJobs {
+		if job.hasHook(hook) {
+			matching = append(matching, job)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		if matching[i].weight != matching[j].weight {
+			return matching[i].weight < matching[j].weight
+		}
+		return matching[i].name < matching[j].name
+	})
+	return matching
+}
+
+// runHooks runs the jobs for the hook one at a time, and stops at the first one that fails
+func (c *Client) runHooks(hookJobs []hookJob, hook string, slug string, annotateSlug bool) error {
+	jobs := hookJobsFor(hookJobs, hook)
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	restconfig, err := rest.InClusterConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get in cluster config")
+	}
+	clientset, err := kubernetes.NewForConfig(restconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to get new kubernetes client")
+	}
+
+	for _, job := range jobs {
+		log.Printf("running %s hook job %s", hook, job.name)
+		if err := runHookJob(clientset, job, hook, slug, annotateSlug); err != nil {
+			return err
+		}
+		log.Printf("%s hook job %s succeeded", hook, job.name)
+	}
+
+	return nil
+}
+
+func runHookJob(clientset kubernetes.Interface, hookJob hookJob, hook string, slug string, annotateSlug bool) error {
+	obj, _, err := parseK8sYaml(hookJob.doc)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse hook job %s", hookJob.name)
+	}
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return errors.Errorf("hook %s is not a job", hookJob.name)
+	}
+	job.Namespace = hookJob.namespace
+	if annotateSlug {
+		if job.Annotations == nil {
+			job.Annotations = map[string]string{}
+		}
+		job.Annotations["kots.io/app-slug"] = slug
+	}
+
+	jobs := clientset.BatchV1().Jobs(hookJob.namespace)
+
+	_, err = jobs.Get(context.TODO(), job.Name, metav1.GetOptions{})
+	if err == nil {
+		if !hookJob.hasDeletePolicy(HookDeletePolicyBeforeCreation) {
+			return HookError{Hook: hook, Job: job.Name, Err: errors.New("job already exists")}
+		}
+		if err := deleteHookJob(clientset, job.Namespace, job.Name, true); err != nil {
+			return errors.Wrapf(err, "failed to delete previous hook job %s", job.Name)
+		}
+	} else if !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get hook job %s", job.Name)
+	}
+
+	if _, err := jobs.Create(context.TODO(), job, metav1.CreateOptions{}); err != nil {
+		return HookError{Hook: hook, Job: job.Name, Err: errors.Wrap(err, "failed to create job")}
+	}
+
+	jobErr := waitForHookJob(clientset, job.Namespace, job.Name)
+	if jobErr != nil {
+		logs, err := hookJobLogs(clientset, job.Namespace, job.Name)
+		if err != nil {
+			log.Printf("failed to get logs for hook job %s: %s", job.Name, err.Error())
+		}
+
+		if hookJob.hasDeletePolicy(HookDeletePolicyFailed) {
+			if err := deleteHookJob(clientset, job.Namespace, job.Name, false); err != nil {
+				log.Printf("failed to delete failed hook job %s: %s", job.Name, err.Error())
+			}
+		}
+
+		return HookError{Hook: hook, Job: job.Name, Err: jobErr, Logs: logs}
+	}
+
+	if hookJob.hasDeletePolicy(HookDeletePolicySucceeded) {
+		if err := deleteHookJob(clientset, job.Namespace, job.Name, false); err != nil {
+			log.Printf("failed to delete successful hook job %s: %s", job.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// waitForHookJob returns nil once the job completes, and an error if it fails or times out
+func waitForHookJob(clientset kubernetes.Interface, namespace string, name string) error {
+	var jobErr error
+	err := wait.PollImmediate(HookPollInterval, HookTimeout, func() (bool, error) {
+		job, err := clientset.BatchV1().Jobs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get job")
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				jobErr = errors.Errorf("%s: %s", condition.Reason, condition.Message)
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return errors.Errorf("timed out after %s", HookTimeout)
+	} else if err != nil {
+		return err
+	}
+	return jobErr
+}
+
+// hookJobLogs returns the last lines of the logs of each container in each pod of the job
+func hookJobLogs(clientset kubernetes.Interface, namespace string, name string) (string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", name),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list pods")
+	}
+
+	var logs bytes.Buffer
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			tailLines := hookLogTailLines
+			req := clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: container.Name,
+				TailLines: &tailLines,
+			})
+			stream, err := req.Stream(context.TODO())
+			if err != nil {
+				fmt.Fprintf(&logs, "pod %s container %s: failed to get logs: %s\n", pod.Name, container.Name, err.Error())
+				continue
+			}
+			fmt.Fprintf(&logs, "pod %s container %s:\n", pod.Name, container.Name)
+			io.Copy(&logs, stream)
+			stream.Close()
+		}
+	}
+
+	return strings.TrimSpace(logs.String()), nil
+}
+
+func deleteHookJob(clientset kubernetes.Interface, namespace string, name string, waitForDelete bool) error {
+	policy := metav1.DeletePropagationBackground
+	if waitForDelete {
+		policy = metav1.DeletePropagationForeground
+	}
+	err := clientset.BatchV1().Jobs(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{PropagationPolicy: &policy})
+	if kuberneteserrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !waitForDelete {
+		return nil
+	}
+
+	return wait.PollImmediate(HookPollInterval, HookTimeout, func() (bool, error) {
+		_, err := clientset.BatchV1().Jobs(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if kuberneteserrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
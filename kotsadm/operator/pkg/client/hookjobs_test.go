@@ -0,0 +1,87 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_splitHookJobs(t *testing.T) {
+	migrate := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install,pre-upgrade
+    helm.sh/hook-weight: "5"`
+	seed := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: seed
+  namespace: data
+  annotations:
+    kots.io/hook: post-install
+    kots.io/hook-delete-policy: hook-succeeded,hook-failed
+    helm.sh/hook: pre-install`
+	test := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: test
+  annotations:
+    helm.sh/hook: test`
+	web := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web`
+
+	hookJobs, other, err := splitHookJobs([]byte(migrate+"\n---\n"+seed+"\n---\n"+test+"\n---\n"+web), "default")
+	if err != nil {
+		t.Fatalf("splitHookJobs() error = %v", err)
+	}
+
+	wantHookJobs := []hookJob{
+		{
+			name:           "migrate",
+			namespace:      "default",
+			hooks:          []string{HookPreInstall, HookPreUpgrade},
+			weight:         5,
+			deletePolicies: []string{HookDeletePolicyBeforeCreation},
+			doc:            []byte(migrate),
+		},
+		{
+			name:           "seed",
+			namespace:      "data",
+			hooks:          []string{HookPostInstall},
+			deletePolicies: []string{HookDeletePolicySucceeded, HookDeletePolicyFailed},
+			doc:            []byte(seed),
+		},
+	}
+	if !reflect.DeepEqual(hookJobs, wantHookJobs) {
+		t.Errorf("splitHookJobs() hook jobs = %+v, want %+v", hookJobs, wantHookJobs)
+	}
+
+	wantOther := test + "\n---\n" + web
+	if string(other) != wantOther {
+		t.Errorf("splitHookJobs() other = %q, want %q", other, wantOther)
+	}
+}
+
+func Test_hookJobsFor(t *testing.T) {
+	hookJobs := []hookJob{
+		{name: "c", hooks: []string{HookPreUpgrade}, weight: 0},
+		{name: "b", hooks: []string{HookPreUpgrade, HookPreInstall}, weight: -5},
+		{name: "a", hooks: []string{HookPreUpgrade}, weight: 0},
+		{name: "d", hooks: []string{HookPostUpgrade}, weight: -10},
+	}
+
+	got := []string{}
+	for _, job := range hookJobsFor(hookJobs, HookPreUpgrade) {
+		got = append(got, job.name)
+	}
+
+	want := []string{"b", "a", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hookJobsFor() = %v, want %v", got, want)
+	}
+}
@@ -51,6 +51,11 @@ func (c *Client) runHooksInformer() error {
 						return
 					}
 
+					// hook jobs are cleaned up once they've run, after their logs are collected
+					if isRunAsHook(job.Annotations) {
+						return
+					}
+
 					cleanUpJob := false
 					reason := ""
 					if job.Status.Active == 0 && job.Status.Succeeded > 0 && strings.Contains(hookValue, "hook-succeeded") {
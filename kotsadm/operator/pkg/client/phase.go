@@ -0,0 +1,100 @@
+package client
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// DeployPhaseAnnotation orders the rollout of an app. Docs are applied in ascending phase order, and
+	// the workloads in a phase must be ready before the next phase is applied. Docs without it are in phase 0.
+	DeployPhaseAnnotation = "kots.io/deploy-phase"
+)
+
+var (
+	PhaseReadyTimeout = time.Minute * 10
+)
+
+type deployPhase struct {
+	phase int
+	docs  []byte
+}
+
+func (p deployPhase) String() string {
+	return strconv.Itoa(p.phase)
+}
+
+// splitMultidocYAMLIntoPhases returns the docs grouped by deploy phase, in the order they should be applied
+func splitMultidocYAMLIntoPhases(multidoc []byte) ([]deployPhase, error) {
+	byPhase := map[int][]string{}
+
+	docs := strings.Split(string(multidoc), "\n---\n")
+	for _, doc := range docs {
+		o := OverlySimpleGVKWithName{}
+		if err := yaml.Unmarshal([]byte(doc), &o); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal doc to look for deploy phase")
+		}
+
+		phase := 0
+		if value, ok := o.Metadata.Annotations[DeployPhaseAnnotation]; ok {
+			parsed, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, errors.Errorf("invalid %s annotation %q on %s %s", DeployPhaseAnnotation, value, o.Kind, o.Metadata.Name)
+			}
+			phase = parsed
+		}
+
+		byPhase[phase] = append(byPhase[phase], doc)
+	}
+
+	phases := []deployPhase{}
+	for phase, docs := range byPhase {
+		phases = append(phases, deployPhase{
+			phase: phase,
+			docs:  []byte(strings.Join(docs, "\n---\n")),
+		})
+	}
+	sort.Slice(phases, func(i, j int) bool {
+		return phases[i].phase < phases[j].phase
+	})
+
+	return phases, nil
+}
+
+// phaseWorkloads returns the workloads in docs that a phase waits on before the next phase is applied
+func phaseWorkloads(multidoc []byte, defaultNamespace string) []types.StatusInformer {
+	workloads := []types.StatusInformer{}
+
+	docs := strings.Split(string(multidoc), "\n---\n")
+	for _, doc := range docs {
+		o := OverlySimpleGVKWithName{}
+		if err := yaml.Unmarshal([]byte(doc), &o); err != nil {
+			continue
+		}
+
+		switch {
+		case o.APIVersion == "apps/v1" && o.Kind == "Deployment":
+		case o.APIVersion == "apps/v1" && o.Kind == "StatefulSet":
+		default:
+			continue
+		}
+
+		namespace := o.Metadata.Namespace
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+		workloads = append(workloads, types.StatusInformer{
+			Kind:      strings.ToLower(o.Kind),
+			Name:      o.Metadata.Name,
+			Namespace: namespace,
+		})
+	}
+
+	return workloads
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_splitMultidocYAMLIntoPhases(t *testing.T) {
+	database := `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: postgres
+  annotations:
+    kots.io/deploy-phase: "-10"`
+	migrations := `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrations
+  annotations:
+    kots.io/deploy-phase: "10"`
+	web := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web`
+	service := `apiVersion: v1
+kind: Service
+metadata:
+  name: web`
+
+	tests := []struct {
+		name     string
+		multidoc string
+		want     []deployPhase
+		wantErr  bool
+	}{
+		{
+			name:     "no phases",
+			multidoc: web + "\n---\n" + service,
+			want: []deployPhase{
+				{phase: 0, docs: []byte(web + "\n---\n" + service)},
+			},
+		},
+		{
+			name:     "ordered phases",
+			multidoc: migrations + "\n---\n" + web + "\n---\n" + database + "\n---\n" + service,
+			want: []deployPhase{
+				{phase: -10, docs: []byte(database)},
+				{phase: 0, docs: []byte(web + "\n---\n" + service)},
+				{phase: 10, docs: []byte(migrations)},
+			},
+		},
+		{
+			name: "invalid phase",
+			multidoc: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  annotations:
+    kots.io/deploy-phase: first`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitMultidocYAMLIntoPhases([]byte(tt.multidoc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitMultidocYAMLIntoPhases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitMultidocYAMLIntoPhases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_phaseWorkloads(t *testing.T) {
+	multidoc := `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: postgres
+  namespace: data
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web`
+
+	want := []types.StatusInformer{
+		{Kind: "statefulset", Name: "postgres", Namespace: "data"},
+		{Kind: "deployment", Name: "web", Namespace: "default"},
+	}
+
+	got := phaseWorkloads([]byte(multidoc), "default")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("phaseWorkloads() = %v, want %v", got, want)
+	}
+}
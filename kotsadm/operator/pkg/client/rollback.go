@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/applier"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/appstate/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	DefaultRollbackReadyTimeout = time.Minute * 10
+)
+
+// rollbackReason returns why the deploy should be rolled back, or an empty string if it succeeded
+// and the status informers became ready in time. Nothing is rolled back when the dry run or the
+// first apply failed, because nothing was applied.
+func (c *Client) rollbackReason(applicationManifests ApplicationManifests, result *applyResult, deployError error) string {
+	if deployError != nil {
+		return fmt.Sprintf("deploy failed: %s", deployError.Error())
+	}
+	if result.notApplied {
+		return ""
+	}
+	if result.hasErr {
+		return fmt.Sprintf("deploy failed to apply: %s", bytes.Join(result.multiStderr, []byte("\n")))
+	}
+
+	readyTimeout := DefaultRollbackReadyTimeout
+	if applicationManifests.AutoRollbackReadyTimeout != "" {
+		d, err := time.ParseDuration(applicationManifests.AutoRollbackReadyTimeout)
+		if err != nil {
+			log.Printf("invalid auto rollback ready timeout %q, using %s", applicationManifests.AutoRollbackReadyTimeout, readyTimeout)
+		} else {
+			readyTimeout = d
+		}
+	}
+
+	if err := c.waitForStatusInformers(applicationManifests, readyTimeout); err != nil {
+		return fmt.Sprintf("app was not ready within %s: %s", readyTimeout, err.Error())
+	}
+
+	return ""
+}
+
+func (c *Client) waitForStatusInformers(applicationManifests ApplicationManifests, timeout time.Duration) error {
+	targetNamespace := c.TargetNamespace
+	if applicationManifests.Namespace != "." && applicationManifests.Namespace != "" {
+		targetNamespace = applicationManifests.Namespace
+	}
+	if targetNamespace == "" {
+		targetNamespace = corev1.NamespaceDefault
+	}
+
+	informers := []types.StatusInformer{}
+	for _, str := range applicationManifests.StatusInformers {
+		informer, err := str.Parse()
+		if err != nil {
+			log.Printf("skipping status informer %s: %s", str, err.Error())
+			continue
+		}
+		if !appstate.IsReadinessSupported(informer) {
+			log.Printf("skipping status informer %s: readiness is not supported for %s", str, informer.Kind)
+			continue
+		}
+		if informer.Namespace == "" {
+			informer.Namespace = targetNamespace
+		}
+		informers = append(informers, informer)
+	}
+	if len(informers) == 0 {
+		return nil
+	}
+
+	restconfig, err := rest.InClusterConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get in cluster config")
+	}
+	clientset, err := kubernetes.NewForConfig(restconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to get new kubernetes client")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resourceStates, err := appstate.WaitForReady(ctx, clientset, informers)
+	if err != nil {
+		notReady := []string{}
+		for _, resourceState := range resourceStates {
+			if resourceState.State != types.StateReady {
+				notReady = append(notReady, fmt.Sprintf("%s/%s is %s", resourceState.Kind, resourceState.Name, resourceState.State))
+			}
+		}
+		if len(notReady) > 0 {
+			return errors.New(strings.Join(notReady, ", "))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// rollback re-applies the previous manifests, and removes anything that only the failed manifests had
+func (c *Client) rollback(applicationManifests ApplicationManifests) (*applyResult, error) {
+	rollbackManifests := applicationManifests
+	rollbackManifests.Manifests = applicationManifests.PreviousManifests
+	rollbackManifests.PreviousManifests = applicationManifests.Manifests
+	rollbackManifests.AutoRollback = false
+	rollbackManifests.isRollback = true
+	// failures are reported once, with the reason for the rollback
+	rollbackManifests.ResultCallback = ""
+
+	if err := c.diffAndRemovePreviousManifests(rollbackManifests); err != nil {
+		return nil, errors.Wrap(err, "failed to remove manifests")
+	}
+
+	result, err := c.ensureResourcesPresent(rollbackManifests)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to apply previous manifests")
+	}
+	if result.hasErr {
+		return nil, errors.Errorf("failed to apply previous manifests: %s", result.stderr())
+	}
+
+	return result, nil
+}
+
+func (c *Client) sendRolledBackResult(applicationManifests ApplicationManifests, reason string, result *applyResult) error {
+	if applicationManifests.ResultCallback == "" {
+		return nil
+	}
+
+	uri := fmt.Sprintf("%s%s", c.APIEndpoint, applicationManifests.ResultCallback)
+	log.Printf("Reporting rolled back result to %q", uri)
+
+	rolledBackResult := struct {
+		AppID          string                 `json:"app_id"`
+		IsError        bool                   `json:"is_error"`
+		RolledBack     bool                   `json:"rolled_back"`
+		RollbackReason string                 `json:"rollback_reason"`
+		ApplyStdout    []byte                 `json:"apply_stdout"`
+		ApplyStderr    []byte                 `json:"apply_stderr"`
+		ApplyResults   []applier.ObjectResult `json:"apply_results,omitempty"`
+		Canary         *canaryResult          `json:"canary,omitempty"`
+	}{
+		AppID:          applicationManifests.AppID,
+		IsError:        true,
+		RolledBack:     true,
+		RollbackReason: reason,
+		ApplyStdout:    bytes.Join(result.multiStdout, []byte("\n")),
+		ApplyStderr:    bytes.Join(result.multiStderr, []byte("\n")),
+		ApplyResults:   result.objects,
+		Canary:         applicationManifests.canary,
+	}
+
+	return c.putResult(uri, rolledBackResult)
+}
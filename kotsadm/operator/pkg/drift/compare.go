@@ -0,0 +1,246 @@
+package drift
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// RedactedValue replaces the values of drifted fields in secrets
+const RedactedValue = "***HIDDEN***"
+
+// ignoredFields are set by the server, and are never drift even if they are in the manifests
+var ignoredFields = map[string]bool{
+	"status":                     true,
+	"metadata.namespace":         true,
+	"metadata.resourceVersion":   true,
+	"metadata.uid":               true,
+	"metadata.selfLink":          true,
+	"metadata.generation":        true,
+	"metadata.creationTimestamp": true,
+	"metadata.managedFields":     true,
+}
+
+// FieldDrift is a field whose live value differs from the applied value. Live is nil when
+// the field is missing from the live object.
+type FieldDrift struct {
+	Path    string      `json:"path"`
+	Desired interface{} `json:"desired"`
+	Live    interface{} `json:"live"`
+}
+
+// Compare returns the fields of desired whose live value differs. Only the fields in desired are
+// compared, so defaults and anything else that the server or a controller adds are not drift.
+func Compare(desired *unstructured.Unstructured, live *unstructured.Unstructured) []FieldDrift {
+	desiredObject := desired.Object
+	isSecret := desired.GetAPIVersion() == "v1" && desired.GetKind() == "Secret"
+	if isSecret {
+		desiredObject = secretWithStringDataAsData(desiredObject)
+	}
+
+	drifts := []FieldDrift{}
+	compareValues("", desiredObject, live.Object, &drifts)
+
+	if isSecret {
+		for i := range drifts {
+			drifts[i].Desired = RedactedValue
+			if drifts[i].Live != nil {
+				drifts[i].Live = RedactedValue
+			}
+		}
+	}
+
+	return drifts
+}
+
+func compareValues(path string, desired interface{}, live interface{}, drifts *[]FieldDrift) {
+	if ignoredFields[path] {
+		return
+	}
+
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		if live == nil {
+			// compare each field against missing, so that empty maps are not drift
+			live = map[string]interface{}{}
+		}
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			*drifts = append(*drifts, FieldDrift{Path: path, Desired: desired, Live: live})
+			return
+		}
+
+		keys := make([]string, 0, len(desiredValue))
+		for key := range desiredValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			compareValues(joinPath(path, key), desiredValue[key], liveValue[key], drifts)
+		}
+
+	case []interface{}:
+		if live == nil && len(desiredValue) == 0 {
+			return
+		}
+		liveValue, ok := live.([]interface{})
+		if !ok {
+			*drifts = append(*drifts, FieldDrift{Path: path, Desired: desired, Live: live})
+			return
+		}
+		compareLists(path, desiredValue, liveValue, drifts)
+
+	default:
+		if live == nil && isZero(desired) {
+			return
+		}
+		if !scalarsEqual(desired, live) {
+			*drifts = append(*drifts, FieldDrift{Path: path, Desired: desired, Live: live})
+		}
+	}
+}
+
+// compareLists matches the items of lists such as containers, env and ports by name, since the
+// server can sort or add to them. Lists of anything else are compared by index.
+func compareLists(path string, desired []interface{}, live []interface{}, drifts *[]FieldDrift) {
+	desiredNames, ok := itemNames(desired)
+	if !ok {
+		if len(desired) != len(live) {
+			*drifts = append(*drifts, FieldDrift{Path: path, Desired: desired, Live: live})
+			return
+		}
+		for i := range desired {
+			compareValues(fmt.Sprintf("%s[%d]", path, i), desired[i], live[i], drifts)
+		}
+		return
+	}
+
+	liveByName := map[string]interface{}{}
+	if liveNames, ok := itemNames(live); ok {
+		for i, name := range liveNames {
+			liveByName[name] = live[i]
+		}
+	}
+	for i, name := range desiredNames {
+		itemPath := fmt.Sprintf("%s[name=%s]", path, name)
+		liveItem, ok := liveByName[name]
+		if !ok {
+			*drifts = append(*drifts, FieldDrift{Path: itemPath, Desired: desired[i], Live: nil})
+			continue
+		}
+		compareValues(itemPath, desired[i], liveItem, drifts)
+	}
+}
+
+// itemNames returns the name of each item, if every item is an object with a unique name
+func itemNames(items []interface{}) ([]string, bool) {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := m["name"].(string)
+		if !ok || name == "" || seen[name] {
+			return nil, false
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, len(names) > 0
+}
+
+// scalarsEqual compares the values as strings, since numbers from yaml and from the server can be
+// of different types. Quantities such as "0.5" and "500m" are equal.
+func scalarsEqual(desired interface{}, live interface{}) bool {
+	desiredString, liveString := scalarString(desired), scalarString(live)
+	if desiredString == liveString {
+		return true
+	}
+
+	if desired == nil || live == nil {
+		return false
+	}
+	desiredQuantity, err := resource.ParseQuantity(desiredString)
+	if err != nil {
+		return false
+	}
+	liveQuantity, err := resource.ParseQuantity(liveString)
+	if err != nil {
+		return false
+	}
+	return desiredQuantity.Cmp(liveQuantity) == 0
+}
+
+func scalarString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func isZero(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case bool:
+		return !value
+	case int64:
+		return value == 0
+	case float64:
+		return value == 0
+	}
+	return false
+}
+
+// secretWithStringDataAsData moves stringData into data, the way the server stores it
+func secretWithStringDataAsData(secret map[string]interface{}) map[string]interface{} {
+	stringData, ok := secret["stringData"].(map[string]interface{})
+	if !ok {
+		return secret
+	}
+
+	data := map[string]interface{}{}
+	if existing, ok := secret["data"].(map[string]interface{}); ok {
+		for key, value := range existing {
+			data[key] = value
+		}
+	}
+	for key, value := range stringData {
+		data[key] = base64.StdEncoding.EncodeToString([]byte(scalarString(value)))
+	}
+
+	converted := map[string]interface{}{}
+	for key, value := range secret {
+		converted[key] = value
+	}
+	delete(converted, "stringData")
+	converted["data"] = data
+	return converted
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%s]", path, key)
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
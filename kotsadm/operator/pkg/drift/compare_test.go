@@ -0,0 +1,197 @@
+package drift
+
+import (
+	"reflect"
+	"testing"
+
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired map[string]interface{}
+		live    map[string]interface{}
+		want    []FieldDrift
+	}{
+		{
+			name: "defaults and server fields are not drift",
+			desired: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]interface{}{
+					"name":              "web",
+					"creationTimestamp": nil,
+				},
+				"spec": map[string]interface{}{
+					"replicas": float64(2),
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":      "web",
+									"image":     "nginx:1.19",
+									"env":       []interface{}{},
+									"resources": map[string]interface{}{},
+								},
+							},
+						},
+					},
+				},
+			},
+			live: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]interface{}{
+					"name":              "web",
+					"namespace":         "default",
+					"resourceVersion":   "1234",
+					"creationTimestamp": "2020-07-01T00:00:00Z",
+				},
+				"spec": map[string]interface{}{
+					"replicas":             int64(2),
+					"revisionHistoryLimit": int64(10),
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":                   "web",
+									"image":                  "nginx:1.19",
+									"imagePullPolicy":        "IfNotPresent",
+									"resources":              map[string]interface{}{},
+									"terminationMessagePath": "/dev/termination-log",
+								},
+							},
+						},
+					},
+				},
+				"status": map[string]interface{}{
+					"replicas": int64(2),
+				},
+			},
+			want: []FieldDrift{},
+		},
+		{
+			name: "changed fields",
+			desired: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"spec": map[string]interface{}{
+					"replicas": float64(2),
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":  "sidecar",
+									"image": "envoy",
+								},
+								map[string]interface{}{
+									"name":  "web",
+									"image": "nginx:1.19",
+									"resources": map[string]interface{}{
+										"limits": map[string]interface{}{
+											"cpu": "0.5",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			live: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"spec": map[string]interface{}{
+					"replicas": int64(5),
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":  "web",
+									"image": "nginx:1.18",
+									"resources": map[string]interface{}{
+										"limits": map[string]interface{}{
+											"cpu": "500m",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: []FieldDrift{
+				{Path: "spec.replicas", Desired: float64(2), Live: int64(5)},
+				{
+					Path:    "spec.template.spec.containers[name=sidecar]",
+					Desired: map[string]interface{}{"name": "sidecar", "image": "envoy"},
+				},
+				{Path: "spec.template.spec.containers[name=web].image", Desired: "nginx:1.19", Live: "nginx:1.18"},
+			},
+		},
+		{
+			name: "lists without names are compared by index",
+			desired: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						"example.com/key": "a",
+					},
+				},
+				"data": map[string]interface{}{
+					"args": []interface{}{"a", "b"},
+				},
+			},
+			live: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						"example.com/key": "b",
+					},
+				},
+				"data": map[string]interface{}{
+					"args": []interface{}{"a", "c"},
+				},
+			},
+			want: []FieldDrift{
+				{Path: "data.args[1]", Desired: "b", Live: "c"},
+				{Path: "metadata.annotations[example.com/key]", Desired: "a", Live: "b"},
+			},
+		},
+		{
+			name: "secret values are redacted and string data is compared as data",
+			desired: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"stringData": map[string]interface{}{
+					"password": "hunter2",
+					"username": "admin",
+				},
+			},
+			live: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"data": map[string]interface{}{
+					"password": "aHVudGVyMw==",
+					"username": "YWRtaW4=",
+				},
+			},
+			want: []FieldDrift{
+				{Path: "data.password", Desired: RedactedValue, Live: RedactedValue},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := &unstructured.Unstructured{Object: tt.desired}
+			live := &unstructured.Unstructured{Object: tt.live}
+			if got := Compare(desired, live); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Package drift finds the differences between the manifests that were applied for an app and the
+// live objects in the cluster, such as changes made with kubectl edit.
+package drift
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/operator/pkg/applier"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// ObjectDrift is an object that was changed or deleted since it was applied
+type ObjectDrift struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Namespace  string       `json:"namespace,omitempty"`
+	Name       string       `json:"name"`
+	Missing    bool         `json:"missing,omitempty"`
+	Fields     []FieldDrift `json:"fields,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+type Scanner struct {
+	dynamicClient dynamic.Interface
+	mapper        meta.ResettableRESTMapper
+}
+
+func NewScanner(config *rest.Config) (*Scanner, error) {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create discovery client")
+	}
+
+	return &Scanner{
+		dynamicClient: dynamicClient,
+		mapper:        restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+	}, nil
+}
+
+// Scan compares every object in manifests with the live object, and returns the objects that drifted.
+// Objects that could not be compared are returned with an error.
+func (s *Scanner) Scan(manifests []byte, targetNamespace string) ([]ObjectDrift, error) {
+	objs, err := applier.DecodeObjects(manifests)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode manifests")
+	}
+
+	s.mapper.Reset()
+
+	drifted := []ObjectDrift{}
+	for _, obj := range objs {
+		objectDrift := ObjectDrift{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Name:       obj.GetName(),
+		}
+
+		gvk := obj.GroupVersionKind()
+		mapping, err := s.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			objectDrift.Error = errors.Wrapf(err, "failed to find resource for %s", gvk.String()).Error()
+			drifted = append(drifted, objectDrift)
+			continue
+		}
+
+		var resource dynamic.ResourceInterface = s.dynamicClient.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			objectDrift.Namespace = obj.GetNamespace()
+			if objectDrift.Namespace == "" {
+				objectDrift.Namespace = targetNamespace
+			}
+			resource = s.dynamicClient.Resource(mapping.Resource).Namespace(objectDrift.Namespace)
+		}
+
+		live, err := resource.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+		if kuberneteserrors.IsNotFound(err) {
+			objectDrift.Missing = true
+			drifted = append(drifted, objectDrift)
+			continue
+		} else if err != nil {
+			objectDrift.Error = errors.Wrap(err, "failed to get object").Error()
+			drifted = append(drifted, objectDrift)
+			continue
+		}
+
+		objectDrift.Fields = Compare(obj, live)
+		if len(objectDrift.Fields) > 0 {
+			drifted = append(drifted, objectDrift)
+		}
+	}
+
+	return drifted, nil
+}
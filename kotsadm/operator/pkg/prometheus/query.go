@@ -0,0 +1,103 @@
+// Package prometheus evaluates instant queries against the Prometheus HTTP API.
+package prometheus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var QueryTimeout = time.Second * 10
+
+type queryResponse struct {
+	Status string    `json:"status"`
+	Error  string    `json:"error"`
+	Data   queryData `json:"data"`
+}
+
+type queryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+type vectorSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// Query evaluates the query at the current time and returns every value in the result, which is
+// one value for a scalar and one per series for a vector
+func Query(address string, query string) ([]float64, error) {
+	u, err := url.Parse(strings.TrimSuffix(address, "/") + "/api/v1/query")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse address")
+	}
+	u.RawQuery = url.Values{"query": []string{query}}.Encode()
+
+	client := http.Client{Timeout: QueryTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query prometheus")
+	}
+	defer resp.Body.Close()
+
+	response := queryResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode response with status code %d", resp.StatusCode)
+	}
+	if response.Status != "success" {
+		return nil, errors.Errorf("query failed: %s", response.Error)
+	}
+
+	switch response.Data.ResultType {
+	case "scalar":
+		sample := []interface{}{}
+		if err := json.Unmarshal(response.Data.Result, &sample); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal scalar")
+		}
+		value, err := sampleValue(sample)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{value}, nil
+
+	case "vector":
+		samples := []vectorSample{}
+		if err := json.Unmarshal(response.Data.Result, &samples); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal vector")
+		}
+		values := []float64{}
+		for _, sample := range samples {
+			value, err := sampleValue(sample.Value)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+
+	default:
+		return nil, errors.Errorf("unsupported result type %q", response.Data.ResultType)
+	}
+}
+
+// sampleValue parses a [timestamp, "value"] pair
+func sampleValue(sample []interface{}) (float64, error) {
+	if len(sample) != 2 {
+		return 0, errors.Errorf("unexpected sample %v", sample)
+	}
+	str, ok := sample[1].(string)
+	if !ok {
+		return 0, errors.Errorf("unexpected sample value %v", sample[1])
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse sample value %q", str)
+	}
+	return value, nil
+}
@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     []float64
+		wantErr  bool
+	}{
+		{
+			name:     "vector",
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"pod":"web-1"},"value":[1593561600,"0.5"]},{"metric":{"pod":"web-2"},"value":[1593561600,"2"]}]}}`,
+			want:     []float64{0.5, 2},
+		},
+		{
+			name:     "empty vector",
+			response: `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			want:     []float64{},
+		},
+		{
+			name:     "scalar",
+			response: `{"status":"success","data":{"resultType":"scalar","result":[1593561600,"0.01"]}}`,
+			want:     []float64{0.01},
+		},
+		{
+			name:     "error",
+			response: `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "matrix",
+			response: `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotQuery string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotQuery = r.URL.Query().Get("query")
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			got, err := Query(server.URL+"/", `sum(rate(http_requests_total{code="500"}[5m]))`)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Query() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotQuery != `sum(rate(http_requests_total{code="500"}[5m]))` {
+				t.Errorf("Query() sent query %q", gotQuery)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
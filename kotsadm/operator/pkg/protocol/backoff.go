@@ -0,0 +1,46 @@
+package protocol
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff is an exponential backoff with jitter. Each delay is a random duration between half of and
+// the full exponential delay, so that operators that lost their connection at the same time don't
+// all reconnect at the same time.
+type Backoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+
+	attempt int
+	rand    *rand.Rand
+}
+
+func NewBackoff() *Backoff {
+	return &Backoff{
+		Min:    time.Second,
+		Max:    time.Minute,
+		Factor: 2,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Next returns the delay before the next attempt
+func (b *Backoff) Next() time.Duration {
+	delay := float64(b.Min) * math.Pow(b.Factor, float64(b.attempt))
+	if delay > float64(b.Max) {
+		delay = float64(b.Max)
+	} else {
+		b.attempt++
+	}
+
+	half := delay / 2
+	return time.Duration(half + b.rand.Float64()*half)
+}
+
+// Reset starts the delays from Min again, after a successful attempt
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,85 @@
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	b := NewBackoff()
+	b.Min = time.Second
+	b.Max = time.Second * 10
+
+	wantMax := []time.Duration{
+		time.Second,
+		time.Second * 2,
+		time.Second * 4,
+		time.Second * 8,
+		time.Second * 10,
+		time.Second * 10,
+	}
+	for i, max := range wantMax {
+		got := b.Next()
+		if got < max/2 || got > max {
+			t.Errorf("Next() attempt %d = %s, want between %s and %s", i, got, max/2, max)
+		}
+	}
+
+	b.Reset()
+	if got := b.Next(); got < time.Second/2 || got > time.Second {
+		t.Errorf("Next() after Reset() = %s, want between %s and %s", got, time.Second/2, time.Second)
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		minVersion int
+		maxVersion int
+		hello      Hello
+		want       int
+		wantErr    bool
+	}{
+		{
+			name:       "same versions",
+			minVersion: 1,
+			maxVersion: 1,
+			hello:      Hello{MinVersion: 1, MaxVersion: 1},
+			want:       1,
+		},
+		{
+			name:       "newer operator",
+			minVersion: 1,
+			maxVersion: 2,
+			hello:      Hello{MinVersion: 1, MaxVersion: 3},
+			want:       2,
+		},
+		{
+			name:       "older operator",
+			minVersion: 1,
+			maxVersion: 3,
+			hello:      Hello{MinVersion: 1, MaxVersion: 2},
+			want:       2,
+		},
+		{
+			name:       "no overlap",
+			minVersion: 2,
+			maxVersion: 3,
+			hello:      Hello{MinVersion: 1, MaxVersion: 1},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NegotiateVersion(tt.minVersion, tt.maxVersion, tt.hello)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NegotiateVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NegotiateVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+var (
+	HandshakeTimeout = time.Second * 10
+	WriteTimeout     = time.Second * 10
+
+	// PingTimeout is how long the connection can go without a ping from the server before it's
+	// considered lost. The server pings more often than this.
+	PingTimeout = time.Second * 90
+)
+
+// Conn is the operator side of a connection to kotsadm
+type Conn struct {
+	Welcome Welcome
+
+	ws       *websocket.Conn
+	writeMtx sync.Mutex
+}
+
+// Dial connects to the kotsadm api, authenticating with the cluster token, and completes the handshake
+func Dial(apiEndpoint string, token string, hello Hello) (*Conn, error) {
+	u, err := url.Parse(apiEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse api endpoint")
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + ConnectPath
+
+	header := http.Header{}
+	header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(":%s", token)))))
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: HandshakeTimeout,
+	}
+	ws, resp, err := dialer.Dial(u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, errors.Wrapf(err, "failed to connect, status code %d", resp.StatusCode)
+		}
+		return nil, errors.Wrap(err, "failed to connect")
+	}
+
+	c := &Conn{
+		ws: ws,
+	}
+
+	if err := c.write(MessageTypeHello, hello); err != nil {
+		ws.Close()
+		return nil, errors.Wrap(err, "failed to send hello")
+	}
+
+	ws.SetReadDeadline(time.Now().Add(HandshakeTimeout))
+	envelope, err := c.read()
+	if err != nil {
+		ws.Close()
+		if closeErr, ok := errors.Cause(err).(*websocket.CloseError); ok && closeErr.Code == CloseUnsupportedVersion {
+			return nil, errors.Errorf("server does not support protocol versions %d-%d: %s", hello.MinVersion, hello.MaxVersion, closeErr.Text)
+		}
+		return nil, errors.Wrap(err, "failed to read welcome")
+	}
+	if envelope.Type != MessageTypeWelcome {
+		ws.Close()
+		return nil, errors.Errorf("expected %s message, got %s", MessageTypeWelcome, envelope.Type)
+	}
+	if err := envelope.Decode(&c.Welcome); err != nil {
+		ws.Close()
+		return nil, err
+	}
+
+	ws.SetReadDeadline(time.Now().Add(PingTimeout))
+	ws.SetPingHandler(func(data string) error {
+		ws.SetReadDeadline(time.Now().Add(PingTimeout))
+		err := ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(WriteTimeout))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+
+	return c, nil
+}
+
+// Next blocks until the next message from the server. Pings are handled while waiting.
+func (c *Conn) Next() (Envelope, error) {
+	return c.read()
+}
+
+// Ack acknowledges every message up to and including id
+func (c *Conn) Ack(id uint64) error {
+	return c.write(MessageTypeAck, Ack{ID: id})
+}
+
+// Close closes the connection cleanly
+func (c *Conn) Close() error {
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(WriteTimeout))
+	return c.ws.Close()
+}
+
+func (c *Conn) read() (Envelope, error) {
+	envelope := Envelope{}
+	if err := c.ws.ReadJSON(&envelope); err != nil {
+		return Envelope{}, errors.Wrap(err, "failed to read message")
+	}
+	return envelope, nil
+}
+
+func (c *Conn) write(messageType string, payload interface{}) error {
+	envelope, err := NewEnvelope(messageType, 0, payload)
+	if err != nil {
+		return err
+	}
+
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+
+	c.ws.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	if err := c.ws.WriteJSON(envelope); err != nil {
+		return errors.Wrapf(err, "failed to write %s message", messageType)
+	}
+	return nil
+}
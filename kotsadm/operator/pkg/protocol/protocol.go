@@ -0,0 +1,108 @@
+// Package protocol is the protocol between kotsadm and the operator. The operator connects to kotsadm
+// with a websocket, and every message in either direction is a JSON encoded Envelope.
+//
+// After connecting, the operator sends a Hello with the protocol versions that it supports, and the
+// server replies with a Welcome with the version that it picked. Every message the server sends after
+// that has an ID, and is sent again on the next connection until the operator acknowledges it. The
+// operator resumes a session by sending the SessionID and the last ID that it handled in its Hello.
+package protocol
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Version is the newest version of the protocol that this package speaks, and MinVersion is the oldest
+	Version    = 1
+	MinVersion = 1
+
+	// ConnectPath is the path of the websocket endpoint on the kotsadm api
+	ConnectPath = "/api/v1/operator/connect"
+
+	// CloseUnsupportedVersion is the websocket close code the server uses when the versions don't overlap
+	CloseUnsupportedVersion = 4001
+)
+
+// Message types sent by the operator
+const (
+	MessageTypeHello = "hello"
+	MessageTypeAck   = "ack"
+)
+
+// Message types sent by the server
+const (
+	MessageTypeWelcome       = "welcome"
+	MessageTypeDeploy        = "deploy"
+	MessageTypeAppInformers  = "appInformers"
+	MessageTypePreflight     = "preflight"
+	MessageTypeSupportBundle = "supportbundle"
+)
+
+// Envelope wraps every message. ID is only set on server messages that must be acknowledged.
+type Envelope struct {
+	Type    string          `json:"type"`
+	ID      uint64          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Hello is the first message the operator sends on a connection
+type Hello struct {
+	MinVersion int `json:"min_version"`
+	MaxVersion int `json:"max_version"`
+
+	// SessionID and LastID are set when resuming a session
+	SessionID string `json:"session_id,omitempty"`
+	LastID    uint64 `json:"last_id,omitempty"`
+}
+
+// Welcome is the server's reply to Hello. When SessionID differs from the one in Hello, the server
+// started a new session and the operator must reset the last ID that it handled.
+type Welcome struct {
+	Version   int    `json:"version"`
+	SessionID string `json:"session_id"`
+	ClusterID string `json:"cluster_id"`
+}
+
+// Ack acknowledges that the operator handled every message up to and including ID
+type Ack struct {
+	ID uint64 `json:"id"`
+}
+
+// NewEnvelope encodes payload into an envelope of the message type
+func NewEnvelope(messageType string, id uint64, payload interface{}) (Envelope, error) {
+	envelope := Envelope{
+		Type: messageType,
+		ID:   id,
+	}
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return Envelope{}, errors.Wrapf(err, "failed to marshal %s payload", messageType)
+		}
+		envelope.Payload = b
+	}
+	return envelope, nil
+}
+
+// Decode decodes the payload of the envelope into v
+func (e Envelope) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal %s payload", e.Type)
+	}
+	return nil
+}
+
+// NegotiateVersion returns the newest version supported by both sides
+func NegotiateVersion(minVersion int, maxVersion int, hello Hello) (int, error) {
+	version := maxVersion
+	if hello.MaxVersion < version {
+		version = hello.MaxVersion
+	}
+	if version < minVersion || version < hello.MinVersion {
+		return 0, errors.Errorf("no common protocol version, server supports %d-%d and operator supports %d-%d",
+			minVersion, maxVersion, hello.MinVersion, hello.MaxVersion)
+	}
+	return version, nil
+}
@@ -11,7 +11,6 @@ import (
 
 var knownKubectlVersions = []semver.Version{
 	semver.MustParse("1.16.3"),
-	semver.MustParse("1.14.9"),
 }
 
 // finds a known version that matches the provided range
@@ -30,12 +30,12 @@ func Test_matchKnownVersion(t *testing.T) {
 		{
 			name:       "1.14.x",
 			userString: "1.14.x",
-			want:       "v1.14.9",
+			want:       "",
 		},
 		{
 			name:       "<1.15.0",
 			userString: "<1.15.0",
-			want:       "v1.14.9",
+			want:       "",
 		},
 		{
 			name:       ">1.15.0 <1.17.0",
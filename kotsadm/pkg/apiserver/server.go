@@ -12,9 +12,12 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/replicatedhq/kots/kotsadm/pkg/appstatus"
 	"github.com/replicatedhq/kots/kotsadm/pkg/automation"
 	"github.com/replicatedhq/kots/kotsadm/pkg/handlers"
 	"github.com/replicatedhq/kots/kotsadm/pkg/informers"
+	"github.com/replicatedhq/kots/kotsadm/pkg/operator"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshotscheduler"
 	"github.com/replicatedhq/kots/kotsadm/pkg/updatechecker"
 )
 
@@ -35,10 +38,18 @@ func Start() {
 		log.Println("Failed to start update checker", err)
 	}
 
+	if err := snapshotscheduler.Start(); err != nil {
+		log.Println("Failed to start snapshot scheduler", err)
+	}
+
 	if err := automation.AutomateInstall(); err != nil {
 		log.Println("Failed to run automated installs", err)
 	}
 
+	operator.Start()
+
+	appstatus.StartPruner()
+
 	u, err := url.Parse("http://kotsadm-api-node:3000")
 	if err != nil {
 		panic(err)
@@ -58,8 +69,11 @@ func Start() {
 	r.HandleFunc("/api/v1/ping", handlers.Ping)
 
 	// Functions that the operator calls
-	r.Path("/api/v1/appstatus").Methods("PUT").HandlerFunc(handlers.NodeProxy(upstream))
+	r.Path("/api/v1/operator/connect").Methods("GET").HandlerFunc(handlers.OperatorConnect)
+	r.Path("/api/v1/appstatus").Methods("PUT").HandlerFunc(handlers.UpdateAppStatus)
 	r.Path("/api/v1/deploy/result").Methods("PUT").HandlerFunc(handlers.NodeProxy(upstream))
+	r.Path("/api/v1/undeploy/result").Methods("PUT").HandlerFunc(handlers.NodeProxy(upstream))
+	r.Path("/api/v1/drift").Methods("PUT").HandlerFunc(handlers.UpdateDriftReport)
 	r.Path("/api/v1/preflight/{appSlug}/{clusterSlug}/{sequence}").Methods("GET").HandlerFunc(handlers.NodeProxy(upstream))
 	r.Path("/api/v1/preflight/{appSlug}/{clusterSlug}/{sequence}").Methods("POST").HandlerFunc(handlers.NodeProxy(upstream))
 
@@ -116,6 +130,15 @@ func Start() {
 	r.Path("/api/v1/app/{appSlug}/license").Methods("OPTIONS", "PUT").HandlerFunc(handlers.SyncLicense)
 	r.Path("/api/v1/app/{appSlug}/updatecheck").Methods("OPTIONS", "POST").HandlerFunc(handlers.AppUpdateCheck)
 	r.Path("/api/v1/app/{appSlug}/updatecheckerspec").Methods("OPTIONS", "PUT").HandlerFunc(handlers.UpdateCheckerSpec)
+	r.Path("/api/v1/app/{appSlug}/cluster/{clusterId}/drift").Methods("OPTIONS", "GET").HandlerFunc(handlers.GetDriftReport)
+	r.Path("/api/v1/app/{appSlug}/cluster/{clusterId}/status/history").Methods("OPTIONS", "GET").HandlerFunc(handlers.GetAppStatusHistory)
+	r.Path("/api/v1/app/{appSlug}/cluster/{clusterId}").Methods("OPTIONS", "POST").HandlerFunc(handlers.CreateAppDownstream)
+
+	// Clusters
+	r.Path("/api/v1/clusters").Methods("OPTIONS", "GET").HandlerFunc(handlers.ListClusters)
+	r.Path("/api/v1/cluster").Methods("OPTIONS", "POST").HandlerFunc(handlers.CreateCluster)
+	r.Path("/api/v1/cluster/{clusterId}").Methods("OPTIONS", "DELETE").HandlerFunc(handlers.DeleteCluster)
+	r.Path("/api/v1/cluster/{clusterId}/token").Methods("OPTIONS", "POST").HandlerFunc(handlers.RotateClusterToken)
 
 	// kotsadm snapshots
 	r.Path("/api/v1/snapshots").Methods("OPTIONS", "GET").HandlerFunc(handlers.ListKotsadmBackups)
@@ -125,10 +148,16 @@ func Start() {
 	r.Path("/api/v1/app/{appSlug}/snapshot/backup").Methods("OPTIONS", "POST").HandlerFunc(handlers.CreateBackup)
 	r.Path("/api/v1/app/{appSlug}/snapshot/restore/status").Methods("OPTIONS", "GET").HandlerFunc(handlers.GetRestoreStatus)
 	r.Path("/api/v1/app/{appSlug}/snapshots").Methods("OPTIONS", "GET").HandlerFunc(handlers.ListBackups)
+	r.Path("/api/v1/app/{appSlug}/snapshot/schedule").Methods("OPTIONS", "GET").HandlerFunc(handlers.GetAppSnapshotSchedule)
+	r.Path("/api/v1/app/{appSlug}/snapshot/schedule").Methods("OPTIONS", "PUT").HandlerFunc(handlers.UpdateAppSnapshotSchedule)
+	r.Path("/api/v1/app/{appSlug}/snapshot/preupgrade").Methods("OPTIONS", "GET").HandlerFunc(handlers.GetPreUpgradeSnapshotPolicy)
+	r.Path("/api/v1/app/{appSlug}/snapshot/preupgrade").Methods("OPTIONS", "PUT").HandlerFunc(handlers.UpdatePreUpgradeSnapshotPolicy)
 
 	// Global snapshot routes
 	r.Path("/api/v1/snapshots/settings").Methods("OPTIONS", "GET").HandlerFunc(handlers.GetGlobalSnapshotSettings)
 	r.Path("/api/v1/snapshots/settings").Methods("OPTIONS", "PUT").HandlerFunc(handlers.UpdateGlobalSnapshotSettings)
+	r.Path("/api/v1/snapshots/schedule").Methods("OPTIONS", "GET").HandlerFunc(handlers.GetInstanceSnapshotSchedule)
+	r.Path("/api/v1/snapshots/schedule").Methods("OPTIONS", "PUT").HandlerFunc(handlers.UpdateInstanceSnapshotSchedule)
 	r.Path("/api/v1/snapshot/{snapshotName}/restore").Methods("OPTIONS", "POST").HandlerFunc(handlers.CreateRestore)
 
 	// Find a home snapshot routes
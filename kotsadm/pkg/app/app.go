@@ -2,6 +2,7 @@ package app
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/replicatedhq/kots/kotsadm/pkg/gitops"
 	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
 	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+	snapshottypes "github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
 	"github.com/segmentio/ksuid"
 	"go.uber.org/zap"
 )
@@ -25,10 +27,24 @@ type App struct {
 
 	// Additional fields will be added here as implementation is moved from node to go
 	RestoreInProgressName string
+	RestoreUndeployStatus UndeployStatus
+	RestoreOptions        snapshottypes.RestoreOptions
 	UpdateCheckerSpec     string
 	IsGitOps              bool
+	// PreUpgradeSnapshotPolicy overrides the pre-upgrade snapshot of the application spec when set
+	PreUpgradeSnapshotPolicy string
+	// UpdateCheckerVersionConstraint is the semver range that scheduled update checks download
+	UpdateCheckerVersionConstraint string
 }
 
+type UndeployStatus string
+
+const (
+	UndeployStatusInProcess UndeployStatus = "in_process"
+	UndeployStatusCompleted UndeployStatus = "completed"
+	UndeployStatusFailed    UndeployStatus = "failed"
+)
+
 type RegistryInfo struct {
 	Hostname    string
 	Username    string
@@ -42,16 +58,20 @@ func Get(id string) (*App, error) {
 		zap.String("id", id))
 
 	db := persistence.MustGetPGSession()
-	query := `select id, slug, name, current_sequence, is_airgap, restore_in_progress_name, update_checker_spec from app where id = $1`
+	query := `select id, slug, name, current_sequence, is_airgap, restore_in_progress_name, restore_undeploy_status, restore_options, update_checker_spec, update_checker_version_constraint, pre_upgrade_snapshot_policy from app where id = $1`
 	row := db.QueryRow(query, id)
 
 	app := App{}
 
 	var currentSequence sql.NullInt64
 	var restoreInProgressName sql.NullString
+	var restoreUndeployStatus sql.NullString
+	var restoreOptions sql.NullString
 	var updateCheckerSpec sql.NullString
+	var updateCheckerVersionConstraint sql.NullString
+	var preUpgradeSnapshotPolicy sql.NullString
 
-	if err := row.Scan(&app.ID, &app.Slug, &app.Name, &currentSequence, &app.IsAirgap, &restoreInProgressName, &updateCheckerSpec); err != nil {
+	if err := row.Scan(&app.ID, &app.Slug, &app.Name, &currentSequence, &app.IsAirgap, &restoreInProgressName, &restoreUndeployStatus, &restoreOptions, &updateCheckerSpec, &updateCheckerVersionConstraint, &preUpgradeSnapshotPolicy); err != nil {
 		return nil, errors.Wrap(err, "failed to scan app")
 	}
 
@@ -62,7 +82,15 @@ func Get(id string) (*App, error) {
 	}
 
 	app.RestoreInProgressName = restoreInProgressName.String
+	app.RestoreUndeployStatus = UndeployStatus(restoreUndeployStatus.String)
+	if restoreOptions.String != "" {
+		if err := json.Unmarshal([]byte(restoreOptions.String), &app.RestoreOptions); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal restore options")
+		}
+	}
 	app.UpdateCheckerSpec = updateCheckerSpec.String
+	app.UpdateCheckerVersionConstraint = updateCheckerVersionConstraint.String
+	app.PreUpgradeSnapshotPolicy = preUpgradeSnapshotPolicy.String
 
 	isGitOps, err := IsGitOpsEnabled(id)
 	if err != nil {
@@ -118,13 +146,30 @@ func IsGitOpsEnabled(appID string) (bool, error) {
 	return false, nil
 }
 
-func SetUpdateCheckerSpec(appID string, updateCheckerSpec string) error {
+func SetUpdateCheckerSpec(appID string, updateCheckerSpec string, versionConstraint string) error {
 	logger.Debug("setting update checker spec",
 		zap.String("appID", appID))
 
 	db := persistence.MustGetPGSession()
-	query := `update app set update_checker_spec = $1 where id = $2`
-	_, err := db.Exec(query, updateCheckerSpec, appID)
+	query := `update app set update_checker_spec = $1, update_checker_version_constraint = $2 where id = $3`
+	_, err := db.Exec(query, updateCheckerSpec, versionConstraint, appID)
+	if err != nil {
+		return errors.Wrap(err, "failed to exec db query")
+	}
+
+	return nil
+}
+
+// SetPreUpgradeSnapshotPolicy overrides the pre-upgrade snapshot of the application spec, an empty
+// policy removes the override
+func SetPreUpgradeSnapshotPolicy(appID string, policy string) error {
+	logger.Debug("setting pre-upgrade snapshot policy",
+		zap.String("appID", appID),
+		zap.String("policy", policy))
+
+	db := persistence.MustGetPGSession()
+	query := `update app set pre_upgrade_snapshot_policy = $1 where id = $2`
+	_, err := db.Exec(query, policy, appID)
 	if err != nil {
 		return errors.Wrap(err, "failed to exec db query")
 	}
@@ -241,13 +286,42 @@ func LastUpdateAtTime(appID string) error {
 	return nil
 }
 
-func InitiateRestore(snapshotName string, appID string) error {
+// InitiateRestore starts a restore of the app from the snapshot, the options limit what's restored
+func InitiateRestore(snapshotName string, appID string, options snapshottypes.RestoreOptions) error {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal restore options")
+	}
+
 	db := persistence.MustGetPGSession()
-	query := `update app set restore_in_progress_name = $1 where id = $2`
-	_, err := db.Exec(query, snapshotName, appID)
+	query := `update app set restore_in_progress_name = $1, restore_options = $2 where id = $3`
+	_, err = db.Exec(query, snapshotName, string(b), appID)
 	if err != nil {
 		return errors.Wrap(err, "failed to update restore_in_progress_name")
 	}
 
 	return nil
 }
+
+func SetRestoreUndeployStatus(appID string, undeployStatus UndeployStatus) error {
+	db := persistence.MustGetPGSession()
+	query := `update app set restore_undeploy_status = $1 where id = $2`
+	_, err := db.Exec(query, undeployStatus, appID)
+	if err != nil {
+		return errors.Wrap(err, "failed to update restore_undeploy_status")
+	}
+
+	return nil
+}
+
+// ResetRestore clears the restore in progress once it has completed or failed
+func ResetRestore(appID string) error {
+	db := persistence.MustGetPGSession()
+	query := `update app set restore_in_progress_name = NULL, restore_undeploy_status = '', restore_options = NULL where id = $1`
+	_, err := db.Exec(query, appID)
+	if err != nil {
+		return errors.Wrap(err, "failed to reset restore")
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package appstatus
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/appstatus/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+)
+
+var (
+	// HistoryRetention is how long state transitions are kept
+	HistoryRetention = time.Hour * 24 * 90
+	// PruneInterval is how often transitions older than HistoryRetention are removed
+	PruneInterval = time.Hour
+)
+
+// StartPruner removes the state transitions that are older than HistoryRetention, once every PruneInterval
+func StartPruner() {
+	go func() {
+		ticker := time.NewTicker(PruneInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := Prune(time.Now().Add(-HistoryRetention)); err != nil {
+				logger.Error(errors.Wrap(err, "failed to prune app status history"))
+			}
+		}
+	}()
+}
+
+// Prune removes the state transitions from before cutoff. The last transition of the app and of each
+// resource before cutoff is kept, as it's their state at cutoff.
+func Prune(cutoff time.Time) error {
+	db := persistence.MustGetPGSession()
+
+	query := `delete from app_status_history h where h.transitioned_at < $1 and exists (
+		select 1 from app_status_history l where l.app_id = h.app_id and l.cluster_id = h.cluster_id
+			and l.transitioned_at > h.transitioned_at and l.transitioned_at <= $1)`
+	_, err := db.Exec(query, cutoff)
+	if err != nil {
+		return errors.Wrap(err, "failed to prune app transitions")
+	}
+
+	query = `delete from app_resource_status_history h where h.transitioned_at < $1 and exists (
+		select 1 from app_resource_status_history l where l.app_id = h.app_id and l.cluster_id = h.cluster_id
+			and l.kind = h.kind and l.namespace = h.namespace and l.name = h.name
+			and l.transitioned_at > h.transitioned_at and l.transitioned_at <= $1)`
+	_, err = db.Exec(query, cutoff)
+	if err != nil {
+		return errors.Wrap(err, "failed to prune resource transitions")
+	}
+
+	return nil
+}
+
+// Set replaces the app's status in its cluster and records the state transitions of the app and
+// its resources there
+func Set(appStatus types.AppStatus) error {
+	marshalled, err := json.Marshal(appStatus.ResourceStates)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal resource states")
+	}
+
+	db := persistence.MustGetPGSession()
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	query := `insert into app_status (app_id, cluster_id, resource_states, updated_at) values ($1, $2, $3, $4)
+		on conflict (app_id, cluster_id) do update set resource_states = $3, updated_at = $4`
+	_, err = tx.Exec(query, appStatus.AppID, appStatus.ClusterID, string(marshalled), appStatus.UpdatedAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert app status")
+	}
+
+	if err := recordTransitions(tx, appStatus); err != nil {
+		return errors.Wrap(err, "failed to record transitions")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+func resourceKey(resourceState types.ResourceState) string {
+	return resourceState.Kind + "/" + resourceState.Namespace + "/" + resourceState.Name
+}
+
+// recordTransitions stores the transitions reported by the operator. Resources that have no
+// history yet, such as when the operator did not report transitions, start with their current
+// state. A transition is only stored when the state differs from the last one recorded, and
+// the app transitions whenever the least ready state of its resources changes.
+func recordTransitions(tx *sql.Tx, appStatus types.AppStatus) error {
+	lastStates, err := getLastResourceStates(tx, appStatus.AppID, appStatus.ClusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get last resource states")
+	}
+
+	var lastAppState types.State
+	row := tx.QueryRow(`select state from app_status_history where app_id = $1 and cluster_id = $2 order by transitioned_at desc limit 1`, appStatus.AppID, appStatus.ClusterID)
+	if err := row.Scan(&lastAppState); err != nil && err != sql.ErrNoRows {
+		return errors.Wrap(err, "failed to get last app state")
+	}
+
+	transitions := []types.ResourceStateTransition{}
+	reported := map[string]bool{}
+	for _, transition := range appStatus.Transitions {
+		transitions = append(transitions, transition)
+		reported[resourceKey(transition.ResourceState)] = true
+	}
+	for _, resourceState := range appStatus.ResourceStates {
+		key := resourceKey(resourceState)
+		if _, ok := lastStates[key]; ok || reported[key] {
+			continue
+		}
+		transitions = append(transitions, types.ResourceStateTransition{
+			ResourceState:  resourceState,
+			TransitionedAt: appStatus.UpdatedAt,
+		})
+	}
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].TransitionedAt.Before(transitions[j].TransitionedAt)
+	})
+
+	for _, transition := range transitions {
+		key := resourceKey(transition.ResourceState)
+		if lastState, ok := lastStates[key]; ok && lastState == transition.State {
+			continue
+		}
+		lastStates[key] = transition.State
+
+		query := `insert into app_resource_status_history (app_id, cluster_id, kind, namespace, name, state, transitioned_at)
+			values ($1, $2, $3, $4, $5, $6, $7)
+			on conflict (app_id, cluster_id, kind, namespace, name, transitioned_at) do update set state = $6`
+		_, err := tx.Exec(query, appStatus.AppID, appStatus.ClusterID, transition.Kind, transition.Namespace, transition.Name, transition.State, transition.TransitionedAt)
+		if err != nil {
+			return errors.Wrap(err, "failed to insert resource transition")
+		}
+
+		// only the resources that the app currently has count towards its state
+		resourceStates := types.ResourceStates{}
+		for _, resourceState := range appStatus.ResourceStates {
+			if state, ok := lastStates[resourceKey(resourceState)]; ok {
+				resourceState.State = state
+			}
+			resourceStates = append(resourceStates, resourceState)
+		}
+		appState := types.GetState(resourceStates)
+		if appState == lastAppState {
+			continue
+		}
+		lastAppState = appState
+
+		query = `insert into app_status_history (app_id, cluster_id, state, transitioned_at) values ($1, $2, $3, $4)
+			on conflict (app_id, cluster_id, transitioned_at) do update set state = $3`
+		_, err = tx.Exec(query, appStatus.AppID, appStatus.ClusterID, appState, transition.TransitionedAt)
+		if err != nil {
+			return errors.Wrap(err, "failed to insert app transition")
+		}
+	}
+
+	return nil
+}
+
+func getLastResourceStates(tx *sql.Tx, appID string, clusterID string) (map[string]types.State, error) {
+	query := `select distinct on (kind, namespace, name) kind, namespace, name, state from app_resource_status_history
+		where app_id = $1 and cluster_id = $2 order by kind, namespace, name, transitioned_at desc`
+	rows, err := tx.Query(query, appID, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query")
+	}
+	defer rows.Close()
+
+	lastStates := map[string]types.State{}
+	for rows.Next() {
+		resourceState := types.ResourceState{}
+		if err := rows.Scan(&resourceState.Kind, &resourceState.Namespace, &resourceState.Name, &resourceState.State); err != nil {
+			return nil, errors.Wrap(err, "failed to scan")
+		}
+		lastStates[resourceKey(resourceState)] = resourceState.State
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to iterate")
+	}
+
+	return lastStates, nil
+}
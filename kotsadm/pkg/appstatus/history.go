@@ -0,0 +1,186 @@
+package appstatus
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/appstatus/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+)
+
+// GetHistory summarizes the states of the app and its resources in the cluster between from and to
+func GetHistory(appID string, clusterID string, from time.Time, to time.Time) (*types.StatusHistory, error) {
+	db := persistence.MustGetPGSession()
+
+	history := types.StatusHistory{
+		From:      from,
+		To:        to,
+		Resources: []types.ResourceStateSummary{},
+	}
+
+	var initial *types.State
+	var state types.State
+	query := `select state from app_status_history where app_id = $1 and cluster_id = $2 and transitioned_at <= $3 order by transitioned_at desc limit 1`
+	row := db.QueryRow(query, appID, clusterID, from)
+	if err := row.Scan(&state); err == nil {
+		initial = &state
+	} else if err != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "failed to get initial app state")
+	}
+
+	transitions := []types.StateTransition{}
+	query = `select state, transitioned_at from app_status_history where app_id = $1 and cluster_id = $2 and transitioned_at > $3 and transitioned_at <= $4 order by transitioned_at`
+	rows, err := db.Query(query, appID, clusterID, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query app transitions")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		transition := types.StateTransition{}
+		if err := rows.Scan(&transition.State, &transition.TransitionedAt); err != nil {
+			return nil, errors.Wrap(err, "failed to scan app transition")
+		}
+		transitions = append(transitions, transition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to iterate app transitions")
+	}
+
+	history.App = summarize(initial, transitions, from, to)
+
+	resources, err := getResourceTransitions(db, appID, clusterID, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get resource transitions")
+	}
+	for _, resource := range resources {
+		history.Resources = append(history.Resources, types.ResourceStateSummary{
+			Kind:         resource.kind,
+			Namespace:    resource.namespace,
+			Name:         resource.name,
+			StateSummary: summarize(resource.initial, resource.transitions, from, to),
+		})
+	}
+
+	return &history, nil
+}
+
+type resourceTransitions struct {
+	kind        string
+	namespace   string
+	name        string
+	initial     *types.State
+	transitions []types.StateTransition
+}
+
+// getResourceTransitions returns the state of each resource at from, and its transitions until to
+func getResourceTransitions(db *sql.DB, appID string, clusterID string, from time.Time, to time.Time) ([]*resourceTransitions, error) {
+	query := `select kind, namespace, name, state, transitioned_at, transitioned_at > $3 from app_resource_status_history
+		where app_id = $1 and cluster_id = $2 and transitioned_at <= $4 and (transitioned_at > $3 or transitioned_at = (
+			select max(transitioned_at) from app_resource_status_history h
+			where h.app_id = $1 and h.cluster_id = $2 and h.kind = app_resource_status_history.kind and h.namespace = app_resource_status_history.namespace
+				and h.name = app_resource_status_history.name and h.transitioned_at <= $3))
+		order by transitioned_at`
+	rows, err := db.Query(query, appID, clusterID, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query")
+	}
+	defer rows.Close()
+
+	byKey := map[string]*resourceTransitions{}
+	for rows.Next() {
+		resourceState := types.ResourceState{}
+		var transitionedAt time.Time
+		var inRange bool
+		if err := rows.Scan(&resourceState.Kind, &resourceState.Namespace, &resourceState.Name, &resourceState.State, &transitionedAt, &inRange); err != nil {
+			return nil, errors.Wrap(err, "failed to scan")
+		}
+
+		key := resourceKey(resourceState)
+		resource, ok := byKey[key]
+		if !ok {
+			resource = &resourceTransitions{
+				kind:        resourceState.Kind,
+				namespace:   resourceState.Namespace,
+				name:        resourceState.Name,
+				transitions: []types.StateTransition{},
+			}
+			byKey[key] = resource
+		}
+
+		if !inRange {
+			state := resourceState.State
+			resource.initial = &state
+			continue
+		}
+		resource.transitions = append(resource.transitions, types.StateTransition{
+			State:          resourceState.State,
+			TransitionedAt: transitionedAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to iterate")
+	}
+
+	resources := []*resourceTransitions{}
+	for _, resource := range byKey {
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].kind != resources[j].kind {
+			return resources[i].kind < resources[j].kind
+		}
+		if resources[i].namespace != resources[j].namespace {
+			return resources[i].namespace < resources[j].namespace
+		}
+		return resources[i].name < resources[j].name
+	})
+
+	return resources, nil
+}
+
+// summarize builds the timeline from the state at from, which is nil when it is not known, and the
+// transitions after it in order. Time before the first known state is not counted.
+func summarize(initial *types.State, transitions []types.StateTransition, from time.Time, to time.Time) types.StateSummary {
+	summary := types.StateSummary{
+		SecondsByState: map[types.State]float64{},
+		Timeline:       []types.StatePeriod{},
+	}
+
+	var current *types.StatePeriod
+	if initial != nil {
+		current = &types.StatePeriod{State: *initial, From: from}
+	}
+	for _, transition := range transitions {
+		if transition.TransitionedAt.Before(from) || transition.TransitionedAt.After(to) {
+			continue
+		}
+		if current != nil {
+			if current.State == transition.State {
+				continue
+			}
+			current.To = transition.TransitionedAt
+			summary.Timeline = append(summary.Timeline, *current)
+		}
+		current = &types.StatePeriod{State: transition.State, From: transition.TransitionedAt}
+	}
+	if current != nil {
+		current.To = to
+		summary.Timeline = append(summary.Timeline, *current)
+	}
+
+	known := float64(0)
+	for _, period := range summary.Timeline {
+		seconds := period.To.Sub(period.From).Seconds()
+		summary.SecondsByState[period.State] += seconds
+		known += seconds
+	}
+	if known > 0 {
+		up := summary.SecondsByState[types.StateReady] + summary.SecondsByState[types.StateDegraded]
+		summary.UptimePercent = up / known * 100
+		summary.ReadyPercent = summary.SecondsByState[types.StateReady] / known * 100
+	}
+
+	return summary
+}
@@ -0,0 +1,102 @@
+package appstatus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/kots/kotsadm/pkg/appstatus/types"
+	"github.com/stretchr/testify/assert"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_summarize(t *testing.T) {
+	from := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour * 10)
+	at := func(hours int) time.Time {
+		return from.Add(time.Hour * time.Duration(hours))
+	}
+	state := func(s types.State) *types.State {
+		return &s
+	}
+
+	tests := []struct {
+		name        string
+		initial     *types.State
+		transitions []types.StateTransition
+		expect      types.StateSummary
+	}{
+		{
+			name:        "no history",
+			transitions: []types.StateTransition{},
+			expect: types.StateSummary{
+				SecondsByState: map[types.State]float64{},
+				Timeline:       []types.StatePeriod{},
+			},
+		},
+		{
+			name:        "ready the whole time",
+			initial:     state(types.StateReady),
+			transitions: []types.StateTransition{},
+			expect: types.StateSummary{
+				UptimePercent: 100,
+				ReadyPercent:  100,
+				SecondsByState: map[types.State]float64{
+					types.StateReady: 36000,
+				},
+				Timeline: []types.StatePeriod{
+					{State: types.StateReady, From: from, To: to},
+				},
+			},
+		},
+		{
+			name:    "degraded and unavailable",
+			initial: state(types.StateReady),
+			transitions: []types.StateTransition{
+				{State: types.StateDegraded, TransitionedAt: at(2)},
+				{State: types.StateDegraded, TransitionedAt: at(3)},
+				{State: types.StateUnavailable, TransitionedAt: at(4)},
+				{State: types.StateReady, TransitionedAt: at(5)},
+			},
+			expect: types.StateSummary{
+				UptimePercent: 90,
+				ReadyPercent:  70,
+				SecondsByState: map[types.State]float64{
+					types.StateReady:       25200,
+					types.StateDegraded:    7200,
+					types.StateUnavailable: 3600,
+				},
+				Timeline: []types.StatePeriod{
+					{State: types.StateReady, From: from, To: at(2)},
+					{State: types.StateDegraded, From: at(2), To: at(4)},
+					{State: types.StateUnavailable, From: at(4), To: at(5)},
+					{State: types.StateReady, From: at(5), To: to},
+				},
+			},
+		},
+		{
+			name: "time before the first state is not counted",
+			transitions: []types.StateTransition{
+				{State: types.StateMissing, TransitionedAt: at(6)},
+				{State: types.StateReady, TransitionedAt: at(7)},
+			},
+			expect: types.StateSummary{
+				UptimePercent: 75,
+				ReadyPercent:  75,
+				SecondsByState: map[types.State]float64{
+					types.StateMissing: 3600,
+					types.StateReady:   10800,
+				},
+				Timeline: []types.StatePeriod{
+					{State: types.StateMissing, From: at(6), To: at(7)},
+					{State: types.StateReady, From: at(7), To: to},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			summary := summarize(test.initial, test.transitions, from, to)
+			assert.Equal(t, test.expect, summary)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"time"
+)
+
+type State string
+
+const (
+	StateReady       State = "ready"
+	StateDegraded    State = "degraded"
+	StateUnavailable State = "unavailable"
+	StateMissing     State = "missing"
+)
+
+type AppStatus struct {
+	AppID          string         `json:"appId"`
+	ClusterID      string         `json:"clusterId"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	ResourceStates ResourceStates `json:"resourceStates"`
+
+	// Transitions are the changes in resource states reported by the operator since its last app status
+	Transitions []ResourceStateTransition `json:"transitions,omitempty"`
+}
+
+type ResourceStates []ResourceState
+
+type ResourceState struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	State     State  `json:"state"`
+}
+
+type ResourceStateTransition struct {
+	ResourceState
+	TransitionedAt time.Time `json:"transitionedAt"`
+}
+
+// DefaultReadyState is the status of an app without status informers
+var DefaultReadyState = ResourceStates{
+	{Kind: "EMPTY", Name: "EMPTY", Namespace: "EMPTY", State: StateReady},
+}
+
+// GetState returns the state of an app, which is the least ready state of its resources
+func GetState(resourceStates ResourceStates) State {
+	if len(resourceStates) == 0 {
+		return StateMissing
+	}
+
+	state := StateReady
+	for _, resourceState := range resourceStates {
+		switch resourceState.State {
+		case StateMissing:
+			return StateMissing
+		case StateUnavailable:
+			state = StateUnavailable
+		case StateDegraded:
+			if state == StateReady {
+				state = StateDegraded
+			}
+		}
+	}
+	return state
+}
+
+// StatusHistory summarizes the states of an app and its resources between From and To
+type StatusHistory struct {
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	App       StateSummary           `json:"app"`
+	Resources []ResourceStateSummary `json:"resources"`
+}
+
+type ResourceStateSummary struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	StateSummary
+}
+
+// StateSummary is the time spent in each state. Time before the first known state is not counted,
+// so percentages are of the time that the state was known.
+type StateSummary struct {
+	// UptimePercent is the time spent ready or degraded, ReadyPercent is the time spent ready
+	UptimePercent  float64           `json:"uptimePercent"`
+	ReadyPercent   float64           `json:"readyPercent"`
+	SecondsByState map[State]float64 `json:"secondsByState"`
+	Timeline       []StatePeriod     `json:"timeline"`
+}
+
+type StatePeriod struct {
+	State State     `json:"state"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+}
+
+type StateTransition struct {
+	State          State
+	TransitionedAt time.Time
+}
@@ -0,0 +1,270 @@
+package cluster
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/cluster/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+	"github.com/replicatedhq/kots/pkg/util"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+)
+
+const tokenLength = 32
+
+var (
+	ErrClusterNotFound       = errors.New("cluster not found")
+	ErrClusterHasDownstreams = errors.New("cluster has apps deployed to it")
+	ErrLocalCluster          = errors.New("the cluster that kotsadm runs in can not be changed")
+)
+
+// GetIDFromDeployToken returns the id of the cluster that the operator token belongs to, or an
+// empty string if there is none
+func GetIDFromDeployToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+
+	db := persistence.MustGetPGSession()
+	query := `select id from cluster where token = $1`
+	row := db.QueryRow(query, token)
+
+	var clusterID string
+	if err := row.Scan(&clusterID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "failed to scan cluster id")
+	}
+
+	return clusterID, nil
+}
+
+// Create registers a cluster that an operator can connect to with the token of the returned cluster
+func Create(title string) (*types.Cluster, error) {
+	logger.Debug("creating cluster",
+		zap.String("title", title))
+
+	db := persistence.MustGetPGSession()
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	titleForSlug := slug.Make(title)
+	slugProposal := titleForSlug
+
+	foundUniqueSlug := false
+	i := 0
+	for !foundUniqueSlug {
+		if i > 0 {
+			slugProposal = fmt.Sprintf("%s-%d", titleForSlug, i)
+		}
+
+		query := `select count(1) as count from cluster where slug = $1`
+		row := tx.QueryRow(query, slugProposal)
+		exists := 0
+		if err := row.Scan(&exists); err != nil {
+			return nil, errors.Wrap(err, "failed to scan existing slug")
+		}
+
+		if exists == 0 {
+			foundUniqueSlug = true
+		} else {
+			i++
+		}
+	}
+
+	cluster := types.Cluster{
+		ID:        ksuid.New().String(),
+		Title:     title,
+		Slug:      slugProposal,
+		CreatedAt: time.Now(),
+		Token:     util.GenPassword(tokenLength),
+	}
+
+	query := `insert into cluster (id, title, slug, created_at, updated_at, cluster_type, is_all_users, token)
+values ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.Exec(query, cluster.ID, cluster.Title, cluster.Slug, cluster.CreatedAt, nil, "ship", true, cluster.Token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to insert cluster")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return &cluster, nil
+}
+
+// List returns the clusters that operators deploy to, gitops clusters are not included
+func List() ([]*types.Cluster, error) {
+	db := persistence.MustGetPGSession()
+	query := `select id, title, slug, created_at, last_heartbeat_at from cluster where cluster_type = 'ship' order by created_at, title`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query clusters")
+	}
+	defer rows.Close()
+
+	clusters := []*types.Cluster{}
+	for rows.Next() {
+		cluster, err := scanCluster(rows)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to iterate clusters")
+	}
+
+	return clusters, nil
+}
+
+func Get(id string) (*types.Cluster, error) {
+	db := persistence.MustGetPGSession()
+	query := `select id, title, slug, created_at, last_heartbeat_at from cluster where id = $1 and cluster_type = 'ship'`
+	row := db.QueryRow(query, id)
+
+	cluster, err := scanCluster(row)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return nil, ErrClusterNotFound
+	}
+	return cluster, err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCluster(row scanner) (*types.Cluster, error) {
+	cluster := types.Cluster{}
+	var lastHeartbeatAt sql.NullTime
+	if err := row.Scan(&cluster.ID, &cluster.Title, &cluster.Slug, &cluster.CreatedAt, &lastHeartbeatAt); err != nil {
+		return nil, errors.Wrap(err, "failed to scan cluster")
+	}
+	if lastHeartbeatAt.Valid {
+		cluster.LastHeartbeatAt = &lastHeartbeatAt.Time
+	}
+
+	return &cluster, nil
+}
+
+// RotateToken issues a new token for the cluster. The operator must be updated with the new
+// token before it can connect again.
+func RotateToken(id string) (*types.Cluster, error) {
+	cluster, err := Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireRemote(id); err != nil {
+		return nil, err
+	}
+
+	cluster.Token = util.GenPassword(tokenLength)
+
+	db := persistence.MustGetPGSession()
+	query := `update cluster set token = $1, updated_at = $2 where id = $3`
+	_, err = db.Exec(query, cluster.Token, time.Now(), id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update cluster token")
+	}
+
+	return cluster, nil
+}
+
+// Delete removes a cluster that has no apps deployed to it
+func Delete(id string) error {
+	if err := requireRemote(id); err != nil {
+		return err
+	}
+
+	db := persistence.MustGetPGSession()
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	query := `select count(1) from app_downstream where cluster_id = $1`
+	row := tx.QueryRow(query, id)
+	count := 0
+	if err := row.Scan(&count); err != nil {
+		return errors.Wrap(err, "failed to count downstreams")
+	}
+	if count > 0 {
+		return ErrClusterHasDownstreams
+	}
+
+	query = `delete from user_cluster where cluster_id = $1`
+	if _, err := tx.Exec(query, id); err != nil {
+		return errors.Wrap(err, "failed to delete user cluster")
+	}
+
+	query = `delete from cluster where id = $1 and cluster_type = 'ship'`
+	result, err := tx.Exec(query, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete cluster")
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return errors.Wrap(err, "failed to get rows affected")
+	} else if rowsAffected == 0 {
+		return ErrClusterNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+// IsLocal returns true for the cluster that kotsadm creates for its own operator, whose token is in
+// the deployment's environment
+func IsLocal(id string) (bool, error) {
+	token := os.Getenv("AUTO_CREATE_CLUSTER_TOKEN")
+	if token == "" {
+		return false, nil
+	}
+
+	localClusterID, err := GetIDFromDeployToken(token)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get local cluster")
+	}
+
+	return localClusterID == id, nil
+}
+
+// requireRemote returns ErrLocalCluster for the local cluster
+func requireRemote(id string) error {
+	isLocal, err := IsLocal(id)
+	if err != nil {
+		return err
+	}
+	if isLocal {
+		return ErrLocalCluster
+	}
+
+	return nil
+}
+
+// SetHeartbeat records the last time that the cluster's operator was heard from
+func SetHeartbeat(id string, heartbeatAt time.Time) error {
+	db := persistence.MustGetPGSession()
+	query := `update cluster set last_heartbeat_at = $1 where id = $2`
+	_, err := db.Exec(query, heartbeatAt, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to update last heartbeat")
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+package types
+
+import (
+	"time"
+)
+
+// Cluster is a cluster that an operator deploys apps to. Token is only set when it was just issued.
+type Cluster struct {
+	ID              string     `json:"id"`
+	Title           string     `json:"title"`
+	Slug            string     `json:"slug"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	LastHeartbeatAt *time.Time `json:"lastHeartbeatAt"`
+	Token           string     `json:"token,omitempty"`
+}
@@ -1,11 +1,15 @@
 package config
 
 import (
+	"encoding/base64"
+
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/kotsadm/pkg/kotsutil"
 	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
 	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/replicatedhq/kots/kotskinds/multitype"
 	kotsconfig "github.com/replicatedhq/kots/pkg/config"
+	"github.com/replicatedhq/kots/pkg/crypto"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/template"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -31,6 +35,44 @@ func IsUnsetItem(item kotsv1beta1.ConfigItem) bool {
 	return true
 }
 
+// ValidateConfigItems checks the submitted item values against the validation rules in the config spec.
+// The rules, the item types and whether an item is shown are read from the spec rather than the submitted
+// items so that a client can't drop them. Only the values are taken from the submitted items.
+func ValidateConfigItems(configSpec *kotsv1beta1.Config, configGroups []*kotsv1beta1.ConfigGroup, cipher *crypto.AESCipher) []kotsconfig.ItemValidationError {
+	if configSpec == nil {
+		return []kotsconfig.ItemValidationError{}
+	}
+
+	itemTypes := map[string]string{}
+	for _, group := range configSpec.Spec.Groups {
+		for _, item := range group.Items {
+			itemTypes[item.Name] = item.Type
+		}
+	}
+
+	values := map[string]string{}
+	for _, group := range configGroups {
+		for _, item := range group.Items {
+			if item.Value.Type != multitype.String {
+				continue
+			}
+
+			value := item.Value.String()
+			if itemTypes[item.Name] == "password" && cipher != nil {
+				// unchanged passwords are sent back encrypted
+				if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+					if decrypted, err := cipher.Decrypt(decoded); err == nil {
+						value = string(decrypted)
+					}
+				}
+			}
+			values[item.Name] = value
+		}
+	}
+
+	return kotsconfig.ValidateConfigValues(configSpec, values)
+}
+
 func NeedsConfiguration(configSpec string, configValuesSpec string, licenseSpec string) (bool, error) {
 	if configSpec == "" {
 		return false, nil
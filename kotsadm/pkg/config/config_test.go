@@ -1 +1,89 @@
 package config
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/replicatedhq/kots/kotskinds/multitype"
+	"github.com/stretchr/testify/assert"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_ValidateConfigItems(t *testing.T) {
+	maxLength := 5
+	configSpec := &kotsv1beta1.Config{
+		Spec: kotsv1beta1.ConfigSpec{
+			Groups: []kotsv1beta1.ConfigGroup{
+				{
+					Name: "database",
+					Items: []kotsv1beta1.ConfigItem{
+						{
+							Name:       "hostname",
+							Type:       "text",
+							Validation: &kotsv1beta1.ConfigItemValidation{MaxLength: &maxLength},
+						},
+						{
+							Name:       "hidden_hostname",
+							Type:       "text",
+							Hidden:     true,
+							Validation: &kotsv1beta1.ConfigItemValidation{MaxLength: &maxLength},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		group  *kotsv1beta1.ConfigGroup
+		expect []string
+	}{
+		{
+			name: "valid",
+			group: &kotsv1beta1.ConfigGroup{
+				Items: []kotsv1beta1.ConfigItem{
+					{Name: "hostname", Value: multitype.FromString("db")},
+				},
+			},
+			expect: []string{},
+		},
+		{
+			name: "invalid",
+			group: &kotsv1beta1.ConfigGroup{
+				Items: []kotsv1beta1.ConfigItem{
+					{Name: "hostname", Value: multitype.FromString("database")},
+				},
+			},
+			expect: []string{"hostname"},
+		},
+		{
+			name: "submitted as hidden",
+			group: &kotsv1beta1.ConfigGroup{
+				When: "false",
+				Items: []kotsv1beta1.ConfigItem{
+					{Name: "hostname", Value: multitype.FromString("database"), Hidden: true, When: "false"},
+				},
+			},
+			expect: []string{"hostname"},
+		},
+		{
+			name: "hidden in the spec",
+			group: &kotsv1beta1.ConfigGroup{
+				Items: []kotsv1beta1.ConfigItem{
+					{Name: "hidden_hostname", Value: multitype.FromString("database")},
+				},
+			},
+			expect: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := []string{}
+			for _, validationError := range ValidateConfigItems(configSpec, []*kotsv1beta1.ConfigGroup{test.group}, nil) {
+				names = append(names, validationError.Name)
+			}
+			assert.Equal(t, test.expect, names)
+		})
+	}
+}
@@ -8,22 +8,98 @@ import (
 	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
 )
 
+var (
+	ErrDownstreamExists = errors.New("app is already deployed to the cluster")
+)
+
 func ListDownstreamsForApp(appID string) ([]*types.Downstream, error) {
 	db := persistence.MustGetPGSession()
-	query := `select cluster_id, downstream_name, current_sequence from app_downstream where app_id = $1`
+	query := `select app_id, cluster_id, downstream_name, current_sequence from app_downstream where app_id = $1`
 	rows, err := db.Query(query, appID)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get downstreams")
 	}
 	defer rows.Close()
 
+	return scanDownstreams(rows)
+}
+
+func ListDownstreamsForCluster(clusterID string) ([]*types.Downstream, error) {
+	db := persistence.MustGetPGSession()
+	query := `select app_id, cluster_id, downstream_name, current_sequence from app_downstream where cluster_id = $1`
+	rows, err := db.Query(query, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get downstreams")
+	}
+	defer rows.Close()
+
+	return scanDownstreams(rows)
+}
+
+// CreateDownstream deploys the app to another cluster. The new downstream starts at the current
+// sequence of the app's other downstreams and copies their version history. The current version is
+// pending in the new cluster until the operator there deploys it.
+func CreateDownstream(appID string, clusterID string, downstreamName string) error {
+	db := persistence.MustGetPGSession()
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	query := `select count(1) from app_downstream where app_id = $1 and cluster_id = $2`
+	row := tx.QueryRow(query, appID, clusterID)
+	count := 0
+	if err := row.Scan(&count); err != nil {
+		return errors.Wrap(err, "failed to count downstreams")
+	}
+	if count > 0 {
+		return ErrDownstreamExists
+	}
+
+	query = `select cluster_id, current_sequence from app_downstream where app_id = $1 order by current_sequence desc nulls last limit 1`
+	row = tx.QueryRow(query, appID)
+	var sourceClusterID string
+	var currentSequence sql.NullInt64
+	if err := row.Scan(&sourceClusterID, &currentSequence); err != nil && err != sql.ErrNoRows {
+		return errors.Wrap(err, "failed to get current sequence")
+	}
+
+	query = `insert into app_downstream (app_id, cluster_id, downstream_name, current_sequence) values ($1, $2, $3, $4)`
+	_, err = tx.Exec(query, appID, clusterID, downstreamName, currentSequence)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert downstream")
+	}
+
+	if sourceClusterID != "" {
+		// versions before the current one were never deployed to this cluster
+		query = `insert into app_downstream_version (app_id, cluster_id, sequence, parent_sequence, created_at, applied_at, version_label,
+			status, source, diff_summary, preflight_result, preflight_result_created_at, preflight_ignore_permissions, git_deployable)
+			select app_id, $3, sequence, parent_sequence, created_at, null, version_label,
+			case when sequence = $4 or status in ('deployed', 'failed') then 'pending' else status end,
+			source, diff_summary, preflight_result, preflight_result_created_at, preflight_ignore_permissions, git_deployable
+			from app_downstream_version where app_id = $1 and cluster_id = $2`
+		_, err = tx.Exec(query, appID, sourceClusterID, clusterID, currentSequence)
+		if err != nil {
+			return errors.Wrap(err, "failed to copy downstream versions")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+func scanDownstreams(rows *sql.Rows) ([]*types.Downstream, error) {
 	downstreams := []*types.Downstream{}
 	for rows.Next() {
 		downstream := types.Downstream{
 			CurrentSequence: -1,
 		}
 		var sequence sql.NullInt64
-		if err := rows.Scan(&downstream.ClusterID, &downstream.Name, &sequence); err != nil {
+		if err := rows.Scan(&downstream.AppID, &downstream.ClusterID, &downstream.Name, &sequence); err != nil {
 			return nil, errors.Wrap(err, "failed to scan downstream")
 		}
 		if sequence.Valid {
@@ -60,6 +136,44 @@ func SetDownstreamVersionPendingPreflight(appID string, sequence int64) error {
 	return nil
 }
 
+// GetPreviouslyDeployedSequence returns the sequence that was deployed before the current one, or -1 if there is none
+func GetPreviouslyDeployedSequence(appID string, clusterID string) (int64, error) {
+	db := persistence.MustGetPGSession()
+	query := `select sequence from app_downstream_version where app_id = $1 and cluster_id = $2 and applied_at is not null order by applied_at desc limit 2`
+	rows, err := db.Query(query, appID, clusterID)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to query deployed sequences")
+	}
+	defer rows.Close()
+
+	sequences := []int64{}
+	for rows.Next() {
+		var sequence int64
+		if err := rows.Scan(&sequence); err != nil {
+			return -1, errors.Wrap(err, "failed to scan sequence")
+		}
+		sequences = append(sequences, sequence)
+	}
+
+	if len(sequences) < 2 {
+		return -1, nil
+	}
+
+	return sequences[1], nil
+}
+
+// SetDownstreamVersionStatus sets the status and status info for the downstream version with the given sequence, app id and cluster id
+func SetDownstreamVersionStatus(appID string, clusterID string, sequence int64, status string, statusInfo string) error {
+	db := persistence.MustGetPGSession()
+	query := `update app_downstream_version set status = $4, status_info = $5 where app_id = $1 and cluster_id = $2 and sequence = $3`
+	_, err := db.Exec(query, appID, clusterID, sequence, status, statusInfo)
+	if err != nil {
+		return errors.Wrap(err, "failed to set downstream version status")
+	}
+
+	return nil
+}
+
 // GetDownstreamVersionStatus gets the status for the downstream version with the given sequence and app id
 func GetDownstreamVersionStatus(appID string, sequence int64) (string, error) {
 	db := persistence.MustGetPGSession()
@@ -1,6 +1,7 @@
 package types
 
 type Downstream struct {
+	AppID           string
 	ClusterID       string
 	Name            string
 	CurrentSequence int64
@@ -0,0 +1,53 @@
+package drift
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/drift/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+)
+
+// SetReport replaces the app's drift report for the cluster in the report
+func SetReport(report *types.DriftReport) error {
+	objects, err := json.Marshal(report.Objects)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal drifted objects")
+	}
+
+	db := persistence.MustGetPGSession()
+	query := `insert into app_drift (app_id, cluster_id, sequence, drifted_objects, reapplied, reapply_error, scanned_at)
+		values ($1, $2, $3, $4, $5, $6, $7)
+		on conflict (app_id, cluster_id) do update set sequence = $3, drifted_objects = $4, reapplied = $5, reapply_error = $6, scanned_at = $7`
+	_, err = db.Exec(query, report.AppID, report.ClusterID, report.Sequence, string(objects), report.Reapplied, report.ReapplyError, report.ScannedAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert drift report")
+	}
+
+	return nil
+}
+
+// GetReport returns the app's last drift report in the cluster, or nil if it was never scanned there
+func GetReport(appID string, clusterID string) (*types.DriftReport, error) {
+	db := persistence.MustGetPGSession()
+	query := `select app_id, cluster_id, sequence, drifted_objects, reapplied, reapply_error, scanned_at from app_drift where app_id = $1 and cluster_id = $2`
+	row := db.QueryRow(query, appID, clusterID)
+
+	report := types.DriftReport{}
+	var objects string
+	var reapplyError sql.NullString
+	if err := row.Scan(&report.AppID, &report.ClusterID, &report.Sequence, &objects, &report.Reapplied, &reapplyError, &report.ScannedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to scan drift report")
+	}
+	report.ReapplyError = reapplyError.String
+
+	if err := json.Unmarshal([]byte(objects), &report.Objects); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal drifted objects")
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"time"
+)
+
+// DriftReport is the result of the operator's last comparison of the live objects of an app
+// with the manifests it applied
+type DriftReport struct {
+	AppID        string        `json:"appId"`
+	ClusterID    string        `json:"clusterId"`
+	Sequence     int64         `json:"sequence"`
+	ScannedAt    time.Time     `json:"scannedAt"`
+	Objects      []ObjectDrift `json:"objects"`
+	Reapplied    bool          `json:"reapplied"`
+	ReapplyError string        `json:"reapplyError,omitempty"`
+}
+
+// ObjectDrift and FieldDrift are reported by the operator, see kotsadm/operator/pkg/drift
+type ObjectDrift struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Namespace  string       `json:"namespace,omitempty"`
+	Name       string       `json:"name"`
+	Missing    bool         `json:"missing,omitempty"`
+	Fields     []FieldDrift `json:"fields,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+type FieldDrift struct {
+	Path    string      `json:"path"`
+	Desired interface{} `json:"desired"`
+	Live    interface{} `json:"live"`
+}
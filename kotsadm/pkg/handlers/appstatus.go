@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/appstatus"
+	appstatustypes "github.com/replicatedhq/kots/kotsadm/pkg/appstatus/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/downstream"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+)
+
+// UpdateAppStatusRequest is sent by the operator when the states of the app's resources change
+type UpdateAppStatusRequest struct {
+	AppID          string                              `json:"app_id"`
+	ResourceStates []UpdateAppStatusResourceState      `json:"resource_states"`
+	UpdatedAt      time.Time                           `json:"updated_at"`
+	Transitions    []UpdateAppStatusResourceTransition `json:"transitions"`
+}
+
+type UpdateAppStatusResourceState struct {
+	Kind      string               `json:"kind"`
+	Name      string               `json:"name"`
+	Namespace string               `json:"namespace"`
+	State     appstatustypes.State `json:"state"`
+}
+
+type UpdateAppStatusResourceTransition struct {
+	UpdateAppStatusResourceState
+	TransitionedAt time.Time `json:"transitioned_at"`
+}
+
+type GetAppStatusHistoryResponse struct {
+	History *appstatustypes.StatusHistory `json:"history"`
+}
+
+func UpdateAppStatus(w http.ResponseWriter, r *http.Request) {
+	clusterID, err := requireValidClusterToken(w, r)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	updateAppStatusRequest := UpdateAppStatusRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&updateAppStatusRequest); err != nil {
+		logger.Error(err)
+		w.WriteHeader(400)
+		return
+	}
+
+	downstreams, err := downstream.ListDownstreamsForApp(updateAppStatusRequest.AppID)
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	isDeployedToCluster := false
+	for _, d := range downstreams {
+		if d.ClusterID == clusterID {
+			isDeployedToCluster = true
+			break
+		}
+	}
+	if !isDeployedToCluster {
+		// the operator's token does not allow it to report the status of apps in other clusters
+		w.WriteHeader(403)
+		return
+	}
+
+	appStatus := appstatustypes.AppStatus{
+		AppID:          updateAppStatusRequest.AppID,
+		ClusterID:      clusterID,
+		UpdatedAt:      updateAppStatusRequest.UpdatedAt,
+		ResourceStates: appstatustypes.ResourceStates{},
+	}
+	for _, resourceState := range updateAppStatusRequest.ResourceStates {
+		appStatus.ResourceStates = append(appStatus.ResourceStates, appstatustypes.ResourceState(resourceState))
+	}
+	for _, transition := range updateAppStatusRequest.Transitions {
+		appStatus.Transitions = append(appStatus.Transitions, appstatustypes.ResourceStateTransition{
+			ResourceState:  appstatustypes.ResourceState(transition.UpdateAppStatusResourceState),
+			TransitionedAt: transition.TransitionedAt,
+		})
+	}
+
+	if err := appstatus.Set(appStatus); err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// GetAppStatusHistory returns the uptime and state timeline of the app and its resources in a cluster. The
+// window is given with the from and to query params in RFC3339 and defaults to the last 24 hours.
+func GetAppStatusHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	now := time.Now()
+	to := now
+	if param := r.URL.Query().Get("to"); param != "" {
+		t, err := time.Parse(time.RFC3339, param)
+		if err != nil {
+			logger.Error(err)
+			w.WriteHeader(400)
+			return
+		}
+		to = t
+	}
+	if to.After(now) {
+		to = now
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if param := r.URL.Query().Get("from"); param != "" {
+		t, err := time.Parse(time.RFC3339, param)
+		if err != nil {
+			logger.Error(err)
+			w.WriteHeader(400)
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		w.WriteHeader(400)
+		return
+	}
+
+	foundApp, err := app.GetFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	history, err := appstatus.GetHistory(foundApp.ID, mux.Vars(r)["clusterId"], from, to)
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	JSON(w, 200, GetAppStatusHistoryResponse{
+		History: history,
+	})
+}
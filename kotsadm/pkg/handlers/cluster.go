@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/cluster"
+	clustertypes "github.com/replicatedhq/kots/kotsadm/pkg/cluster/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/downstream"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/operator"
+)
+
+type listClustersResponse struct {
+	Clusters []clusterResponse `json:"clusters"`
+}
+
+type clusterResponse struct {
+	clustertypes.Cluster
+	IsConnected bool     `json:"isConnected"`
+	AppIDs      []string `json:"appIds,omitempty"`
+}
+
+type createClusterRequest struct {
+	Title string `json:"title"`
+}
+
+// clusterTokenResponse is returned when a token is issued, the operator in the cluster connects to
+// kotsadm with KOTSADM_TOKEN set to the token
+type clusterTokenResponse struct {
+	Success bool                  `json:"success"`
+	Error   string                `json:"error,omitempty"`
+	Cluster *clustertypes.Cluster `json:"cluster,omitempty"`
+}
+
+type deleteClusterResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type createAppDownstreamResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+func ListClusters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	clusters, err := cluster.List()
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	response := listClustersResponse{
+		Clusters: []clusterResponse{},
+	}
+	for _, c := range clusters {
+		downstreams, err := downstream.ListDownstreamsForCluster(c.ID)
+		if err != nil {
+			logger.Error(err)
+			w.WriteHeader(500)
+			return
+		}
+
+		item := clusterResponse{
+			Cluster:     *c,
+			IsConnected: operator.IsConnected(c.ID),
+		}
+		for _, d := range downstreams {
+			item.AppIDs = append(item.AppIDs, d.AppID)
+		}
+		response.Clusters = append(response.Clusters, item)
+	}
+
+	JSON(w, 200, response)
+}
+
+func CreateCluster(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	response := clusterTokenResponse{}
+
+	request := createClusterRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error(err)
+		response.Error = "failed to decode request body"
+		JSON(w, 400, response)
+		return
+	}
+
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		response.Error = "title is required"
+		JSON(w, 400, response)
+		return
+	}
+
+	c, err := cluster.Create(title)
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	response.Success = true
+	response.Cluster = c
+
+	JSON(w, 200, response)
+}
+
+func RotateClusterToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	clusterID := mux.Vars(r)["clusterId"]
+	c, err := cluster.RotateToken(clusterID)
+	if errors.Cause(err) == cluster.ErrClusterNotFound {
+		JSON(w, 404, clusterTokenResponse{Error: err.Error()})
+		return
+	} else if errors.Cause(err) == cluster.ErrLocalCluster {
+		JSON(w, 400, clusterTokenResponse{Error: err.Error()})
+		return
+	} else if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// the operator is connected with the old token
+	operator.Disconnect(clusterID)
+
+	JSON(w, 200, clusterTokenResponse{
+		Success: true,
+		Cluster: c,
+	})
+}
+
+func DeleteCluster(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	clusterID := mux.Vars(r)["clusterId"]
+	err := cluster.Delete(clusterID)
+	switch errors.Cause(err) {
+	case nil:
+	case cluster.ErrClusterNotFound:
+		JSON(w, 404, deleteClusterResponse{Error: err.Error()})
+		return
+	case cluster.ErrClusterHasDownstreams, cluster.ErrLocalCluster:
+		JSON(w, 400, deleteClusterResponse{Error: err.Error()})
+		return
+	default:
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	operator.Disconnect(clusterID)
+
+	JSON(w, 200, deleteClusterResponse{Success: true})
+}
+
+// CreateAppDownstream deploys the app to the cluster, starting at the app's current version
+func CreateAppDownstream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	foundApp, err := app.GetFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	c, err := cluster.Get(mux.Vars(r)["clusterId"])
+	if errors.Cause(err) == cluster.ErrClusterNotFound {
+		JSON(w, 404, createAppDownstreamResponse{Error: err.Error()})
+		return
+	} else if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	err = downstream.CreateDownstream(foundApp.ID, c.ID, c.Title)
+	if errors.Cause(err) == downstream.ErrDownstreamExists {
+		JSON(w, 409, createAppDownstreamResponse{Error: err.Error()})
+		return
+	} else if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	JSON(w, 200, createAppDownstreamResponse{Success: true})
+}
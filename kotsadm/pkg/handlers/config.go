@@ -27,6 +27,7 @@ import (
 	versiontypes "github.com/replicatedhq/kots/kotsadm/pkg/version/types"
 	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
 	"github.com/replicatedhq/kots/kotskinds/multitype"
+	kotsconfig "github.com/replicatedhq/kots/pkg/config"
 	"github.com/replicatedhq/kots/pkg/crypto"
 )
 
@@ -37,9 +38,10 @@ type UpdateAppConfigRequest struct {
 }
 
 type UpdateAppConfigResponse struct {
-	Success       bool     `json:"success"`
-	Error         string   `json:"error,omitempty"`
-	RequiredItems []string `json:"requiredItems,omitempty"`
+	Success          bool                             `json:"success"`
+	Error            string                           `json:"error,omitempty"`
+	RequiredItems    []string                         `json:"requiredItems,omitempty"`
+	ValidationErrors []kotsconfig.ItemValidationError `json:"validationErrors,omitempty"`
 }
 
 func UpdateAppConfig(w http.ResponseWriter, r *http.Request) {
@@ -96,7 +98,7 @@ func UpdateAppConfig(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if len(resp.RequiredItems) > 0 {
+		if len(resp.RequiredItems) > 0 || len(resp.ValidationErrors) > 0 {
 			JSON(w, 400, resp)
 			return
 		}
@@ -125,7 +127,7 @@ func UpdateAppConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(resp.RequiredItems) > 0 {
+	if len(resp.RequiredItems) > 0 || len(resp.ValidationErrors) > 0 {
 		JSON(w, 400, resp)
 		return
 	}
@@ -184,11 +186,36 @@ func updateAppConfig(updateApp *app.App, sequence int64, req UpdateAppConfigRequ
 		return updateAppConfigResponse, nil
 	}
 
+	// without a cipher, passwords are validated as they were submitted
+	validationCipher, _ := crypto.AESCipherFromString(kotsKinds.Installation.Spec.EncryptionKey)
+
+	// values that break a validation rule are never written, for any version
+	validationErrors := config.ValidateConfigItems(kotsKinds.Config, req.ConfigGroups, validationCipher)
+	if len(validationErrors) > 0 {
+		messages := make([]string, 0, len(validationErrors))
+		for _, validationError := range validationErrors {
+			messages = append(messages, fmt.Sprintf("%s %s", validationError.Title, validationError.Message))
+		}
+		updateAppConfigResponse.ValidationErrors = validationErrors
+		updateAppConfigResponse.Error = fmt.Sprintf("The following fields are invalid: %s", strings.Join(messages, ", "))
+		if isPrimaryVersion {
+			return updateAppConfigResponse, nil
+		}
+		return updateAppConfigResponse, errors.New(updateAppConfigResponse.Error)
+	}
+
 	// we don't merge, this is a wholesale replacement of the config values
 	// so we don't need the complex logic in kots, we can just write
 	values := kotsKinds.ConfigValues.Spec.Values
 	for _, group := range req.ConfigGroups {
 		for _, item := range group.Items {
+			// array items and items in repeatable groups keep their ordered list of values
+			if group.Repeatable || item.Multiple {
+				v := values[item.Name]
+				v.MultiValue = item.MultiValue
+				values[item.Name] = v
+			}
+
 			if item.Value.Type == multitype.Bool {
 				updatedValue := item.Value.BoolVal
 				v := values[item.Name]
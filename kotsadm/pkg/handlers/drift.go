@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/downstream"
+	"github.com/replicatedhq/kots/kotsadm/pkg/drift"
+	drifttypes "github.com/replicatedhq/kots/kotsadm/pkg/drift/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+)
+
+// UpdateDriftReportRequest is sent by the operator after each drift scan
+type UpdateDriftReportRequest struct {
+	AppID        string                   `json:"app_id"`
+	ScannedAt    time.Time                `json:"scanned_at"`
+	Objects      []drifttypes.ObjectDrift `json:"objects"`
+	Reapplied    bool                     `json:"reapplied"`
+	ReapplyError string                   `json:"reapply_error"`
+}
+
+type GetDriftReportResponse struct {
+	Report *drifttypes.DriftReport `json:"report"`
+}
+
+func UpdateDriftReport(w http.ResponseWriter, r *http.Request) {
+	clusterID, err := requireValidClusterToken(w, r)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+
+	updateDriftReportRequest := UpdateDriftReportRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&updateDriftReportRequest); err != nil {
+		logger.Error(err)
+		w.WriteHeader(400)
+		return
+	}
+
+	downstreams, err := downstream.ListDownstreamsForApp(updateDriftReportRequest.AppID)
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	sequence := int64(-1)
+	for _, d := range downstreams {
+		if d.ClusterID == clusterID {
+			sequence = d.CurrentSequence
+		}
+	}
+	if sequence < 0 {
+		// the app is not deployed to the operator's cluster
+		w.WriteHeader(404)
+		return
+	}
+
+	report := drifttypes.DriftReport{
+		AppID:        updateDriftReportRequest.AppID,
+		ClusterID:    clusterID,
+		Sequence:     sequence,
+		ScannedAt:    updateDriftReportRequest.ScannedAt,
+		Objects:      updateDriftReportRequest.Objects,
+		Reapplied:    updateDriftReportRequest.Reapplied,
+		ReapplyError: updateDriftReportRequest.ReapplyError,
+	}
+	if report.Objects == nil {
+		report.Objects = []drifttypes.ObjectDrift{}
+	}
+	if err := drift.SetReport(&report); err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func GetDriftReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	foundApp, err := app.GetFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	report, err := drift.GetReport(foundApp.ID, mux.Vars(r)["clusterId"])
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+
+	JSON(w, 200, GetDriftReportResponse{
+		Report: report,
+	})
+}
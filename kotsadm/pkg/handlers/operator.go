@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/replicatedhq/kots/kotsadm/pkg/operator"
+)
+
+// OperatorConnect is the websocket that the operator connects to, authenticated with the cluster token
+func OperatorConnect(w http.ResponseWriter, r *http.Request) {
+	operator.Connect(w, r)
+}
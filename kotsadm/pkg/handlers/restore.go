@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -11,10 +13,16 @@ import (
 	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
 	"github.com/replicatedhq/kots/kotsadm/pkg/session"
 	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot"
+	snapshottypes "github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
 	"github.com/replicatedhq/kots/pkg/kotsadm"
 	"github.com/replicatedhq/kots/pkg/kotsadm/types"
 )
 
+// CreateRestoreRequest limits what's restored, an empty request restores the whole snapshot
+type CreateRestoreRequest struct {
+	snapshottypes.RestoreOptions
+}
+
 type CreateRestoreResponse struct {
 	Success bool   `json:"success"`
 	Error   string `json:"error,omitempty"`
@@ -59,6 +67,22 @@ func CreateRestore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	createRestoreRequest := CreateRestoreRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&createRestoreRequest); err != nil && err != io.EOF {
+		logger.Error(err)
+		createRestoreResponse.Error = "failed to decode request body"
+		JSON(w, 400, createRestoreResponse)
+		return
+	}
+	restoreOptions := createRestoreRequest.RestoreOptions
+
+	if err := snapshot.ValidateRestoreOptions(restoreOptions); err != nil {
+		logger.Error(err)
+		createRestoreResponse.Error = errors.Cause(err).Error()
+		JSON(w, 400, createRestoreResponse)
+		return
+	}
+
 	snapshotName := mux.Vars(r)["snapshotName"]
 
 	backup, err := snapshot.GetBackup(snapshotName)
@@ -69,10 +93,18 @@ func CreateRestore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if backup.Annotations[types.VeleroKey] == types.VeleroLabelConsoleValue {
-		// this is a kotsadm snapshot being restored
+	isKotsadmBackup := backup.Annotations[types.VeleroKey] == types.VeleroLabelConsoleValue
+	if isKotsadmBackup && restoreOptions.IsPartial() && !restoreOptions.KotsadmOnly {
+		createRestoreResponse.Error = "admin console snapshots can only be restored in full"
+		JSON(w, 400, createRestoreResponse)
+		return
+	}
+
+	if isKotsadmBackup || restoreOptions.KotsadmOnly {
+		// the admin console is being restored, it's deleted and restored by a job
 		opts := &types.RestoreJobOptions{
-			BackupName: snapshotName,
+			BackupName:  snapshotName,
+			KotsadmOnly: restoreOptions.KotsadmOnly,
 		}
 		if err := kotsadm.CreateRestoreJob(opts); err != nil {
 			logger.Error(err)
@@ -135,7 +167,7 @@ func CreateRestore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.InitiateRestore(snapshotName, appID)
+	err = app.InitiateRestore(snapshotName, appID, restoreOptions)
 	if err != nil {
 		logger.Error(err)
 		createRestoreResponse.Error = "failed to initiate restore"
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/cluster"
 	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
 	"github.com/replicatedhq/kots/kotsadm/pkg/session"
 	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
@@ -67,3 +68,20 @@ func requireValidKOTSToken(w http.ResponseWriter, r *http.Request) error {
 
 	return errors.New("invalid auth")
 }
+
+// requireValidClusterToken authenticates a request from an operator, and returns its cluster id
+func requireValidClusterToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	_, token, _ := r.BasicAuth()
+	clusterID, err := cluster.GetIDFromDeployToken(token)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return "", errors.Wrap(err, "failed to get cluster from token")
+	}
+
+	if clusterID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return "", errors.New("invalid cluster token")
+	}
+
+	return clusterID, nil
+}
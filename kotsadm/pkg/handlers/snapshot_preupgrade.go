@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot"
+)
+
+type PreUpgradeSnapshotPolicyResponse struct {
+	Policy string `json:"policy"`
+
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// UpdatePreUpgradeSnapshotPolicyRequest overrides the pre-upgrade snapshot of the application spec,
+// an empty policy removes the override
+type UpdatePreUpgradeSnapshotPolicyRequest struct {
+	Policy string `json:"policy"`
+}
+
+func GetPreUpgradeSnapshotPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	foundApp, err := app.GetFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		logger.Error(err)
+		JSON(w, 500, PreUpgradeSnapshotPolicyResponse{Error: "failed to get app from app slug"})
+		return
+	}
+
+	JSON(w, 200, PreUpgradeSnapshotPolicyResponse{
+		Policy:  foundApp.PreUpgradeSnapshotPolicy,
+		Success: true,
+	})
+}
+
+func UpdatePreUpgradeSnapshotPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	preUpgradeSnapshotPolicyResponse := PreUpgradeSnapshotPolicyResponse{
+		Success: false,
+	}
+
+	updatePreUpgradeSnapshotPolicyRequest := UpdatePreUpgradeSnapshotPolicyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&updatePreUpgradeSnapshotPolicyRequest); err != nil {
+		logger.Error(err)
+		preUpgradeSnapshotPolicyResponse.Error = "failed to decode request body"
+		JSON(w, 400, preUpgradeSnapshotPolicyResponse)
+		return
+	}
+
+	policy := updatePreUpgradeSnapshotPolicyRequest.Policy
+	switch policy {
+	case "", snapshot.PreUpgradeSnapshotDisabled, snapshot.PreUpgradeSnapshotWarn, snapshot.PreUpgradeSnapshotBlock:
+	default:
+		preUpgradeSnapshotPolicyResponse.Error = "unknown pre-upgrade snapshot policy"
+		JSON(w, 400, preUpgradeSnapshotPolicyResponse)
+		return
+	}
+
+	foundApp, err := app.GetFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		logger.Error(err)
+		preUpgradeSnapshotPolicyResponse.Error = "failed to get app from app slug"
+		JSON(w, 500, preUpgradeSnapshotPolicyResponse)
+		return
+	}
+
+	if err := app.SetPreUpgradeSnapshotPolicy(foundApp.ID, policy); err != nil {
+		logger.Error(err)
+		preUpgradeSnapshotPolicyResponse.Error = "failed to set pre-upgrade snapshot policy"
+		JSON(w, 500, preUpgradeSnapshotPolicyResponse)
+		return
+	}
+
+	preUpgradeSnapshotPolicyResponse.Policy = policy
+	preUpgradeSnapshotPolicyResponse.Success = true
+
+	JSON(w, 200, preUpgradeSnapshotPolicyResponse)
+}
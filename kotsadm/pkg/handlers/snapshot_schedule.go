@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot"
+	snapshottypes "github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshotscheduler"
+	cron "github.com/robfig/cron/v3"
+)
+
+type SnapshotScheduleResponse struct {
+	Schedule  string                  `json:"schedule"`
+	Retention snapshottypes.Retention `json:"retention"`
+
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// UpdateSnapshotScheduleRequest replaces the schedule, an empty schedule disables scheduled snapshots
+type UpdateSnapshotScheduleRequest struct {
+	Schedule  string                  `json:"schedule"`
+	Retention snapshottypes.Retention `json:"retention"`
+}
+
+func GetAppSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	foundApp, err := app.GetFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		logger.Error(err)
+		JSON(w, 500, SnapshotScheduleResponse{Error: "failed to get app from app slug"})
+		return
+	}
+
+	getSnapshotSchedule(w, foundApp.ID)
+}
+
+func UpdateAppSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	foundApp, err := app.GetFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		logger.Error(err)
+		JSON(w, 500, SnapshotScheduleResponse{Error: "failed to get app from app slug"})
+		return
+	}
+
+	updateSnapshotSchedule(w, r, foundApp.ID)
+}
+
+// GetInstanceSnapshotSchedule returns the schedule that backs up every app and the admin console
+func GetInstanceSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	getSnapshotSchedule(w, snapshot.InstanceScheduleID)
+}
+
+func UpdateInstanceSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(200)
+		return
+	}
+
+	if err := requireValidSession(w, r); err != nil {
+		logger.Error(err)
+		return
+	}
+
+	updateSnapshotSchedule(w, r, snapshot.InstanceScheduleID)
+}
+
+func getSnapshotSchedule(w http.ResponseWriter, scheduleID string) {
+	schedule, err := snapshot.GetSchedule(scheduleID)
+	if err != nil {
+		logger.Error(err)
+		JSON(w, 500, SnapshotScheduleResponse{Error: "failed to get schedule"})
+		return
+	}
+
+	snapshotScheduleResponse := SnapshotScheduleResponse{
+		Success: true,
+	}
+	if schedule != nil {
+		snapshotScheduleResponse.Schedule = schedule.Schedule
+		snapshotScheduleResponse.Retention = schedule.Retention
+	}
+
+	JSON(w, 200, snapshotScheduleResponse)
+}
+
+func updateSnapshotSchedule(w http.ResponseWriter, r *http.Request, scheduleID string) {
+	snapshotScheduleResponse := SnapshotScheduleResponse{
+		Success: false,
+	}
+
+	updateSnapshotScheduleRequest := UpdateSnapshotScheduleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&updateSnapshotScheduleRequest); err != nil {
+		logger.Error(err)
+		snapshotScheduleResponse.Error = "failed to decode request body"
+		JSON(w, 400, snapshotScheduleResponse)
+		return
+	}
+
+	retention := updateSnapshotScheduleRequest.Retention
+	if retention.KeepLast < 0 || retention.KeepDaily < 0 || retention.KeepWeekly < 0 || retention.KeepMonthly < 0 {
+		snapshotScheduleResponse.Error = "retention rules can not be negative"
+		JSON(w, 400, snapshotScheduleResponse)
+		return
+	}
+
+	cronSpec := updateSnapshotScheduleRequest.Schedule
+	if cronSpec == "" {
+		if err := snapshot.DeleteSchedule(scheduleID); err != nil {
+			logger.Error(err)
+			snapshotScheduleResponse.Error = "failed to delete schedule"
+			JSON(w, 500, snapshotScheduleResponse)
+			return
+		}
+	} else {
+		if _, err := cron.ParseStandard(cronSpec); err != nil {
+			logger.Error(err)
+			snapshotScheduleResponse.Error = "failed to parse cron spec"
+			JSON(w, 400, snapshotScheduleResponse)
+			return
+		}
+
+		if err := snapshot.SetSchedule(scheduleID, cronSpec, retention); err != nil {
+			logger.Error(err)
+			snapshotScheduleResponse.Error = "failed to set schedule"
+			JSON(w, 500, snapshotScheduleResponse)
+			return
+		}
+
+		snapshotScheduleResponse.Schedule = cronSpec
+		snapshotScheduleResponse.Retention = retention
+	}
+
+	if err := snapshotscheduler.Configure(scheduleID); err != nil {
+		logger.Error(err)
+		snapshotScheduleResponse.Error = "failed to reconfigure snapshot schedule cron job"
+		JSON(w, 500, snapshotScheduleResponse)
+		return
+	}
+
+	snapshotScheduleResponse.Success = true
+
+	JSON(w, 200, snapshotScheduleResponse)
+}
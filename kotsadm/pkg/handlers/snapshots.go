@@ -21,6 +21,9 @@ type GlobalSnapshotSettingsResponse struct {
 	IsResticRunning bool     `json:"isResticRunning"`
 	IsKurl          bool     `json:"isKurl"`
 
+	MigrationStatus  string `json:"migrationStatus,omitempty"`
+	MigrationMessage string `json:"migrationMessage,omitempty"`
+
 	Store   *snapshottypes.Store `json:"store,omitempty"`
 	Success bool                 `json:"success"`
 	Error   string               `json:"error,omitempty"`
@@ -31,11 +34,12 @@ type UpdateGlobalSnapshotSettingsRequest struct {
 	Bucket   string `json:"bucket"`
 	Path     string `json:"path"`
 
-	AWS      *snapshottypes.StoreAWS    `json:"aws"`
-	Google   *snapshottypes.StoreGoogle `json:"gcp"`
-	Azure    *snapshottypes.StoreAzure  `json:"azure"`
-	Other    *snapshottypes.StoreOther  `json:"other"`
-	Internal bool                       `json:"internal"`
+	AWS        *snapshottypes.StoreAWS        `json:"aws"`
+	Google     *snapshottypes.StoreGoogle     `json:"gcp"`
+	Azure      *snapshottypes.StoreAzure      `json:"azure"`
+	Other      *snapshottypes.StoreOther      `json:"other"`
+	Internal   bool                           `json:"internal"`
+	FileSystem *snapshottypes.StoreFileSystem `json:"fileSystem"`
 }
 
 func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
@@ -93,7 +97,20 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 	globalSnapshotSettingsResponse.IsResticRunning = veleroStatus.ResticStatus == "Ready"
 	globalSnapshotSettingsResponse.IsKurl = kurl.IsKurl()
 
-	store, err := snapshot.GetGlobalStore(nil)
+	migrationStatus, _, err := snapshot.GetStoreMigrationStatus()
+	if err != nil {
+		logger.Error(err)
+		globalSnapshotSettingsResponse.Error = "failed to get store migration status"
+		JSON(w, 500, globalSnapshotSettingsResponse)
+		return
+	}
+	if migrationStatus == "running" {
+		globalSnapshotSettingsResponse.Error = "backups are being copied to the current store, wait for this to complete before changing it"
+		JSON(w, 400, globalSnapshotSettingsResponse)
+		return
+	}
+
+	previousStore, err := snapshot.GetGlobalStore(nil)
 	if err != nil {
 		logger.Error(err)
 		globalSnapshotSettingsResponse.Error = "failed to get store"
@@ -101,6 +118,9 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the request is merged into a copy, the current store is kept to migrate the backups from
+	store := previousStore.DeepCopy()
+
 	store.Provider = updateGlobalSnapshotSettingsRequest.Provider
 	store.Bucket = updateGlobalSnapshotSettingsRequest.Bucket
 	store.Path = updateGlobalSnapshotSettingsRequest.Path
@@ -113,6 +133,7 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		store.Google = nil
 		store.Other = nil
 		store.Internal = nil
+		store.FileSystem = nil
 
 		store.AWS.UseInstanceRole = updateGlobalSnapshotSettingsRequest.AWS.UseInstanceRole
 		if store.AWS.UseInstanceRole {
@@ -151,6 +172,7 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		store.Azure = nil
 		store.Other = nil
 		store.Internal = nil
+		store.FileSystem = nil
 
 		store.Google.UseInstanceRole = updateGlobalSnapshotSettingsRequest.Google.UseInstanceRole
 		if store.Google.UseInstanceRole {
@@ -192,6 +214,7 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		store.Google = nil
 		store.Other = nil
 		store.Internal = nil
+		store.FileSystem = nil
 
 		if updateGlobalSnapshotSettingsRequest.Azure.ResourceGroup != "" {
 			store.Azure.ResourceGroup = updateGlobalSnapshotSettingsRequest.Azure.ResourceGroup
@@ -229,6 +252,7 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		store.Google = nil
 		store.Azure = nil
 		store.Internal = nil
+		store.FileSystem = nil
 
 		if updateGlobalSnapshotSettingsRequest.Other.AccessKeyID != "" {
 			store.Other.AccessKeyID = updateGlobalSnapshotSettingsRequest.Other.AccessKeyID
@@ -268,6 +292,7 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		store.Google = nil
 		store.Azure = nil
 		store.Other = nil
+		store.FileSystem = nil
 
 		secret, err := kurl.GetS3Secret()
 		if err != nil {
@@ -292,6 +317,56 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		store.Internal.Endpoint = string(secret.Data["endpoint"])
 		store.Internal.ObjectStoreClusterIP = string(secret.Data["object-store-cluster-ip"])
 		store.Internal.Region = "us-east-1"
+	} else if updateGlobalSnapshotSettingsRequest.FileSystem != nil {
+		store.AWS = nil
+		store.Google = nil
+		store.Azure = nil
+		store.Other = nil
+		store.Internal = nil
+
+		store.FileSystem = &snapshottypes.StoreFileSystem{
+			NFS:      updateGlobalSnapshotSettingsRequest.FileSystem.NFS,
+			HostPath: updateGlobalSnapshotSettingsRequest.FileSystem.HostPath,
+		}
+		if err := snapshot.ValidateFileSystemConfig(store.FileSystem); err != nil {
+			globalSnapshotSettingsResponse.Error = err.Error()
+			JSON(w, 400, globalSnapshotSettingsResponse)
+			return
+		}
+
+		store.Provider = "aws"
+		store.Bucket = snapshot.FileSystemMinioBucketName
+		store.Path = ""
+
+		// the minio server has to be running before the store can be validated, so this can take
+		// minutes and is done in the background
+		err := snapshot.StartFileSystemStoreUpdate(previousStore, store)
+		if errors.Cause(err) == snapshot.ErrStoreMigrationRunning {
+			globalSnapshotSettingsResponse.Error = "backups are being copied to the current store, wait for this to complete before changing it"
+			JSON(w, 400, globalSnapshotSettingsResponse)
+			return
+		} else if err != nil {
+			logger.Error(err)
+			globalSnapshotSettingsResponse.Error = "failed to start updating the store"
+			JSON(w, 500, globalSnapshotSettingsResponse)
+			return
+		}
+
+		// the current store is returned until the update completes
+		currentStore := previousStore.DeepCopy()
+		if err := snapshot.Redact(currentStore); err != nil {
+			logger.Error(err)
+			globalSnapshotSettingsResponse.Error = "failed to redact"
+			JSON(w, 500, globalSnapshotSettingsResponse)
+			return
+		}
+
+		globalSnapshotSettingsResponse.Store = currentStore
+		globalSnapshotSettingsResponse.MigrationStatus = "running"
+		globalSnapshotSettingsResponse.Success = true
+
+		JSON(w, 200, globalSnapshotSettingsResponse)
+		return
 	}
 
 	if err := snapshot.ValidateStore(store); err != nil {
@@ -309,6 +384,25 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the file system minio is removed when the migration completes
+	migrating, err := snapshot.StartStoreMigration(previousStore, store)
+	if errors.Cause(err) == snapshot.ErrStoreMigrationRunning {
+		globalSnapshotSettingsResponse.Error = "store was updated but backups are already being copied to the previous store, update it again once this completes"
+		JSON(w, 400, globalSnapshotSettingsResponse)
+		return
+	} else if err != nil {
+		logger.Error(err)
+		globalSnapshotSettingsResponse.Error = "store was updated but failed to start copying existing backups to it"
+		JSON(w, 500, globalSnapshotSettingsResponse)
+		return
+	}
+
+	if !migrating && previousStore != nil && previousStore.FileSystem != nil && store.FileSystem == nil {
+		if err := snapshot.DeleteFileSystemMinio(); err != nil {
+			logger.Error(err)
+		}
+	}
+
 	// most plugins (all?) require that velero be restared after updating
 	if err := snapshot.RestartVelero(); err != nil {
 		logger.Error(err)
@@ -333,6 +427,9 @@ func UpdateGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	globalSnapshotSettingsResponse.Store = updatedStore
+	if migrating {
+		globalSnapshotSettingsResponse.MigrationStatus = "running"
+	}
 	globalSnapshotSettingsResponse.Success = true
 
 	JSON(w, 200, globalSnapshotSettingsResponse)
@@ -400,7 +497,17 @@ func GetGlobalSnapshotSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	migrationStatus, migrationMessage, err := snapshot.GetStoreMigrationStatus()
+	if err != nil {
+		logger.Error(err)
+		globalSnapshotSettingsResponse.Error = "failed to get store migration status"
+		JSON(w, 500, globalSnapshotSettingsResponse)
+		return
+	}
+
 	globalSnapshotSettingsResponse.Store = store
+	globalSnapshotSettingsResponse.MigrationStatus = migrationStatus
+	globalSnapshotSettingsResponse.MigrationMessage = migrationMessage
 	globalSnapshotSettingsResponse.Success = true
 
 	JSON(w, 200, globalSnapshotSettingsResponse)
@@ -10,6 +10,7 @@ import (
 	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
 	"github.com/replicatedhq/kots/kotsadm/pkg/session"
 	"github.com/replicatedhq/kots/kotsadm/pkg/updatechecker"
+	"github.com/replicatedhq/kots/pkg/upstream"
 	"github.com/replicatedhq/kots/pkg/util"
 )
 
@@ -55,7 +56,14 @@ func AppUpdateCheck(w http.ResponseWriter, r *http.Request) {
 		deploy, _ = strconv.ParseBool(d)
 	}
 
-	availableUpdates, err := updatechecker.CheckForUpdates(foundApp.ID, deploy)
+	versionConstraint := r.URL.Query().Get("versionConstraint")
+	if _, err := upstream.ParseVersionConstraint(versionConstraint); err != nil {
+		logger.Error(err)
+		w.WriteHeader(400)
+		return
+	}
+
+	availableUpdates, err := updatechecker.CheckForUpdates(foundApp.ID, deploy, versionConstraint)
 	if err != nil {
 		logger.Error(err)
 		w.WriteHeader(500)
@@ -10,11 +10,13 @@ import (
 	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
 	"github.com/replicatedhq/kots/kotsadm/pkg/session"
 	"github.com/replicatedhq/kots/kotsadm/pkg/updatechecker"
+	"github.com/replicatedhq/kots/pkg/upstream"
 	cron "github.com/robfig/cron/v3"
 )
 
 type UpdateCheckerSpecRequest struct {
 	UpdateCheckerSpec string `json:"updateCheckerSpec"`
+	VersionConstraint string `json:"versionConstraint"`
 }
 
 type UpdateCheckerSpecResponse struct {
@@ -82,7 +84,15 @@ func UpdateCheckerSpec(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := app.SetUpdateCheckerSpec(foundApp.ID, cronSpec); err != nil {
+	versionConstraint := updateCheckerSpecRequest.VersionConstraint
+	if _, err := upstream.ParseVersionConstraint(versionConstraint); err != nil {
+		logger.Error(err)
+		updateCheckerSpecResponse.Error = "failed to parse version constraint"
+		JSON(w, 400, updateCheckerSpecResponse)
+		return
+	}
+
+	if err := app.SetUpdateCheckerSpec(foundApp.ID, cronSpec, versionConstraint); err != nil {
 		logger.Error(err)
 		updateCheckerSpecResponse.Error = "failed to set update checker spec"
 		JSON(w, 500, updateCheckerSpecResponse)
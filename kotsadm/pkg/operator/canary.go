@@ -0,0 +1,129 @@
+package operator
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/operator/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/params"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
+const RolloutStrategyCanary = "canary"
+
+var (
+	// ErrUnknownRolloutStrategy is returned for a rollout strategy that the operator can't run.
+	// Deploying the same version again won't succeed.
+	ErrUnknownRolloutStrategy = errors.New(`unknown rollout strategy, the only supported strategy is "canary"`)
+)
+
+// setRolloutArgs sets the canary options of the app's rollout, the operator only runs a canary
+// when a version is upgraded
+func setRolloutArgs(args *types.DeployArgs, spec kotsv1beta1.ApplicationSpec) error {
+	if spec.Rollout == nil || spec.Rollout.Strategy == "" {
+		return nil
+	}
+	if spec.Rollout.Strategy != RolloutStrategyCanary {
+		return errors.Wrapf(ErrUnknownRolloutStrategy, "rollout strategy %q", spec.Rollout.Strategy)
+	}
+
+	args.RolloutStrategy = RolloutStrategyCanary
+	if spec.Rollout.Canary == nil {
+		return nil
+	}
+
+	args.CanaryReplicas = spec.Rollout.Canary.Replicas
+	args.CanaryBakePeriod = spec.Rollout.Canary.BakePeriod
+
+	checks, err := canaryChecks(spec.Rollout.Canary.Checks, spec.Graphs)
+	if err != nil {
+		return errors.Wrap(err, "failed to get canary checks")
+	}
+	args.CanaryChecks = checks
+
+	if len(checks) > 0 {
+		prometheusAddress, err := params.Get(params.PrometheusAddress)
+		if err != nil {
+			return errors.Wrap(err, "failed to get prometheus address")
+		}
+		args.PrometheusAddress = prometheusAddress
+	}
+
+	return nil
+}
+
+// canaryChecks resolves the checks into Prometheus queries. A check that names a graph has a
+// query for each of the graph's queries.
+func canaryChecks(checks []kotsv1beta1.CanaryCheck, graphs []kotsv1beta1.MetricGraph) ([]types.CanaryCheck, error) {
+	resolved := []types.CanaryCheck{}
+	for _, check := range checks {
+		min, err := parseBound(check.Min)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse min")
+		}
+		max, err := parseBound(check.Max)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse max")
+		}
+
+		if check.Graph == "" {
+			if check.Query == "" {
+				return nil, errors.New("check has neither a graph nor a query")
+			}
+			resolved = append(resolved, types.CanaryCheck{
+				Name:  check.Query,
+				Query: check.Query,
+				Min:   min,
+				Max:   max,
+			})
+			continue
+		}
+
+		graph := findGraph(graphs, check.Graph)
+		if graph == nil {
+			return nil, errors.Errorf("graph %q not found", check.Graph)
+		}
+
+		queries := []kotsv1beta1.MetricQuery{}
+		if graph.Query != "" {
+			queries = append(queries, kotsv1beta1.MetricQuery{Query: graph.Query, Legend: graph.Legend})
+		}
+		queries = append(queries, graph.Queries...)
+
+		for _, query := range queries {
+			name := graph.Title
+			if query.Legend != "" {
+				name = fmt.Sprintf("%s (%s)", graph.Title, query.Legend)
+			}
+			resolved = append(resolved, types.CanaryCheck{
+				Name:  name,
+				Query: query.Query,
+				Min:   min,
+				Max:   max,
+			})
+		}
+	}
+
+	return resolved, nil
+}
+
+func findGraph(graphs []kotsv1beta1.MetricGraph, title string) *kotsv1beta1.MetricGraph {
+	for i := range graphs {
+		if graphs[i].Title == title {
+			return &graphs[i]
+		}
+	}
+	return nil
+}
+
+func parseBound(str string) (*float64, error) {
+	if str == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
@@ -0,0 +1,129 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/operator/types"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_canaryChecks(t *testing.T) {
+	zero := float64(0)
+	max := float64(0.05)
+
+	graphs := []kotsv1beta1.MetricGraph{
+		{
+			Title: "Error rate",
+			Query: `sum(rate(http_requests_total{code="500"}[5m]))`,
+		},
+		{
+			Title: "Latency",
+			Queries: []kotsv1beta1.MetricQuery{
+				{Query: "p50_latency", Legend: "p50"},
+				{Query: "p99_latency", Legend: "p99"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		checks  []kotsv1beta1.CanaryCheck
+		expect  []types.CanaryCheck
+		wantErr bool
+	}{
+		{
+			name:   "no checks",
+			checks: nil,
+			expect: []types.CanaryCheck{},
+		},
+		{
+			name: "query and graphs",
+			checks: []kotsv1beta1.CanaryCheck{
+				{Query: "up", Min: "0"},
+				{Graph: "Error rate", Max: "0.05"},
+				{Graph: "Latency", Max: "0.05"},
+			},
+			expect: []types.CanaryCheck{
+				{Name: "up", Query: "up", Min: &zero},
+				{Name: "Error rate", Query: `sum(rate(http_requests_total{code="500"}[5m]))`, Max: &max},
+				{Name: "Latency (p50)", Query: "p50_latency", Max: &max},
+				{Name: "Latency (p99)", Query: "p99_latency", Max: &max},
+			},
+		},
+		{
+			name:    "missing graph",
+			checks:  []kotsv1beta1.CanaryCheck{{Graph: "Throughput"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid bound",
+			checks:  []kotsv1beta1.CanaryCheck{{Query: "up", Min: "one"}},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			checks, err := canaryChecks(test.checks, graphs)
+			if test.wantErr {
+				req.Error(err)
+				return
+			}
+			req.NoError(err)
+
+			assert.Equal(t, test.expect, checks)
+		})
+	}
+}
+
+func Test_setRolloutArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		rollout   *kotsv1beta1.Rollout
+		expect    types.DeployArgs
+		expectErr error
+	}{
+		{
+			name:   "no rollout",
+			expect: types.DeployArgs{},
+		},
+		{
+			name: "canary",
+			rollout: &kotsv1beta1.Rollout{
+				Strategy: "canary",
+				Canary:   &kotsv1beta1.CanaryRollout{Replicas: 2, BakePeriod: "10m"},
+			},
+			expect: types.DeployArgs{
+				RolloutStrategy:  "canary",
+				CanaryReplicas:   2,
+				CanaryBakePeriod: "10m",
+				CanaryChecks:     []types.CanaryCheck{},
+			},
+		},
+		{
+			name:      "unknown strategy",
+			rollout:   &kotsv1beta1.Rollout{Strategy: "blue-green"},
+			expectErr: ErrUnknownRolloutStrategy,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := require.New(t)
+
+			args := types.DeployArgs{}
+			err := setRolloutArgs(&args, kotsv1beta1.ApplicationSpec{Rollout: test.rollout})
+			if test.expectErr != nil {
+				req.Equal(test.expectErr, errors.Cause(err))
+				return
+			}
+			req.NoError(err)
+
+			assert.Equal(t, test.expect, args)
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package operator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/appstatus"
+	appstatustypes "github.com/replicatedhq/kots/kotsadm/pkg/appstatus/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/downstream"
+	downstreamtypes "github.com/replicatedhq/kots/kotsadm/pkg/downstream/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/kotsutil"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/operator/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot"
+	snapshottypes "github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/supportbundle"
+	"github.com/replicatedhq/kots/kotsadm/pkg/version"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	"go.uber.org/zap"
+)
+
+// Start sends the desired state of every app to the operators that are connected, once a second
+func Start() {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for _, s := range connectedSessions() {
+				if err := s.deployApps(); err != nil {
+					logger.Error(errors.Wrapf(err, "failed to deploy apps to cluster %s", s.clusterID))
+				}
+				if err := s.collectSupportBundles(); err != nil {
+					logger.Error(errors.Wrapf(err, "failed to collect support bundles from cluster %s", s.clusterID))
+				}
+				if err := s.handleRestores(); err != nil {
+					logger.Error(errors.Wrapf(err, "failed to handle restores on cluster %s", s.clusterID))
+				}
+			}
+		}
+	}()
+}
+
+// deployApps sends the manifests of every app whose current sequence changed since it was last sent
+func (s *session) deployApps() error {
+	downstreams, err := downstream.ListDownstreamsForCluster(s.clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list downstreams")
+	}
+
+	for _, d := range downstreams {
+		if d.CurrentSequence < 0 {
+			continue
+		}
+		if lastDeployedSequence, ok := s.lastDeployedSequences[d.AppID]; ok && lastDeployedSequence == d.CurrentSequence {
+			continue
+		}
+
+		a, err := app.Get(d.AppID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get app")
+		}
+		if a.RestoreInProgressName != "" {
+			continue
+		}
+
+		done, err := s.preUpgradeSnapshotDone(a, d)
+		if err != nil {
+			logger.Error(errors.Wrapf(err, "failed to check pre-upgrade snapshot of app %s sequence %d", a.Slug, d.CurrentSequence))
+			continue
+		}
+		if !done {
+			continue
+		}
+
+		kotsKinds, err := s.deployApp(a, d)
+		if err != nil {
+			logger.Error(errors.Wrapf(err, "failed to deploy app %s sequence %d", a.Slug, d.CurrentSequence))
+			if err := downstream.SetDownstreamVersionStatus(a.ID, d.ClusterID, d.CurrentSequence, "failed", err.Error()); err != nil {
+				logger.Error(err)
+			}
+			if errors.Cause(err) == ErrUnknownRolloutStrategy {
+				// the version can't be deployed, don't retry it until the sequence changes
+				s.lastDeployedSequences[a.ID] = d.CurrentSequence
+			}
+			continue
+		}
+		s.lastDeployedSequences[a.ID] = d.CurrentSequence
+
+		informers := kotsKinds.KotsApplication.Spec.StatusInformers
+		if len(informers) > 0 {
+			args := types.AppInformersArgs{
+				AppID:     a.ID,
+				Informers: informers,
+			}
+			if err := s.send(types.MessageTypeAppInformers, args); err != nil {
+				logger.Error(err)
+			}
+		} else {
+			// no informers, the app is ready as soon as it's deployed
+			appStatus := appstatustypes.AppStatus{
+				AppID:          a.ID,
+				ClusterID:      d.ClusterID,
+				UpdatedAt:      time.Now(),
+				ResourceStates: appstatustypes.DefaultReadyState,
+			}
+			if err := appstatus.Set(appStatus); err != nil {
+				logger.Error(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *session) deployApp(a *app.App, d *downstreamtypes.Downstream) (*kotsutil.KotsKinds, error) {
+	logger.Debug("deploying app",
+		zap.String("appID", a.ID),
+		zap.Int64("sequence", d.CurrentSequence))
+
+	manifests, kotsKinds, imagePullSecret, err := renderManifests(a.ID, d.CurrentSequence, d.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render manifests")
+	}
+
+	spec := kotsKinds.KotsApplication.Spec
+	args := types.DeployArgs{
+		AppID:                a.ID,
+		AppSlug:              a.Slug,
+		KubectlVersion:       spec.KubectlVersion,
+		Applier:              spec.Applier,
+		AdditionalNamespaces: spec.AdditionalNamespaces,
+		ImagePullSecret:      imagePullSecret,
+		Namespace:            ".",
+		Manifests:            base64.StdEncoding.EncodeToString(manifests),
+		ResultCallback:       "/api/v1/deploy/result",
+		Wait:                 false,
+		AnnotateSlug:         os.Getenv("ANNOTATE_SLUG") != "",
+		StatusInformers:      spec.StatusInformers,
+	}
+	if spec.AutoRollback != nil {
+		args.AutoRollback = spec.AutoRollback.Enabled
+		args.AutoRollbackReadyTimeout = spec.AutoRollback.ReadyTimeout
+	}
+	if spec.DriftDetection != nil {
+		args.DriftReapply = spec.DriftDetection.Reapply
+	}
+	if err := setRolloutArgs(&args, spec); err != nil {
+		return nil, errors.Wrap(err, "failed to set rollout args")
+	}
+
+	previousSequence, err := downstream.GetPreviouslyDeployedSequence(a.ID, d.ClusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get previously deployed sequence")
+	}
+	if previousSequence >= 0 {
+		previousManifests, _, _, err := renderManifests(a.ID, previousSequence, d.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render previous manifests")
+		}
+		args.PreviousManifests = base64.StdEncoding.EncodeToString(previousManifests)
+	}
+
+	if err := s.send(types.MessageTypeDeploy, args); err != nil {
+		return nil, errors.Wrap(err, "failed to send deploy")
+	}
+
+	return kotsKinds, nil
+}
+
+func (s *session) collectSupportBundles() error {
+	pendingSupportBundles, err := supportbundle.ListPendingForCluster(s.clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list pending support bundles")
+	}
+
+	for _, pendingSupportBundle := range pendingSupportBundles {
+		a, err := app.Get(pendingSupportBundle.AppID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get app")
+		}
+
+		args := types.SupportBundleArgs{
+			URI: fmt.Sprintf("%s/api/v1/troubleshoot/%s?incluster=true", s.apiEndpoint, a.Slug),
+		}
+		if err := s.send(types.MessageTypeSupportBundle, args); err != nil {
+			return errors.Wrap(err, "failed to send support bundle")
+		}
+
+		if err := supportbundle.ClearPending(pendingSupportBundle.ID); err != nil {
+			return errors.Wrap(err, "failed to clear pending support bundle")
+		}
+	}
+
+	return nil
+}
+
+// handleRestores moves the restores in progress along. The app is undeployed first, then the
+// velero restore is created, and the restored sequence is deployed once the restore completes.
+func (s *session) handleRestores() error {
+	downstreams, err := downstream.ListDownstreamsForCluster(s.clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list downstreams")
+	}
+
+	for _, d := range downstreams {
+		a, err := app.Get(d.AppID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get app")
+		}
+		if a.RestoreInProgressName == "" {
+			continue
+		}
+
+		switch a.RestoreUndeployStatus {
+		case app.UndeployStatusInProcess, app.UndeployStatusFailed:
+			// waiting on the operator, or nothing left to do
+		case app.UndeployStatusCompleted:
+			if err := handleUndeployCompleted(a); err != nil {
+				logger.Error(errors.Wrapf(err, "failed to handle restore %s", a.RestoreInProgressName))
+			}
+		default:
+			// restoring into other namespaces leaves the deployed app in place
+			if len(a.RestoreOptions.NamespaceMapping) > 0 {
+				if err := app.SetRestoreUndeployStatus(a.ID, app.UndeployStatusCompleted); err != nil {
+					logger.Error(errors.Wrap(err, "failed to set restore undeploy status"))
+				}
+				continue
+			}
+			if err := s.undeployApp(a, d); err != nil {
+				logger.Error(errors.Wrapf(err, "failed to undeploy app %s for restore", a.Slug))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *session) undeployApp(a *app.App, d *downstreamtypes.Downstream) error {
+	logger.Debug("undeploying app for restore",
+		zap.String("appID", a.ID),
+		zap.String("restore", a.RestoreInProgressName))
+
+	manifests, kotsKinds, _, err := renderManifests(a.ID, d.CurrentSequence, d.Name)
+	if err != nil {
+		return errors.Wrap(err, "failed to render manifests")
+	}
+
+	backup, err := snapshot.GetBackup(a.RestoreInProgressName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get backup")
+	}
+
+	// an empty set of manifests makes the operator delete everything in the previous manifests, or
+	// only what's in the restored namespaces when the restore is limited to some namespaces
+	args := types.DeployArgs{
+		AppID:             a.ID,
+		AppSlug:           a.Slug,
+		KubectlVersion:    kotsKinds.KotsApplication.Spec.KubectlVersion,
+		Applier:           kotsKinds.KotsApplication.Spec.Applier,
+		Namespace:         ".",
+		Manifests:         "",
+		PreviousManifests: base64.StdEncoding.EncodeToString(manifests),
+		ResultCallback:    "/api/v1/undeploy/result",
+		Wait:              true,
+		ClearNamespaces:   restoredNamespaces(backup.Spec.IncludedNamespaces, a.RestoreOptions),
+		ClearPVCs:         restoresVolumes(a.RestoreOptions, snapshot.AppNamespace()),
+		RestoreNamespaces: a.RestoreOptions.Namespaces,
+	}
+	if err := s.send(types.MessageTypeDeploy, args); err != nil {
+		return errors.Wrap(err, "failed to send undeploy")
+	}
+
+	// the restored sequence must be deployed after the restore, even if it's the same sequence
+	delete(s.lastDeployedSequences, a.ID)
+
+	if err := app.SetRestoreUndeployStatus(a.ID, app.UndeployStatusInProcess); err != nil {
+		return errors.Wrap(err, "failed to set restore undeploy status")
+	}
+
+	return nil
+}
+
+func handleUndeployCompleted(a *app.App) error {
+	restore, err := snapshot.GetRestore(a.RestoreInProgressName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get restore")
+	}
+
+	if restore == nil {
+		logger.Debug("creating velero restore",
+			zap.String("restore", a.RestoreInProgressName))
+		if err := snapshot.CreateRestore(a.RestoreInProgressName, a.RestoreOptions); err != nil {
+			return errors.Wrap(err, "failed to create restore")
+		}
+		return nil
+	}
+
+	switch restore.Status.Phase {
+	case velerov1.RestorePhaseCompleted:
+		if len(a.RestoreOptions.NamespaceMapping) > 0 {
+			logger.Debug("restore into other namespaces complete",
+				zap.String("appID", a.ID),
+				zap.String("restore", a.RestoreInProgressName))
+			if err := app.ResetRestore(a.ID); err != nil {
+				return errors.Wrap(err, "failed to reset restore")
+			}
+			return nil
+		}
+
+		backup, err := snapshot.GetBackup(restore.Spec.BackupName)
+		if err != nil {
+			return errors.Wrap(err, "failed to get backup")
+		}
+		sequence, err := strconv.ParseInt(backup.Annotations["kots.io/app-sequence"], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse sequence from backup")
+		}
+
+		logger.Debug("restore complete, deploying restored sequence",
+			zap.String("appID", a.ID),
+			zap.Int64("sequence", sequence))
+		if err := version.DeployVersionWithoutSnapshot(a.ID, sequence); err != nil {
+			return errors.Wrap(err, "failed to deploy restored version")
+		}
+		if err := app.ResetRestore(a.ID); err != nil {
+			return errors.Wrap(err, "failed to reset restore")
+		}
+
+	case velerov1.RestorePhaseFailed, velerov1.RestorePhasePartiallyFailed:
+		logger.Debug("restore failed",
+			zap.String("appID", a.ID),
+			zap.String("restore", a.RestoreInProgressName))
+		if err := app.ResetRestore(a.ID); err != nil {
+			return errors.Wrap(err, "failed to reset restore")
+		}
+	}
+
+	return nil
+}
+
+// restoredNamespaces returns the namespaces of the backup whose resources are all restored, and can
+// be cleared before the restore
+func restoredNamespaces(backupNamespaces []string, options snapshottypes.RestoreOptions) []string {
+	if !options.IsPartial() {
+		return backupNamespaces
+	}
+	if options.LabelSelector != "" || len(options.Resources) > 0 || options.VolumesOnly {
+		return nil
+	}
+	return options.Namespaces
+}
+
+// restoresVolumes returns true if the restore replaces the persistent volume claims of the app in the
+// target namespace. A label selector may leave some out, so they are kept.
+func restoresVolumes(options snapshottypes.RestoreOptions, targetNamespace string) bool {
+	if options.LabelSelector != "" {
+		return false
+	}
+	if len(options.Namespaces) > 0 && !containsString(options.Namespaces, targetNamespace) {
+		return false
+	}
+	if options.VolumesOnly || len(options.Resources) == 0 {
+		return true
+	}
+	for _, resource := range options.Resources {
+		if resource == "persistentvolumeclaims" || resource == "pvc" {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// renderManifests builds the downstream's kustomization in the archive of the app version, and
+// returns the manifests along with the kots kinds and the image pull secret of the version
+func renderManifests(appID string, sequence int64, downstreamName string) ([]byte, *kotsutil.KotsKinds, string, error) {
+	archivePath, err := version.GetAppVersionArchive(appID, sequence)
+	if err != nil {
+		return nil, nil, "", errors.Wrap(err, "failed to get app version archive")
+	}
+	defer os.RemoveAll(archivePath)
+
+	kotsKinds, err := kotsutil.LoadKotsKindsFromPath(archivePath)
+	if err != nil {
+		return nil, nil, "", errors.Wrap(err, "failed to load kots kinds")
+	}
+
+	// clusters that were registered after the version was created don't have a downstream overlay
+	kustomizeBuildTarget := filepath.Join(archivePath, "overlays", "downstreams", downstreamName)
+	if _, err := os.Stat(kustomizeBuildTarget); os.IsNotExist(err) {
+		kustomizeBuildTarget = filepath.Join(archivePath, "overlays", "midstream")
+	}
+	manifests, err := exec.Command(fmt.Sprintf("kustomize%s", kotsKinds.KustomizeVersion()), "build", kustomizeBuildTarget).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return nil, nil, "", errors.Wrapf(err, "failed to run kustomize: %s", string(ee.Stderr))
+		}
+		return nil, nil, "", errors.Wrap(err, "failed to run kustomize")
+	}
+
+	imagePullSecret := ""
+	secretFilename := filepath.Join(archivePath, "overlays", "midstream", "secret.yaml")
+	if _, err := os.Stat(secretFilename); err == nil {
+		b, err := ioutil.ReadFile(secretFilename)
+		if err != nil {
+			return nil, nil, "", errors.Wrap(err, "failed to read image pull secret")
+		}
+		imagePullSecret = string(b)
+	}
+
+	return manifests, kotsKinds, imagePullSecret, nil
+}
@@ -0,0 +1,89 @@
+package operator
+
+import (
+	"testing"
+
+	snapshottypes "github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	"github.com/stretchr/testify/assert"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_restoresVolumes(t *testing.T) {
+	tests := []struct {
+		name    string
+		options snapshottypes.RestoreOptions
+		expect  bool
+	}{
+		{
+			name:    "full restore",
+			options: snapshottypes.RestoreOptions{},
+			expect:  true,
+		},
+		{
+			name:    "target namespace",
+			options: snapshottypes.RestoreOptions{Namespaces: []string{"app", "other"}},
+			expect:  true,
+		},
+		{
+			name:    "other namespace",
+			options: snapshottypes.RestoreOptions{Namespaces: []string{"other"}},
+			expect:  false,
+		},
+		{
+			name:    "label selector",
+			options: snapshottypes.RestoreOptions{LabelSelector: "app=postgres"},
+			expect:  false,
+		},
+		{
+			name:    "volumes only",
+			options: snapshottypes.RestoreOptions{VolumesOnly: true},
+			expect:  true,
+		},
+		{
+			name:    "config maps",
+			options: snapshottypes.RestoreOptions{Resources: []string{"configmaps"}},
+			expect:  false,
+		},
+		{
+			name:    "persistent volume claims",
+			options: snapshottypes.RestoreOptions{Resources: []string{"configmaps", "persistentvolumeclaims"}},
+			expect:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, restoresVolumes(test.options, "app"))
+		})
+	}
+}
+
+func Test_restoredNamespaces(t *testing.T) {
+	backupNamespaces := []string{"app", "other"}
+
+	tests := []struct {
+		name    string
+		options snapshottypes.RestoreOptions
+		expect  []string
+	}{
+		{
+			name:    "full restore",
+			options: snapshottypes.RestoreOptions{},
+			expect:  []string{"app", "other"},
+		},
+		{
+			name:    "other namespace",
+			options: snapshottypes.RestoreOptions{Namespaces: []string{"other"}},
+			expect:  []string{"other"},
+		},
+		{
+			name:    "label selector",
+			options: snapshottypes.RestoreOptions{Namespaces: []string{"other"}, LabelSelector: "app=postgres"},
+			expect:  nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, restoredNamespaces(backupNamespaces, test.options))
+		})
+	}
+}
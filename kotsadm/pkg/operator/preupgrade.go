@@ -0,0 +1,106 @@
+package operator
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/cluster"
+	"github.com/replicatedhq/kots/kotsadm/pkg/downstream"
+	downstreamtypes "github.com/replicatedhq/kots/kotsadm/pkg/downstream/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/kotsutil"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot"
+	"github.com/replicatedhq/kots/kotsadm/pkg/version"
+	"go.uber.org/zap"
+)
+
+// preUpgradeSnapshotDone returns true when the current sequence of the downstream can be sent to the
+// operator. If a pre-upgrade snapshot is pending, it's started, and the version waits for the backup
+// to finish. A failed backup blocks the version unless the policy only warns.
+func (s *session) preUpgradeSnapshotDone(a *app.App, d *downstreamtypes.Downstream) (bool, error) {
+	// snapshots only back up the cluster that kotsadm runs in
+	isLocal, err := cluster.IsLocal(s.clusterID)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check for local cluster")
+	}
+	if !isLocal {
+		return true, nil
+	}
+
+	preUpgradeSnapshot, err := snapshot.GetPreUpgradeSnapshot(a.ID, d.CurrentSequence)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get pre-upgrade snapshot")
+	}
+	if preUpgradeSnapshot == nil {
+		return true, nil
+	}
+
+	if preUpgradeSnapshot.Status == snapshot.PreUpgradeSnapshotPending {
+		policy, timeout, err := preUpgradeSnapshotPolicy(a, d.CurrentSequence)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get pre-upgrade snapshot policy")
+		}
+		if policy == "" {
+			if err := snapshot.DeletePreUpgradeSnapshot(a.ID, d.CurrentSequence); err != nil {
+				return false, errors.Wrap(err, "failed to delete pre-upgrade snapshot")
+			}
+			return true, nil
+		}
+
+		preUpgradeSnapshot, err = snapshot.StartPreUpgradeSnapshot(a, d.CurrentSequence, preUpgradeSnapshot.PreviousSequence, policy, timeout)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to start pre-upgrade snapshot")
+		}
+	}
+
+	wasFinished := preUpgradeSnapshot.Status != snapshot.PreUpgradeSnapshotRunning
+	if err := snapshot.UpdatePreUpgradeSnapshot(preUpgradeSnapshot); err != nil {
+		return false, errors.Wrap(err, "failed to update pre-upgrade snapshot")
+	}
+
+	switch preUpgradeSnapshot.Status {
+	case snapshot.PreUpgradeSnapshotCompleted:
+		return true, nil
+
+	case snapshot.PreUpgradeSnapshotFailed:
+		if preUpgradeSnapshot.OnFailure == snapshot.PreUpgradeSnapshotWarn {
+			if !wasFinished {
+				logger.Info("deploying app after pre-upgrade snapshot failed",
+					zap.String("appID", a.ID),
+					zap.Int64("sequence", d.CurrentSequence),
+					zap.String("error", preUpgradeSnapshot.Error))
+			}
+			return true, nil
+		}
+
+		if !wasFinished {
+			statusInfo := fmt.Sprintf("pre-upgrade snapshot failed: %s", preUpgradeSnapshot.Error)
+			if err := downstream.SetDownstreamVersionStatus(a.ID, d.ClusterID, d.CurrentSequence, "failed", statusInfo); err != nil {
+				return false, errors.Wrap(err, "failed to set downstream version status")
+			}
+		}
+		return false, nil
+	}
+
+	return false, nil
+}
+
+// preUpgradeSnapshotPolicy returns the pre-upgrade snapshot policy of the version that's about to be
+// deployed
+func preUpgradeSnapshotPolicy(a *app.App, sequence int64) (string, time.Duration, error) {
+	archivePath, err := version.GetAppVersionArchive(a.ID, sequence)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "failed to get app version archive")
+	}
+	defer os.RemoveAll(archivePath)
+
+	kotsKinds, err := kotsutil.LoadKotsKindsFromPath(archivePath)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "failed to load kots kinds")
+	}
+
+	return snapshot.PreUpgradeSnapshotPolicy(a.PreUpgradeSnapshotPolicy, kotsKinds.KotsApplication.Spec.PreUpgradeSnapshot)
+}
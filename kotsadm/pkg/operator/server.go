@@ -0,0 +1,374 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/cluster"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/operator/types"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+)
+
+var (
+	HelloTimeout = time.Second * 10
+	WriteTimeout = time.Second * 10
+
+	// PingInterval is how often the server pings the operator, and PongTimeout is how long the
+	// connection can go without a pong before it's considered lost
+	PingInterval = time.Second * 30
+	PongTimeout  = time.Second * 90
+)
+
+var upgrader = websocket.Upgrader{
+	// the operator is not a browser, and authenticates with the cluster token
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+var (
+	sessionsMtx sync.Mutex
+	sessions    = map[string]*session{}
+)
+
+// session is the state of an operator that outlives a single connection. Messages are kept in
+// pending until the operator acknowledges them, and are sent again when the operator resumes
+// the session on a new connection.
+type session struct {
+	id          string
+	clusterID   string
+	apiEndpoint string
+
+	mtx     sync.Mutex
+	conn    *websocket.Conn
+	lastID  uint64
+	pending []types.Envelope
+
+	// lastDeployedSequences is only used by the desired state loop
+	lastDeployedSequences map[string]int64
+}
+
+// Connect upgrades the request to a websocket, completes the handshake and then reads acks
+// from the operator until the connection is lost
+func Connect(w http.ResponseWriter, r *http.Request) {
+	_, token, _ := r.BasicAuth()
+	clusterID, err := cluster.GetIDFromDeployToken(token)
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(500)
+		return
+	}
+	if clusterID == "" {
+		w.WriteHeader(401)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to upgrade operator connection"))
+		return
+	}
+	defer conn.Close()
+
+	hello := types.Hello{}
+	conn.SetReadDeadline(time.Now().Add(HelloTimeout))
+	if err := readMessage(conn, types.MessageTypeHello, &hello); err != nil {
+		logger.Error(errors.Wrap(err, "failed to read hello"))
+		return
+	}
+
+	version, err := negotiateVersion(hello)
+	if err != nil {
+		logger.Error(err)
+		message := websocket.FormatCloseMessage(types.CloseUnsupportedVersion, err.Error())
+		conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(WriteTimeout))
+		return
+	}
+
+	s := getOrCreateSession(clusterID, hello.SessionID, requestEndpoint(r))
+	welcome := types.Welcome{
+		Version:   version,
+		SessionID: s.id,
+		ClusterID: clusterID,
+	}
+	if err := s.attach(conn, welcome, hello.LastID); err != nil {
+		logger.Error(errors.Wrap(err, "failed to attach operator connection"))
+		return
+	}
+	defer s.detach(conn)
+
+	logger.Debug("operator connected",
+		zap.String("clusterID", clusterID),
+		zap.String("sessionID", s.id),
+		zap.Bool("resumed", hello.SessionID == s.id))
+
+	heartbeat(clusterID)
+	conn.SetReadDeadline(time.Now().Add(PongTimeout))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(PongTimeout))
+		heartbeat(clusterID)
+		return nil
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go ping(conn, done)
+
+	for {
+		ack := types.Ack{}
+		if err := readMessage(conn, types.MessageTypeAck, &ack); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.Error(errors.Wrap(err, "failed to read from operator"))
+			}
+			return
+		}
+		s.ack(ack.ID)
+	}
+}
+
+func ping(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(WriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// requestEndpoint is the endpoint that the operator connected to, which is the kotsadm service for
+// the local operator and an ingress for operators in remote clusters
+func requestEndpoint(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+// heartbeat records that the cluster's operator is connected, it's called on connect and on every pong
+func heartbeat(clusterID string) {
+	if err := cluster.SetHeartbeat(clusterID, time.Now()); err != nil {
+		logger.Error(errors.Wrap(err, "failed to set cluster heartbeat"))
+	}
+}
+
+func negotiateVersion(hello types.Hello) (int, error) {
+	version := types.ProtocolVersion
+	if hello.MaxVersion < version {
+		version = hello.MaxVersion
+	}
+	if version < types.ProtocolMinVersion || version < hello.MinVersion {
+		return 0, errors.Errorf("no common protocol version, server supports %d-%d and operator supports %d-%d",
+			types.ProtocolMinVersion, types.ProtocolVersion, hello.MinVersion, hello.MaxVersion)
+	}
+	return version, nil
+}
+
+// getOrCreateSession resumes the cluster's session when the operator sent its id, and replaces
+// it with a new session otherwise
+func getOrCreateSession(clusterID string, sessionID string, apiEndpoint string) *session {
+	sessionsMtx.Lock()
+	defer sessionsMtx.Unlock()
+
+	existing, ok := sessions[clusterID]
+	if ok && sessionID != "" && existing.id == sessionID {
+		existing.mtx.Lock()
+		existing.apiEndpoint = apiEndpoint
+		existing.mtx.Unlock()
+		return existing
+	}
+
+	if ok {
+		existing.close()
+	}
+
+	s := &session{
+		id:                    ksuid.New().String(),
+		clusterID:             clusterID,
+		apiEndpoint:           apiEndpoint,
+		lastDeployedSequences: map[string]int64{},
+	}
+	sessions[clusterID] = s
+	return s
+}
+
+// connectedSessions returns the sessions that currently have an operator connected
+func connectedSessions() []*session {
+	sessionsMtx.Lock()
+	defer sessionsMtx.Unlock()
+
+	connected := []*session{}
+	for _, s := range sessions {
+		if s.isConnected() {
+			connected = append(connected, s)
+		}
+	}
+	return connected
+}
+
+// IsConnected returns true if the cluster's operator is currently connected
+func IsConnected(clusterID string) bool {
+	sessionsMtx.Lock()
+	s, ok := sessions[clusterID]
+	sessionsMtx.Unlock()
+
+	return ok && s.isConnected()
+}
+
+// Disconnect closes the cluster's session, such as when its token is rotated or it is deleted.
+// Messages that the operator has not acknowledged are dropped.
+func Disconnect(clusterID string) {
+	sessionsMtx.Lock()
+	defer sessionsMtx.Unlock()
+
+	if s, ok := sessions[clusterID]; ok {
+		s.close()
+		delete(sessions, clusterID)
+	}
+}
+
+// attach makes conn the session's connection, closing the previous one, and sends the welcome
+// followed by every message that the operator has not acknowledged
+func (s *session) attach(conn *websocket.Conn, welcome types.Welcome, lastID uint64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn = conn
+	s.removePending(lastID)
+
+	envelope, err := newEnvelope(types.MessageTypeWelcome, 0, welcome)
+	if err != nil {
+		return err
+	}
+	if err := s.write(envelope); err != nil {
+		return err
+	}
+
+	for _, envelope := range s.pending {
+		if err := s.write(envelope); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *session) detach(conn *websocket.Conn) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.conn == conn {
+		s.conn = nil
+	}
+}
+
+func (s *session) close() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+func (s *session) isConnected() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.conn != nil
+}
+
+func (s *session) ack(id uint64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.removePending(id)
+}
+
+// send queues a message for the operator and writes it if the operator is connected. A failed
+// write is not an error, the message is sent again when the operator reconnects.
+func (s *session) send(messageType string, payload interface{}) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	envelope, err := newEnvelope(messageType, s.lastID+1, payload)
+	if err != nil {
+		return err
+	}
+	s.lastID = envelope.ID
+	s.pending = append(s.pending, envelope)
+
+	if s.conn == nil {
+		return nil
+	}
+	if err := s.write(envelope); err != nil {
+		logger.Error(errors.Wrapf(err, "failed to send %s message to operator", messageType))
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return nil
+}
+
+// removePending must be called with the lock held
+func (s *session) removePending(id uint64) {
+	pending := []types.Envelope{}
+	for _, envelope := range s.pending {
+		if envelope.ID > id {
+			pending = append(pending, envelope)
+		}
+	}
+	s.pending = pending
+}
+
+// write must be called with the lock held
+func (s *session) write(envelope types.Envelope) error {
+	s.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	if err := s.conn.WriteJSON(envelope); err != nil {
+		return errors.Wrapf(err, "failed to write %s message", envelope.Type)
+	}
+	return nil
+}
+
+func newEnvelope(messageType string, id uint64, payload interface{}) (types.Envelope, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return types.Envelope{}, errors.Wrapf(err, "failed to marshal %s payload", messageType)
+	}
+
+	return types.Envelope{
+		Type:    messageType,
+		ID:      id,
+		Payload: b,
+	}, nil
+}
+
+func readMessage(conn *websocket.Conn, messageType string, v interface{}) error {
+	envelope := types.Envelope{}
+	if err := conn.ReadJSON(&envelope); err != nil {
+		return err
+	}
+	if envelope.Type != messageType {
+		return errors.Errorf("expected %s message, got %s", messageType, envelope.Type)
+	}
+	if err := json.Unmarshal(envelope.Payload, v); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal %s payload", messageType)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+// These are the wire types of the kotsadm <-> operator protocol. The operator is a separate module,
+// and its side of the protocol is in kotsadm/operator/pkg/protocol. The two must be kept in sync.
+const (
+	ProtocolVersion    = 1
+	ProtocolMinVersion = 1
+
+	CloseUnsupportedVersion = 4001
+
+	MessageTypeHello         = "hello"
+	MessageTypeAck           = "ack"
+	MessageTypeWelcome       = "welcome"
+	MessageTypeDeploy        = "deploy"
+	MessageTypeAppInformers  = "appInformers"
+	MessageTypePreflight     = "preflight"
+	MessageTypeSupportBundle = "supportbundle"
+)
+
+type Envelope struct {
+	Type    string          `json:"type"`
+	ID      uint64          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type Hello struct {
+	MinVersion int    `json:"min_version"`
+	MaxVersion int    `json:"max_version"`
+	SessionID  string `json:"session_id,omitempty"`
+	LastID     uint64 `json:"last_id,omitempty"`
+}
+
+type Welcome struct {
+	Version   int    `json:"version"`
+	SessionID string `json:"session_id"`
+	ClusterID string `json:"cluster_id"`
+}
+
+type Ack struct {
+	ID uint64 `json:"id"`
+}
+
+// DeployArgs are the manifests of an app for the operator to apply
+type DeployArgs struct {
+	AppID                string   `json:"app_id"`
+	AppSlug              string   `json:"app_slug"`
+	KubectlVersion       string   `json:"kubectl_version"`
+	Applier              string   `json:"applier"`
+	AdditionalNamespaces []string `json:"additional_namespaces"`
+	ImagePullSecret      string   `json:"image_pull_secret"`
+	Namespace            string   `json:"namespace"`
+	Manifests            string   `json:"manifests"`
+	PreviousManifests    string   `json:"previous_manifests"`
+	ResultCallback       string   `json:"result_callback"`
+	Wait                 bool     `json:"wait"`
+	ClearNamespaces      []string `json:"clear_namespaces,omitempty"`
+	ClearPVCs            bool     `json:"clear_pvcs,omitempty"`
+	RestoreNamespaces    []string `json:"restore_namespaces,omitempty"`
+	AnnotateSlug         bool     `json:"annotate_slug"`
+
+	AutoRollback             bool     `json:"auto_rollback"`
+	AutoRollbackReadyTimeout string   `json:"auto_rollback_ready_timeout"`
+	StatusInformers          []string `json:"status_informers"`
+
+	DriftReapply bool `json:"drift_reapply"`
+
+	RolloutStrategy   string        `json:"rollout_strategy,omitempty"`
+	CanaryReplicas    int32         `json:"canary_replicas,omitempty"`
+	CanaryBakePeriod  string        `json:"canary_bake_period,omitempty"`
+	CanaryChecks      []CanaryCheck `json:"canary_checks,omitempty"`
+	PrometheusAddress string        `json:"prometheus_address,omitempty"`
+}
+
+// CanaryCheck is a Prometheus query whose values must stay within Min and Max during a canary
+type CanaryCheck struct {
+	Name  string   `json:"name"`
+	Query string   `json:"query"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+type AppInformersArgs struct {
+	AppID     string   `json:"app_id"`
+	Informers []string `json:"informers"`
+}
+
+type SupportBundleArgs struct {
+	URI string `json:"uri"`
+}
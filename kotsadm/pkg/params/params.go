@@ -0,0 +1,31 @@
+package params
+
+import (
+	"database/sql"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+)
+
+const (
+	PrometheusAddress = "PROMETHEUS_ADDRESS"
+)
+
+// Get returns the value of the param that was set in the admin console, or the environment
+// variable of the same name when it was not set
+func Get(name string) (string, error) {
+	db := persistence.MustGetPGSession()
+	query := `select value from kotsadm_params where key = $1`
+	row := db.QueryRow(query, name)
+
+	var value string
+	if err := row.Scan(&value); err != nil {
+		if err == sql.ErrNoRows {
+			return os.Getenv(name), nil
+		}
+		return "", errors.Wrapf(err, "failed to scan param %s", name)
+	}
+
+	return value, nil
+}
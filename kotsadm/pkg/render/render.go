@@ -53,8 +53,9 @@ func RenderFile(kotsKinds *kotsutil.KotsKinds, registrySettings *registrytypes.R
 	if kotsKinds.ConfigValues != nil {
 		for k, v := range kotsKinds.ConfigValues.Spec.Values {
 			templateContextValues[k] = template.ItemValue{
-				Value:   v.Value,
-				Default: v.Default,
+				Value:      v.Value,
+				Default:    v.Default,
+				MultiValue: v.MultiValue,
 			}
 		}
 	}
@@ -27,63 +27,134 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
+const (
+	TriggerManual     = "manual"
+	TriggerSchedule   = "schedule"
+	TriggerPreUpgrade = "pre-upgrade"
+
+	// ScheduleAnnotation is set on backups that were taken by a schedule to the id of the schedule
+	ScheduleAnnotation = "kots.io/snapshot-schedule"
+)
+
+// RetainedBackupTTL is the ttl of scheduled backups with retention rules. kotsadm deletes them when
+// the rules no longer keep them, so velero should not expire them first.
+var RetainedBackupTTL = time.Hour * 24 * 365 * 10
+
+type backupOptions struct {
+	trigger     string
+	schedule    *types.SnapshotSchedule
+	annotations map[string]string
+}
+
+func (o backupOptions) annotate(backup *velerov1.Backup) {
+	backup.Annotations["kots.io/snapshot-trigger"] = o.trigger
+	for k, v := range o.annotations {
+		backup.Annotations[k] = v
+	}
+	if o.schedule == nil {
+		return
+	}
+
+	backup.Annotations[ScheduleAnnotation] = o.schedule.ID
+	if !o.schedule.Retention.IsEmpty() {
+		backup.Spec.TTL = metav1.Duration{Duration: RetainedBackupTTL}
+	}
+}
+
 func CreateBackup(a *app.App) error {
-	if err := createApplicationBackup(a); err != nil {
+	options := backupOptions{
+		trigger: TriggerManual,
+	}
+
+	if _, err := createApplicationBackup(a, options); err != nil {
 		return errors.Wrap(err, "failed to create application backup")
 	}
 
-	if err := createAdminConsoleBackup(); err != nil {
+	if err := createAdminConsoleBackup(options); err != nil {
 		return errors.Wrap(err, "failed to create admin console backup")
 	}
 
 	return nil
 }
 
-func createApplicationBackup(a *app.App) error {
+// CreateScheduledBackup backs up the app of the schedule, or every installed app for the instance
+// schedule, and the admin console
+func CreateScheduledBackup(schedule *types.SnapshotSchedule) error {
+	options := backupOptions{
+		trigger:  TriggerSchedule,
+		schedule: schedule,
+	}
+
+	apps := []*app.App{}
+	if schedule.ID == InstanceScheduleID {
+		installed, err := app.ListInstalled()
+		if err != nil {
+			return errors.Wrap(err, "failed to list installed apps")
+		}
+		apps = installed
+	} else {
+		a, err := app.Get(schedule.ID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get app")
+		}
+		apps = append(apps, a)
+	}
+
+	for _, a := range apps {
+		if _, err := createApplicationBackup(a, options); err != nil {
+			return errors.Wrapf(err, "failed to create application backup for app %s", a.Slug)
+		}
+	}
+
+	if err := createAdminConsoleBackup(options); err != nil {
+		return errors.Wrap(err, "failed to create admin console backup")
+	}
+
+	return nil
+}
+
+// createApplicationBackup backs up the app with the backup spec of its current sequence, and returns
+// the name of the velero backup
+func createApplicationBackup(a *app.App, options backupOptions) (string, error) {
 	logger.Debug("creating backup",
 		zap.String("appID", a.ID),
 		zap.Int64("sequence", a.CurrentSequence))
 
 	archiveDir, err := version.GetAppVersionArchive(a.ID, a.CurrentSequence)
 	if err != nil {
-		return errors.Wrap(err, "failed to get app version archive")
+		return "", errors.Wrap(err, "failed to get app version archive")
 	}
 
 	kotsadmVeleroBackendStorageLocation, err := findBackupStoreLocation()
 	if err != nil {
-		return errors.Wrap(err, "failed to find backupstoragelocations")
+		return "", errors.Wrap(err, "failed to find backupstoragelocations")
 	}
 
 	kotsKinds, err := kotsutil.LoadKotsKindsFromPath(archiveDir)
 	if err != nil {
-		return errors.Wrap(err, "failed to load kots kinds from path")
+		return "", errors.Wrap(err, "failed to load kots kinds from path")
 	}
 
 	registrySettings, err := getRegistrySettingsForApp(a.ID)
 	if err != nil {
-		return errors.Wrap(err, "failed to get registry settings for app")
+		return "", errors.Wrap(err, "failed to get registry settings for app")
 	}
 
 	backupSpec, err := kotsKinds.Marshal("velero.io", "v1", "Backup")
 	if err != nil {
-		return errors.Wrap(err, "failed to get backup spec from kotskinds")
+		return "", errors.Wrap(err, "failed to get backup spec from kotskinds")
 	}
 
 	renderedBackup, err := render.RenderFile(kotsKinds, registrySettings, []byte(backupSpec))
 	if err != nil {
-		return errors.Wrap(err, "failed to render backup")
+		return "", errors.Wrap(err, "failed to render backup")
 	}
 	veleroBackup, err := kotsutil.LoadBackupFromContents(renderedBackup)
 	if err != nil {
-		return errors.Wrap(err, "failed to load backup from contents")
-	}
-
-	appNamespace := os.Getenv("POD_NAMESPACE")
-	if os.Getenv("KOTSADM_TARGET_NAMESPACE") != "" {
-		appNamespace = os.Getenv("KOTSADM_TARGET_NAMESPACE")
+		return "", errors.Wrap(err, "failed to load backup from contents")
 	}
 
-	includedNamespaces := []string{appNamespace}
+	includedNamespaces := []string{AppNamespace()}
 	includedNamespaces = append(includedNamespaces, kotsKinds.KotsApplication.Spec.AdditionalNamespaces...)
 
 	veleroBackup.Name = ""
@@ -91,7 +162,6 @@ func createApplicationBackup(a *app.App) error {
 
 	veleroBackup.Namespace = kotsadmVeleroBackendStorageLocation.Namespace
 	veleroBackup.Annotations = map[string]string{
-		"kots.io/snapshot-trigger":   "manual",
 		"kots.io/app-id":             a.ID,
 		"kots.io/app-sequence":       strconv.FormatInt(a.CurrentSequence, 10),
 		"kots.io/snapshot-requested": time.Now().UTC().Format(time.RFC3339),
@@ -108,25 +178,36 @@ func createApplicationBackup(a *app.App) error {
 		"kots.io/app-slug": a.Slug,
 	}
 
+	options.annotate(veleroBackup)
+
 	cfg, err := config.GetConfig()
 	if err != nil {
-		return errors.Wrap(err, "failed to get cluster config")
+		return "", errors.Wrap(err, "failed to get cluster config")
 	}
 
 	veleroClient, err := veleroclientv1.NewForConfig(cfg)
 	if err != nil {
-		return errors.Wrap(err, "failed to create clientset")
+		return "", errors.Wrap(err, "failed to create clientset")
 	}
 
-	_, err = veleroClient.Backups(kotsadmVeleroBackendStorageLocation.Namespace).Create(context.TODO(), veleroBackup, metav1.CreateOptions{})
+	created, err := veleroClient.Backups(kotsadmVeleroBackendStorageLocation.Namespace).Create(context.TODO(), veleroBackup, metav1.CreateOptions{})
 	if err != nil {
-		return errors.Wrap(err, "failed to create velero backup")
+		return "", errors.Wrap(err, "failed to create velero backup")
 	}
 
-	return nil
+	return created.Name, nil
+}
+
+// AppNamespace returns the namespace that the operator deploys the apps to, which is the first
+// namespace of every app backup
+func AppNamespace() string {
+	if os.Getenv("KOTSADM_TARGET_NAMESPACE") != "" {
+		return os.Getenv("KOTSADM_TARGET_NAMESPACE")
+	}
+	return os.Getenv("POD_NAMESPACE")
 }
 
-func createAdminConsoleBackup() error {
+func createAdminConsoleBackup(options backupOptions) error {
 	logger.Debug("creating admin console backup")
 
 	kotsadmVeleroBackendStorageLocation, err := findBackupStoreLocation()
@@ -140,7 +221,6 @@ func createAdminConsoleBackup() error {
 			GenerateName: "kotsadm-",
 			Namespace:    kotsadmVeleroBackendStorageLocation.Namespace,
 			Annotations: map[string]string{
-				"kots.io/snapshot-trigger":   "manual",
 				"kots.io/snapshot-requested": time.Now().UTC().Format(time.RFC3339),
 				kotstypes.VeleroKey:          kotstypes.VeleroLabelConsoleValue,
 			},
@@ -157,6 +237,7 @@ func createAdminConsoleBackup() error {
 			},
 		},
 	}
+	options.annotate(veleroBackup)
 
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -0,0 +1,599 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/task"
+	"github.com/replicatedhq/kots/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	FileSystemMinioName       = "kotsadm-fs-minio"
+	FileSystemMinioBucketName = "velero"
+	FileSystemMinioRegion     = "us-east-1"
+
+	// fileSystemMinioNextName serves a new share while the backups are copied to it
+	fileSystemMinioNextName     = "kotsadm-fs-minio-next"
+	fileSystemMinioPort         = 9000
+	fileSystemMinioVolumeName   = "data"
+	defaultFileSystemMinioImage = "minio/minio:RELEASE.2019-10-12T01-39-57Z"
+)
+
+// FileSystemMinioEndpoint is the in-cluster url that velero uses to reach the minio server
+// in front of the file system
+func FileSystemMinioEndpoint(namespace string) string {
+	return fileSystemMinioEndpoint(FileSystemMinioName, namespace)
+}
+
+func fileSystemMinioEndpoint(name string, namespace string) string {
+	return fmt.Sprintf("http://%s.%s:%d", name, namespace, fileSystemMinioPort)
+}
+
+// ValidateFileSystemConfig checks that exactly one of an NFS share or a host path is set
+func ValidateFileSystemConfig(fileSystem *types.StoreFileSystem) error {
+	if fileSystem == nil {
+		return errors.New("file system configuration is required")
+	}
+
+	if fileSystem.NFS != nil && fileSystem.HostPath != "" {
+		return errors.New("only one of an NFS share or a host path can be used")
+	}
+
+	if fileSystem.NFS != nil {
+		if fileSystem.NFS.Server == "" {
+			return errors.New("NFS server is required")
+		}
+		if !filepath.IsAbs(fileSystem.NFS.Path) {
+			return errors.Errorf("NFS path %q must be absolute", fileSystem.NFS.Path)
+		}
+		return nil
+	}
+
+	if fileSystem.HostPath != "" {
+		if !filepath.IsAbs(fileSystem.HostPath) {
+			return errors.Errorf("host path %q must be absolute", fileSystem.HostPath)
+		}
+		return nil
+	}
+
+	return errors.New("an NFS share or a host path is required")
+}
+
+// DeployFileSystemMinio creates or updates the minio server that serves the file system
+// as an S3-compatible store, and waits for it to be ready. The credentials and endpoint
+// of the server are set on fileSystem.
+func DeployFileSystemMinio(fileSystem *types.StoreFileSystem) error {
+	return deployFileSystemMinio(FileSystemMinioName, fileSystem)
+}
+
+func deployFileSystemMinio(name string, fileSystem *types.StoreFileSystem) error {
+	if err := ValidateFileSystemConfig(fileSystem); err != nil {
+		return errors.Wrap(err, "invalid file system configuration")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster config")
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create clientset")
+	}
+
+	// the server runs next to kotsadm so that it can use the same minio image and pull secrets
+	namespace := os.Getenv("POD_NAMESPACE")
+
+	secret, err := ensureFileSystemMinioSecret(clientset, namespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure secret")
+	}
+
+	podSpec, err := fileSystemMinioPodSpec(clientset, namespace, fileSystem)
+	if err != nil {
+		return errors.Wrap(err, "failed to get pod spec")
+	}
+
+	deployment := fileSystemMinioDeployment(name, namespace, podSpec)
+	existingDeployment, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get existing deployment")
+	}
+	if kuberneteserrors.IsNotFound(err) {
+		_, err = clientset.AppsV1().Deployments(namespace).Create(context.TODO(), deployment, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to create deployment")
+		}
+	} else {
+		existingDeployment.Spec.Template = deployment.Spec.Template
+		_, err = clientset.AppsV1().Deployments(namespace).Update(context.TODO(), existingDeployment, metav1.UpdateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to update deployment")
+		}
+	}
+
+	_, err = clientset.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get existing service")
+	}
+	if kuberneteserrors.IsNotFound(err) {
+		_, err = clientset.CoreV1().Services(namespace).Create(context.TODO(), fileSystemMinioService(name, namespace), metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to create service")
+		}
+	}
+
+	if err := waitForFileSystemMinio(clientset, namespace, name, 2*time.Minute); err != nil {
+		return errors.Wrap(err, "failed to wait for minio")
+	}
+
+	fileSystem.AccessKeyID = string(secret.Data["accesskey"])
+	fileSystem.SecretAccessKey = string(secret.Data["secretkey"])
+	fileSystem.Endpoint = fileSystemMinioEndpoint(name, namespace)
+
+	return nil
+}
+
+// StartFileSystemStoreUpdate switches the store to the file system in the background, since the
+// minio server has to be ready before the store can be validated. Progress is reported in the
+// store migration status, and the backups are copied from the previous store.
+func StartFileSystemStoreUpdate(previousStore *types.Store, store *types.Store) error {
+	if err := ValidateFileSystemConfig(store.FileSystem); err != nil {
+		return errors.Wrap(err, "invalid file system configuration")
+	}
+
+	storeMigrationMtx.Lock()
+	defer storeMigrationMtx.Unlock()
+
+	status, _, err := GetStoreMigrationStatus()
+	if err != nil {
+		return errors.Wrap(err, "failed to get store migration status")
+	}
+	if status == "running" {
+		return ErrStoreMigrationRunning
+	}
+
+	if err := task.SetTaskStatus(StoreMigrationTaskID, "Starting the file system server", "running"); err != nil {
+		return errors.Wrap(err, "failed to set task status")
+	}
+
+	// the stores are owned by the update from here on
+	previousStore, store = previousStore.DeepCopy(), store.DeepCopy()
+	go func() {
+		if err := updateFileSystemStore(previousStore, store); err != nil {
+			logger.Error(err)
+			if err := task.SetTaskStatus(StoreMigrationTaskID, err.Error(), "failed"); err != nil {
+				logger.Error(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func updateFileSystemStore(previousStore *types.Store, store *types.Store) error {
+	finishedCh := make(chan struct{})
+	defer close(finishedCh)
+	go keepTaskAlive(StoreMigrationTaskID, finishedCh)
+
+	// the same minio server serves every share, so the backups are copied to the new share before
+	// the server is moved to it
+	isMovingShares := previousStore != nil && previousStore.FileSystem != nil && !isSameFileSystem(previousStore.FileSystem, store.FileSystem)
+	if isMovingShares {
+		if err := copyBackupsToFileSystem(previousStore, store.FileSystem); err != nil {
+			return errors.Wrap(err, "failed to copy backups to the new file system")
+		}
+	}
+
+	if err := DeployFileSystemMinio(store.FileSystem); err != nil {
+		return errors.Wrap(err, "failed to deploy file system minio")
+	}
+
+	if err := task.SetTaskStatus(StoreMigrationTaskID, "Updating the store", "running"); err != nil {
+		logger.Error(err)
+	}
+
+	if err := ValidateStore(store); err != nil {
+		return errors.Wrap(err, "failed to validate store")
+	}
+
+	if _, err := UpdateGlobalStore(store); err != nil {
+		return errors.Wrap(err, "failed to update global store")
+	}
+
+	// most plugins (all?) require that velero be restared after updating
+	if err := RestartVelero(); err != nil {
+		return errors.Wrap(err, "failed to restart velero")
+	}
+
+	migrating := false
+	if !isMovingShares {
+		started, err := startStoreMigration(previousStore, store)
+		if err != nil {
+			return errors.Wrap(err, "failed to start copying existing backups to the store")
+		}
+		migrating = started
+	}
+	if !migrating {
+		if err := task.ClearTaskStatus(StoreMigrationTaskID); err != nil {
+			logger.Error(err)
+		}
+	}
+
+	return nil
+}
+
+// copyBackupsToFileSystem copies the backups from the share that the minio server currently serves
+// to another share, through a second minio server that is removed once the copy is done
+func copyBackupsToFileSystem(previousStore *types.Store, fileSystem *types.StoreFileSystem) error {
+	if err := task.SetTaskStatus(StoreMigrationTaskID, "Starting a file system server for the new share", "running"); err != nil {
+		logger.Error(err)
+	}
+
+	nextFileSystem := *fileSystem
+	if err := deployFileSystemMinio(fileSystemMinioNextName, &nextFileSystem); err != nil {
+		return errors.Wrap(err, "failed to deploy minio for the new share")
+	}
+	defer func() {
+		if err := deleteFileSystemMinio(fileSystemMinioNextName, false); err != nil {
+			logger.Error(err)
+		}
+	}()
+
+	nextStore := previousStore.DeepCopy()
+	nextStore.Path = ""
+	nextStore.FileSystem = &nextFileSystem
+
+	// this also creates the bucket on the new share
+	if err := validateFileSystem(nextStore.FileSystem, nextStore.Bucket); err != nil {
+		return errors.Wrap(err, "failed to validate new share")
+	}
+
+	fromClient, err := s3ClientForStore(previousStore)
+	if err != nil {
+		return errors.Wrap(err, "failed to create client for previous share")
+	}
+
+	toClient, err := s3ClientForStore(nextStore)
+	if err != nil {
+		return errors.Wrap(err, "failed to create client for new share")
+	}
+
+	if _, err := copyBackups(previousStore, nextStore, fromClient, toClient); err != nil {
+		return errors.Wrap(err, "failed to copy backups")
+	}
+
+	return nil
+}
+
+// DeleteFileSystemMinio removes the minio server once the file system is no longer the store.
+// The backups are left on the file system.
+func DeleteFileSystemMinio() error {
+	return deleteFileSystemMinio(FileSystemMinioName, true)
+}
+
+func deleteFileSystemMinio(name string, deleteSecret bool) error {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster config")
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create clientset")
+	}
+
+	namespace := os.Getenv("POD_NAMESPACE")
+
+	err = clientset.AppsV1().Deployments(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete deployment")
+	}
+
+	err = clientset.CoreV1().Services(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete service")
+	}
+
+	if !deleteSecret {
+		return nil
+	}
+
+	// the secret is shared by all the servers
+	err = clientset.CoreV1().Secrets(namespace).Delete(context.TODO(), FileSystemMinioName, metav1.DeleteOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete secret")
+	}
+
+	return nil
+}
+
+// isSameFileSystem returns true when both are served from the same NFS share or host path
+func isSameFileSystem(a *types.StoreFileSystem, b *types.StoreFileSystem) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.HostPath != b.HostPath {
+		return false
+	}
+	if a.NFS == nil || b.NFS == nil {
+		return a.NFS == b.NFS
+	}
+	return a.NFS.Server == b.NFS.Server && a.NFS.Path == b.NFS.Path
+}
+
+// getFileSystemConfig reads the NFS share or host path back from the minio deployment,
+// returns nil if there is no deployment
+func getFileSystemConfig(clientset kubernetes.Interface, namespace string) (*types.StoreFileSystem, error) {
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), FileSystemMinioName, metav1.GetOptions{})
+	if kuberneteserrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get deployment")
+	}
+
+	fileSystem := types.StoreFileSystem{}
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		if volume.Name != fileSystemMinioVolumeName {
+			continue
+		}
+		if volume.NFS != nil {
+			fileSystem.NFS = &types.StoreNFS{
+				Server: volume.NFS.Server,
+				Path:   volume.NFS.Path,
+			}
+		} else if volume.HostPath != nil {
+			fileSystem.HostPath = volume.HostPath.Path
+		}
+	}
+
+	return &fileSystem, nil
+}
+
+func ensureFileSystemMinioSecret(clientset kubernetes.Interface, namespace string) (*corev1.Secret, error) {
+	existingSecret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), FileSystemMinioName, metav1.GetOptions{})
+	if err == nil {
+		return existingSecret, nil
+	}
+	if !kuberneteserrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "failed to get existing secret")
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      FileSystemMinioName,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"accesskey": []byte(util.GenPassword(20)),
+			"secretkey": []byte(util.GenPassword(40)),
+		},
+	}
+
+	created, err := clientset.CoreV1().Secrets(namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create secret")
+	}
+
+	return created, nil
+}
+
+// fileSystemMinioPodSpec reuses the image, pull secrets and security context of the kotsadm minio
+// statefulset when there is one, so that this also works in airgapped installs
+func fileSystemMinioPodSpec(clientset kubernetes.Interface, namespace string, fileSystem *types.StoreFileSystem) (corev1.PodSpec, error) {
+	image := defaultFileSystemMinioImage
+	var imagePullSecrets []corev1.LocalObjectReference
+	var securityContext *corev1.PodSecurityContext
+
+	statefulset, err := clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), "kotsadm-minio", metav1.GetOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return corev1.PodSpec{}, errors.Wrap(err, "failed to get kotsadm minio statefulset")
+	}
+	if err == nil && len(statefulset.Spec.Template.Spec.Containers) > 0 {
+		image = statefulset.Spec.Template.Spec.Containers[0].Image
+		imagePullSecrets = statefulset.Spec.Template.Spec.ImagePullSecrets
+		securityContext = statefulset.Spec.Template.Spec.SecurityContext
+	}
+
+	secretEnv := func(name string, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: FileSystemMinioName,
+					},
+					Key: key,
+				},
+			},
+		}
+	}
+
+	podSpec := corev1.PodSpec{
+		SecurityContext:  securityContext,
+		ImagePullSecrets: imagePullSecrets,
+		Volumes: []corev1.Volume{
+			fileSystemMinioVolume(fileSystem),
+			{
+				Name: "minio-config-dir",
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+		},
+		Containers: []corev1.Container{
+			{
+				Image:           image,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Name:            "minio",
+				Command: []string{
+					"/bin/sh",
+					"-ce",
+					"/usr/bin/docker-entrypoint.sh minio -C /home/minio/.minio/ server /export",
+				},
+				Ports: []corev1.ContainerPort{
+					{
+						Name:          "service",
+						ContainerPort: fileSystemMinioPort,
+					},
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{
+						Name:      fileSystemMinioVolumeName,
+						MountPath: "/export",
+					},
+					{
+						Name:      "minio-config-dir",
+						MountPath: "/home/minio/.minio/",
+					},
+				},
+				Env: []corev1.EnvVar{
+					secretEnv("MINIO_ACCESS_KEY", "accesskey"),
+					secretEnv("MINIO_SECRET_KEY", "secretkey"),
+					{
+						Name:  "MINIO_BROWSER",
+						Value: "off",
+					},
+				},
+				ReadinessProbe: &corev1.Probe{
+					InitialDelaySeconds: 5,
+					TimeoutSeconds:      1,
+					FailureThreshold:    3,
+					SuccessThreshold:    1,
+					PeriodSeconds:       15,
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path:   "/minio/health/ready",
+							Port:   intstr.FromString("service"),
+							Scheme: corev1.URISchemeHTTP,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return podSpec, nil
+}
+
+func fileSystemMinioVolume(fileSystem *types.StoreFileSystem) corev1.Volume {
+	volume := corev1.Volume{
+		Name: fileSystemMinioVolumeName,
+	}
+
+	if fileSystem.NFS != nil {
+		volume.NFS = &corev1.NFSVolumeSource{
+			Server: fileSystem.NFS.Server,
+			Path:   fileSystem.NFS.Path,
+		}
+	} else {
+		hostPathType := corev1.HostPathDirectoryOrCreate
+		volume.HostPath = &corev1.HostPathVolumeSource{
+			Path: fileSystem.HostPath,
+			Type: &hostPathType,
+		}
+	}
+
+	return volume
+}
+
+func fileSystemMinioDeployment(name string, namespace string, podSpec corev1.PodSpec) *appsv1.Deployment {
+	replicas := int32(1)
+	labels := map[string]string{
+		"app": name,
+	}
+
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			// two servers must never write to the same share
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+func fileSystemMinioService(name string, namespace string) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"app": name,
+			},
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "service",
+					Port:       fileSystemMinioPort,
+					TargetPort: intstr.FromInt(fileSystemMinioPort),
+				},
+			},
+		},
+	}
+}
+
+func waitForFileSystemMinio(clientset kubernetes.Interface, namespace string, name string, timeout time.Duration) error {
+	start := time.Now()
+
+	for {
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to get deployment")
+		}
+
+		if deployment.Status.ObservedGeneration >= deployment.Generation && deployment.Status.UpdatedReplicas > 0 && deployment.Status.ReadyReplicas > 0 {
+			return nil
+		}
+
+		time.Sleep(time.Second)
+
+		if time.Now().Sub(start) > timeout {
+			return errors.New("timeout waiting for minio to be ready")
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	"github.com/stretchr/testify/assert"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_ValidateFileSystemConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileSystem  *types.StoreFileSystem
+		expectError bool
+	}{
+		{
+			name: "nfs",
+			fileSystem: &types.StoreFileSystem{
+				NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"},
+			},
+		},
+		{
+			name: "host path",
+			fileSystem: &types.StoreFileSystem{
+				HostPath: "/var/lib/backups",
+			},
+		},
+		{
+			name:        "nothing",
+			fileSystem:  &types.StoreFileSystem{},
+			expectError: true,
+		},
+		{
+			name: "both",
+			fileSystem: &types.StoreFileSystem{
+				NFS:      &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"},
+				HostPath: "/var/lib/backups",
+			},
+			expectError: true,
+		},
+		{
+			name: "nfs without a server",
+			fileSystem: &types.StoreFileSystem{
+				NFS: &types.StoreNFS{Path: "/exports/backups"},
+			},
+			expectError: true,
+		},
+		{
+			name: "relative nfs path",
+			fileSystem: &types.StoreFileSystem{
+				NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "exports/backups"},
+			},
+			expectError: true,
+		},
+		{
+			name: "relative host path",
+			fileSystem: &types.StoreFileSystem{
+				HostPath: "backups",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateFileSystemConfig(test.fileSystem)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_fileSystemMinioVolume(t *testing.T) {
+	hostPathType := corev1.HostPathDirectoryOrCreate
+
+	tests := []struct {
+		name       string
+		fileSystem *types.StoreFileSystem
+		expect     corev1.Volume
+	}{
+		{
+			name: "nfs",
+			fileSystem: &types.StoreFileSystem{
+				NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"},
+			},
+			expect: corev1.Volume{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					NFS: &corev1.NFSVolumeSource{Server: "10.0.0.5", Path: "/exports/backups"},
+				},
+			},
+		},
+		{
+			name: "host path",
+			fileSystem: &types.StoreFileSystem{
+				HostPath: "/var/lib/backups",
+			},
+			expect: corev1.Volume{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: "/var/lib/backups", Type: &hostPathType},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, fileSystemMinioVolume(test.fileSystem))
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	"github.com/replicatedhq/kots/kotsadm/pkg/task"
+	veleroclientv1 "github.com/vmware-tanzu/velero/pkg/generated/clientset/versioned/typed/velero/v1"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const StoreMigrationTaskID = "snapshot-store-migration"
+
+var (
+	ErrStoreMigrationRunning = errors.New("backups are being copied to the current store")
+
+	// storeMigrationMtx makes checking for a running migration and starting one atomic
+	storeMigrationMtx sync.Mutex
+)
+
+// the directories velero keeps under the prefix of a backup storage location that are copied
+// when the store changes
+var migratedStoreDirs = []string{"backups", "restic"}
+
+type storeObject struct {
+	Key  string
+	Size int64
+}
+
+// GetStoreMigrationStatus returns the status and the last progress message of a running
+// or failed store migration
+func GetStoreMigrationStatus() (string, string, error) {
+	status, message, err := task.GetTaskStatusWithMessage(StoreMigrationTaskID)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to get task status")
+	}
+
+	return status, message, nil
+}
+
+// StartStoreMigration copies the velero backups and restic repositories from the previous store
+// to the new one in the background, so that they are synced back into the cluster after the
+// backup storage location changes. Progress is reported in the task status table.
+// Only S3-compatible stores can be copied from and to. Returns false if there is nothing to copy,
+// and ErrStoreMigrationRunning if a migration is already running.
+func StartStoreMigration(from *types.Store, to *types.Store) (bool, error) {
+	storeMigrationMtx.Lock()
+	defer storeMigrationMtx.Unlock()
+
+	status, _, err := GetStoreMigrationStatus()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get store migration status")
+	}
+	if status == "running" {
+		return false, ErrStoreMigrationRunning
+	}
+
+	return startStoreMigration(from, to)
+}
+
+// startStoreMigration is StartStoreMigration for callers that already own the running task
+func startStoreMigration(from *types.Store, to *types.Store) (bool, error) {
+	if from == nil || to == nil || isSameStore(from, to) {
+		return false, nil
+	}
+
+	if !isS3Compatible(from) || !isS3Compatible(to) {
+		logger.Info("not migrating backups between stores",
+			zap.String("from", from.Provider),
+			zap.String("to", to.Provider))
+		return false, nil
+	}
+
+	fromClient, err := s3ClientForStore(from)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create client for previous store")
+	}
+
+	toClient, err := s3ClientForStore(to)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create client for new store")
+	}
+
+	if err := task.SetTaskStatus(StoreMigrationTaskID, "Listing backups in the previous store", "running"); err != nil {
+		return false, errors.Wrap(err, "failed to set task status")
+	}
+
+	go func() {
+		if err := migrateStore(from, to, fromClient, toClient); err != nil {
+			logger.Error(err)
+		}
+	}()
+
+	return true, nil
+}
+
+func migrateStore(from *types.Store, to *types.Store, fromClient *s3.S3, toClient *s3.S3) (finalError error) {
+	finishedCh := make(chan struct{})
+	defer close(finishedCh)
+	go keepTaskAlive(StoreMigrationTaskID, finishedCh)
+
+	defer func() {
+		if finalError == nil {
+			if err := task.ClearTaskStatus(StoreMigrationTaskID); err != nil {
+				logger.Error(err)
+			}
+		} else {
+			if err := task.SetTaskStatus(StoreMigrationTaskID, finalError.Error(), "failed"); err != nil {
+				logger.Error(err)
+			}
+		}
+	}()
+
+	copied, err := copyBackups(from, to, fromClient, toClient)
+	if err != nil {
+		return errors.Wrap(err, "failed to copy backups")
+	}
+
+	// velero keeps the location of each restic repository, these are recreated for the new store
+	if err := resetResticRepositories(); err != nil {
+		return errors.Wrap(err, "failed to reset restic repositories")
+	}
+
+	if from.FileSystem != nil && to.FileSystem == nil {
+		if err := DeleteFileSystemMinio(); err != nil {
+			logger.Error(err)
+		}
+	}
+
+	logger.Debug("migrated backups to the new store",
+		zap.Int("copied", copied))
+
+	return nil
+}
+
+// copyBackups copies the objects that are missing or have a different size in the new store, and
+// verifies that all objects were copied. Returns the number of objects copied.
+func copyBackups(from *types.Store, to *types.Store, fromClient *s3.S3, toClient *s3.S3) (int, error) {
+	objects, err := listStoreObjects(fromClient, from.Bucket, from.Path)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list objects in previous store")
+	}
+
+	existingObjects, err := listStoreObjects(toClient, to.Bucket, to.Path)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list objects in new store")
+	}
+
+	existingSizes := map[string]int64{}
+	for _, object := range existingObjects {
+		existingSizes[object.Key] = object.Size
+	}
+
+	copied := 0
+	for i, object := range objects {
+		if err := task.SetTaskStatus(StoreMigrationTaskID, fmt.Sprintf("Copying object %d of %d to the new store", i+1, len(objects)), "running"); err != nil {
+			logger.Error(err)
+		}
+
+		toKey := migratedObjectKey(object.Key, from.Path, to.Path)
+		if size, ok := existingSizes[toKey]; ok && size == object.Size {
+			continue
+		}
+
+		if err := copyObject(fromClient, from.Bucket, object.Key, toClient, to.Bucket, toKey); err != nil {
+			return 0, errors.Wrap(err, "failed to copy object")
+		}
+		copied++
+	}
+
+	if err := task.SetTaskStatus(StoreMigrationTaskID, "Verifying the backups in the new store", "running"); err != nil {
+		logger.Error(err)
+	}
+
+	copiedObjects, err := listStoreObjects(toClient, to.Bucket, to.Path)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list objects in new store")
+	}
+
+	if missing := missingObjects(objects, copiedObjects, from.Path, to.Path); len(missing) > 0 {
+		return 0, errors.Errorf("%d objects were not copied to the new store, including %s", len(missing), missing[0])
+	}
+
+	return copied, nil
+}
+
+// keepTaskAlive updates the timestamp of the task every second until finishedCh is closed,
+// otherwise the task is no longer reported as running
+func keepTaskAlive(taskID string, finishedCh chan struct{}) {
+	for {
+		select {
+		case <-time.After(time.Second):
+			if err := task.UpdateTaskStatusTimestamp(taskID); err != nil {
+				logger.Error(err)
+			}
+		case <-finishedCh:
+			return
+		}
+	}
+}
+
+// listStoreObjects lists the objects velero keeps under the prefix of a store, skipping restic locks
+// that would keep the repositories locked in the new store
+func listStoreObjects(client *s3.S3, bucket string, prefix string) ([]storeObject, error) {
+	objects := []storeObject{}
+
+	for _, dir := range migratedStoreDirs {
+		err := client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(storeDirPrefix(prefix, dir)),
+		}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, object := range page.Contents {
+				if strings.Contains(*object.Key, "/locks/") {
+					continue
+				}
+				objects = append(objects, storeObject{
+					Key:  *object.Key,
+					Size: aws.Int64Value(object.Size),
+				})
+			}
+			return true
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list %s", dir)
+		}
+	}
+
+	return objects, nil
+}
+
+func copyObject(fromClient *s3.S3, fromBucket string, fromKey string, toClient *s3.S3, toBucket string, toKey string) error {
+	object, err := fromClient.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(fromBucket),
+		Key:    aws.String(fromKey),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get %s", fromKey)
+	}
+	defer object.Body.Close()
+
+	uploader := s3manager.NewUploaderWithClient(toClient)
+	_, err = uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(toBucket),
+		Key:    aws.String(toKey),
+		Body:   object.Body,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to put %s", toKey)
+	}
+
+	return nil
+}
+
+// missingObjects returns the keys of the objects that are not in the new store with the same size
+func missingObjects(objects []storeObject, copiedObjects []storeObject, fromPrefix string, toPrefix string) []string {
+	copiedSizes := map[string]int64{}
+	for _, object := range copiedObjects {
+		copiedSizes[object.Key] = object.Size
+	}
+
+	missing := []string{}
+	for _, object := range objects {
+		size, ok := copiedSizes[migratedObjectKey(object.Key, fromPrefix, toPrefix)]
+		if !ok || size != object.Size {
+			missing = append(missing, object.Key)
+		}
+	}
+
+	return missing
+}
+
+func resetResticRepositories() error {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster config")
+	}
+
+	veleroClient, err := veleroclientv1.NewForConfig(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create velero clientset")
+	}
+
+	veleroNamespace, err := DetectVeleroNamespace()
+	if err != nil {
+		return errors.Wrap(err, "failed to detect velero namespace")
+	}
+
+	repositories, err := veleroClient.ResticRepositories(veleroNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list restic repositories")
+	}
+
+	for _, repository := range repositories.Items {
+		err := veleroClient.ResticRepositories(veleroNamespace).Delete(context.TODO(), repository.Name, metav1.DeleteOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete restic repository %s", repository.Name)
+		}
+	}
+
+	return nil
+}
+
+// storeDirPrefix is where velero keeps dir under the prefix of a backup storage location
+func storeDirPrefix(prefix string, dir string) string {
+	return strings.TrimPrefix(path.Join(prefix, dir)+"/", "/")
+}
+
+// migratedObjectKey is the key of an object after moving it from one store prefix to another
+func migratedObjectKey(key string, fromPrefix string, toPrefix string) string {
+	fromPrefix = strings.Trim(fromPrefix, "/")
+	if fromPrefix != "" {
+		key = strings.TrimPrefix(key, fromPrefix+"/")
+	}
+	return strings.TrimPrefix(path.Join(toPrefix, key), "/")
+}
+
+func isSameStore(a *types.Store, b *types.Store) bool {
+	if a.Provider != b.Provider || a.Bucket != b.Bucket || a.Path != b.Path || storeEndpoint(a) != storeEndpoint(b) {
+		return false
+	}
+	// every file system is served from the same endpoint
+	return isSameFileSystem(a.FileSystem, b.FileSystem)
+}
+
+func isS3Compatible(store *types.Store) bool {
+	return store.AWS != nil || store.Other != nil || store.Internal != nil || store.FileSystem != nil
+}
+
+func storeEndpoint(store *types.Store) string {
+	switch {
+	case store.Other != nil:
+		return store.Other.Endpoint
+	case store.Internal != nil:
+		return store.Internal.Endpoint
+	case store.FileSystem != nil:
+		return store.FileSystem.Endpoint
+	}
+	return ""
+}
+
+func s3ClientForStore(store *types.Store) (*s3.S3, error) {
+	var s3Config *aws.Config
+	var accessKeyID, secretAccessKey string
+
+	switch {
+	case store.AWS != nil:
+		s3Config = &aws.Config{
+			Region: aws.String(store.AWS.Region),
+		}
+		if !store.AWS.UseInstanceRole {
+			accessKeyID, secretAccessKey = store.AWS.AccessKeyID, store.AWS.SecretAccessKey
+		}
+	case store.Other != nil:
+		s3Config = &aws.Config{
+			Region:           aws.String(store.Other.Region),
+			Endpoint:         aws.String(store.Other.Endpoint),
+			DisableSSL:       aws.Bool(true), // TODO: this needs to be configurable
+			S3ForcePathStyle: aws.Bool(true),
+		}
+		accessKeyID, secretAccessKey = store.Other.AccessKeyID, store.Other.SecretAccessKey
+	case store.Internal != nil:
+		s3Config = &aws.Config{
+			Region:           aws.String(store.Internal.Region),
+			Endpoint:         aws.String(store.Internal.Endpoint),
+			DisableSSL:       aws.Bool(true),
+			S3ForcePathStyle: aws.Bool(true),
+		}
+		accessKeyID, secretAccessKey = store.Internal.AccessKeyID, store.Internal.SecretAccessKey
+	case store.FileSystem != nil:
+		s3Config = &aws.Config{
+			Region:           aws.String(FileSystemMinioRegion),
+			Endpoint:         aws.String(store.FileSystem.Endpoint),
+			DisableSSL:       aws.Bool(true),
+			S3ForcePathStyle: aws.Bool(true),
+		}
+		accessKeyID, secretAccessKey = store.FileSystem.AccessKeyID, store.FileSystem.SecretAccessKey
+	default:
+		return nil, errors.Errorf("backups cannot be copied with provider %s", store.Provider)
+	}
+
+	if accessKeyID != "" && secretAccessKey != "" {
+		s3Config.Credentials = credentials.NewStaticCredentials(accessKeyID, secretAccessKey, "")
+	}
+
+	return s3.New(session.New(s3Config)), nil
+}
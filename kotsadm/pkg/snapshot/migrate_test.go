@@ -0,0 +1,156 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	"github.com/stretchr/testify/assert"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_migratedObjectKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		fromPrefix string
+		toPrefix   string
+		expect     string
+	}{
+		{
+			name:   "no prefixes",
+			key:    "backups/daily/velero-backup.json",
+			expect: "backups/daily/velero-backup.json",
+		},
+		{
+			name:       "from a prefix",
+			key:        "kots/backups/daily/velero-backup.json",
+			fromPrefix: "kots",
+			expect:     "backups/daily/velero-backup.json",
+		},
+		{
+			name:     "to a prefix",
+			key:      "restic/app/config",
+			toPrefix: "/snapshots/kots",
+			expect:   "snapshots/kots/restic/app/config",
+		},
+		{
+			name:       "between prefixes",
+			key:        "old/restic/app/data/00/0011",
+			fromPrefix: "/old/",
+			toPrefix:   "new",
+			expect:     "new/restic/app/data/00/0011",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, migratedObjectKey(test.key, test.fromPrefix, test.toPrefix))
+		})
+	}
+}
+
+func Test_missingObjects(t *testing.T) {
+	objects := []storeObject{
+		{Key: "old/backups/daily/daily.tar.gz", Size: 100},
+		{Key: "old/backups/daily/velero-backup.json", Size: 10},
+		{Key: "old/restic/app/config", Size: 5},
+	}
+
+	tests := []struct {
+		name          string
+		copiedObjects []storeObject
+		expect        []string
+	}{
+		{
+			name: "all copied",
+			copiedObjects: []storeObject{
+				{Key: "backups/daily/daily.tar.gz", Size: 100},
+				{Key: "backups/daily/velero-backup.json", Size: 10},
+				{Key: "restic/app/config", Size: 5},
+				{Key: "backups/other/velero-backup.json", Size: 12},
+			},
+			expect: []string{},
+		},
+		{
+			name: "missing and truncated",
+			copiedObjects: []storeObject{
+				{Key: "backups/daily/daily.tar.gz", Size: 50},
+				{Key: "backups/daily/velero-backup.json", Size: 10},
+			},
+			expect: []string{"old/backups/daily/daily.tar.gz", "old/restic/app/config"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, missingObjects(objects, test.copiedObjects, "old", ""))
+		})
+	}
+}
+
+func Test_isSameStore(t *testing.T) {
+	fileSystemStore := func(fileSystem types.StoreFileSystem) *types.Store {
+		fileSystem.Endpoint = "http://kotsadm-fs-minio.default:9000"
+		return &types.Store{
+			Provider:   "aws",
+			Bucket:     FileSystemMinioBucketName,
+			FileSystem: &fileSystem,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		a      *types.Store
+		b      *types.Store
+		expect bool
+	}{
+		{
+			name:   "same nfs share",
+			a:      fileSystemStore(types.StoreFileSystem{NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"}}),
+			b:      fileSystemStore(types.StoreFileSystem{NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"}}),
+			expect: true,
+		},
+		{
+			name:   "nfs path changed",
+			a:      fileSystemStore(types.StoreFileSystem{NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"}}),
+			b:      fileSystemStore(types.StoreFileSystem{NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/other"}}),
+			expect: false,
+		},
+		{
+			name:   "nfs server changed",
+			a:      fileSystemStore(types.StoreFileSystem{NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"}}),
+			b:      fileSystemStore(types.StoreFileSystem{NFS: &types.StoreNFS{Server: "10.0.0.6", Path: "/exports/backups"}}),
+			expect: false,
+		},
+		{
+			name:   "nfs to host path",
+			a:      fileSystemStore(types.StoreFileSystem{NFS: &types.StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"}}),
+			b:      fileSystemStore(types.StoreFileSystem{HostPath: "/var/lib/backups"}),
+			expect: false,
+		},
+		{
+			name:   "host path changed",
+			a:      fileSystemStore(types.StoreFileSystem{HostPath: "/var/lib/backups"}),
+			b:      fileSystemStore(types.StoreFileSystem{HostPath: "/var/lib/other"}),
+			expect: false,
+		},
+		{
+			name:   "same bucket",
+			a:      &types.Store{Provider: "aws", Bucket: "backups", AWS: &types.StoreAWS{Region: "us-east-1"}},
+			b:      &types.Store{Provider: "aws", Bucket: "backups", AWS: &types.StoreAWS{Region: "us-east-1"}},
+			expect: true,
+		},
+		{
+			name:   "prefix changed",
+			a:      &types.Store{Provider: "aws", Bucket: "backups", AWS: &types.StoreAWS{Region: "us-east-1"}},
+			b:      &types.Store{Provider: "aws", Bucket: "backups", Path: "kots", AWS: &types.StoreAWS{Region: "us-east-1"}},
+			expect: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, isSameStore(test.a, test.b))
+		})
+	}
+}
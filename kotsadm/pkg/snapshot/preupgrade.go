@@ -0,0 +1,249 @@
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	veleroclientv1 "github.com/vmware-tanzu/velero/pkg/generated/clientset/versioned/typed/velero/v1"
+	"go.uber.org/zap"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	PreUpgradeSnapshotDisabled = "disabled"
+	PreUpgradeSnapshotWarn     = "warn"
+	PreUpgradeSnapshotBlock    = "block"
+
+	// PreUpgradeSnapshotPending is recorded when a version is deployed over another one, the snapshot is
+	// started, or the record removed if the policy is disabled, before the version is sent to the operator
+	PreUpgradeSnapshotPending   = "pending"
+	PreUpgradeSnapshotRunning   = "running"
+	PreUpgradeSnapshotCompleted = "completed"
+	PreUpgradeSnapshotFailed    = "failed"
+
+	// PreUpgradeSequenceAnnotation is set on pre-upgrade backups to the sequence that was deployed after them
+	PreUpgradeSequenceAnnotation = "kots.io/pre-upgrade-sequence"
+)
+
+var DefaultPreUpgradeSnapshotTimeout = time.Hour
+
+// PreUpgradeSnapshotPolicy returns what to do when the pre-upgrade snapshot fails, or an empty string
+// if no snapshot should be taken. The policy of the app overrides the application spec.
+func PreUpgradeSnapshotPolicy(appPolicy string, spec *kotsv1beta1.PreUpgradeSnapshot) (string, time.Duration, error) {
+	policy := ""
+	timeout := DefaultPreUpgradeSnapshotTimeout
+
+	if spec != nil {
+		if spec.Enabled {
+			policy = spec.OnFailure
+			if policy == "" {
+				policy = PreUpgradeSnapshotBlock
+			}
+		}
+		if spec.Timeout != "" {
+			d, err := time.ParseDuration(spec.Timeout)
+			if err != nil {
+				return "", 0, errors.Wrapf(err, "failed to parse timeout %q", spec.Timeout)
+			}
+			timeout = d
+		}
+	}
+
+	if appPolicy != "" {
+		policy = appPolicy
+	}
+
+	switch policy {
+	case "", PreUpgradeSnapshotDisabled:
+		return "", timeout, nil
+	case PreUpgradeSnapshotWarn, PreUpgradeSnapshotBlock:
+		return policy, timeout, nil
+	}
+
+	return "", 0, errors.Errorf("unknown pre-upgrade snapshot policy %q", policy)
+}
+
+// GetPreUpgradeSnapshot returns the snapshot that was taken before the sequence was deployed, or nil
+// if there is none
+func GetPreUpgradeSnapshot(appID string, sequence int64) (*types.PreUpgradeSnapshot, error) {
+	db := persistence.MustGetPGSession()
+	query := `select app_id, sequence, previous_sequence, backup_name, status, error, on_failure, started_at, deadline, finished_at
+from pre_upgrade_snapshot where app_id = $1 and sequence = $2`
+	row := db.QueryRow(query, appID, sequence)
+
+	snapshot := types.PreUpgradeSnapshot{}
+	var backupName sql.NullString
+	var snapshotError sql.NullString
+	var onFailure sql.NullString
+	var deadline sql.NullTime
+	var finishedAt sql.NullTime
+	err := row.Scan(&snapshot.AppID, &snapshot.Sequence, &snapshot.PreviousSequence, &backupName, &snapshot.Status,
+		&snapshotError, &onFailure, &snapshot.StartedAt, &deadline, &finishedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to scan pre-upgrade snapshot")
+	}
+
+	snapshot.BackupName = backupName.String
+	snapshot.Error = snapshotError.String
+	snapshot.OnFailure = onFailure.String
+	snapshot.Deadline = deadline.Time
+	if finishedAt.Valid {
+		snapshot.FinishedAt = &finishedAt.Time
+	}
+
+	return &snapshot, nil
+}
+
+// DeletePreUpgradeSnapshot removes the record of a pending snapshot that won't be taken
+func DeletePreUpgradeSnapshot(appID string, sequence int64) error {
+	db := persistence.MustGetPGSession()
+	query := `delete from pre_upgrade_snapshot where app_id = $1 and sequence = $2`
+	_, err := db.Exec(query, appID, sequence)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete pre-upgrade snapshot")
+	}
+
+	return nil
+}
+
+// StartPreUpgradeSnapshot backs up the previous sequence of the app, which is the one that is deployed,
+// before the sequence is deployed over it. A backup that can't be created is recorded as failed.
+func StartPreUpgradeSnapshot(a *app.App, sequence int64, previousSequence int64, onFailure string, timeout time.Duration) (*types.PreUpgradeSnapshot, error) {
+	logger.Debug("starting pre-upgrade snapshot",
+		zap.String("appID", a.ID),
+		zap.Int64("sequence", sequence),
+		zap.Int64("previousSequence", previousSequence))
+
+	now := time.Now()
+	snapshot := &types.PreUpgradeSnapshot{
+		AppID:            a.ID,
+		Sequence:         sequence,
+		PreviousSequence: previousSequence,
+		Status:           PreUpgradeSnapshotRunning,
+		OnFailure:        onFailure,
+		StartedAt:        now,
+		Deadline:         now.Add(timeout),
+	}
+
+	deployed := *a
+	deployed.CurrentSequence = previousSequence
+	options := backupOptions{
+		trigger: TriggerPreUpgrade,
+		annotations: map[string]string{
+			PreUpgradeSequenceAnnotation: strconv.FormatInt(sequence, 10),
+		},
+	}
+	backupName, err := createApplicationBackup(&deployed, options)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to create pre-upgrade backup"))
+		snapshot.Status = PreUpgradeSnapshotFailed
+		snapshot.Error = fmt.Sprintf("failed to create backup: %s", errors.Cause(err).Error())
+		snapshot.FinishedAt = &now
+	}
+	snapshot.BackupName = backupName
+
+	db := persistence.MustGetPGSession()
+	query := `insert into pre_upgrade_snapshot (app_id, sequence, previous_sequence, backup_name, status, error, on_failure, started_at, deadline, finished_at)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+on conflict (app_id, sequence) do update set
+  previous_sequence = EXCLUDED.previous_sequence,
+  backup_name = EXCLUDED.backup_name,
+  status = EXCLUDED.status,
+  error = EXCLUDED.error,
+  on_failure = EXCLUDED.on_failure,
+  started_at = EXCLUDED.started_at,
+  deadline = EXCLUDED.deadline,
+  finished_at = EXCLUDED.finished_at`
+	_, err = db.Exec(query, snapshot.AppID, snapshot.Sequence, snapshot.PreviousSequence, snapshot.BackupName, snapshot.Status,
+		snapshot.Error, snapshot.OnFailure, snapshot.StartedAt, snapshot.Deadline, snapshot.FinishedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to insert pre-upgrade snapshot")
+	}
+
+	return snapshot, nil
+}
+
+// UpdatePreUpgradeSnapshot records whether the backup of a running snapshot completed, failed or
+// timed out
+func UpdatePreUpgradeSnapshot(snapshot *types.PreUpgradeSnapshot) error {
+	if snapshot.Status != PreUpgradeSnapshotRunning {
+		return nil
+	}
+
+	now := time.Now()
+	phase, err := getBackupPhase(snapshot.BackupName)
+	if err != nil {
+		if now.Before(snapshot.Deadline) {
+			return errors.Wrap(err, "failed to get backup phase")
+		}
+		// the deadline applies even when the backup can't be checked
+		logger.Error(errors.Wrap(err, "failed to get backup phase"))
+	}
+
+	switch phase {
+	case velerov1.BackupPhaseCompleted:
+		snapshot.Status = PreUpgradeSnapshotCompleted
+	case velerov1.BackupPhasePartiallyFailed, velerov1.BackupPhaseFailed, velerov1.BackupPhaseFailedValidation:
+		snapshot.Status = PreUpgradeSnapshotFailed
+		snapshot.Error = fmt.Sprintf("backup %s finished with phase %s", snapshot.BackupName, phase)
+	default:
+		if now.Before(snapshot.Deadline) {
+			return nil
+		}
+		snapshot.Status = PreUpgradeSnapshotFailed
+		snapshot.Error = fmt.Sprintf("backup %s did not complete by %s", snapshot.BackupName, snapshot.Deadline.UTC().Format(time.RFC3339))
+	}
+	snapshot.FinishedAt = &now
+
+	db := persistence.MustGetPGSession()
+	query := `update pre_upgrade_snapshot set status = $1, error = $2, finished_at = $3 where app_id = $4 and sequence = $5`
+	_, err = db.Exec(query, snapshot.Status, snapshot.Error, snapshot.FinishedAt, snapshot.AppID, snapshot.Sequence)
+	if err != nil {
+		return errors.Wrap(err, "failed to update pre-upgrade snapshot")
+	}
+
+	return nil
+}
+
+// getBackupPhase returns the phase of the velero backup, a backup that no longer exists has failed
+func getBackupPhase(name string) (velerov1.BackupPhase, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get cluster config")
+	}
+
+	veleroClient, err := veleroclientv1.NewForConfig(cfg)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create clientset")
+	}
+
+	backendStorageLocation, err := findBackupStoreLocation()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find backupstoragelocations")
+	}
+
+	veleroBackup, err := veleroClient.Backups(backendStorageLocation.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if kuberneteserrors.IsNotFound(err) {
+			return velerov1.BackupPhaseFailed, nil
+		}
+		return "", errors.Wrap(err, "failed to get velero backup")
+	}
+
+	return veleroBackup.Status.Phase, nil
+}
@@ -0,0 +1,81 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_PreUpgradeSnapshotPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		appPolicy     string
+		spec          *kotsv1beta1.PreUpgradeSnapshot
+		expectPolicy  string
+		expectTimeout time.Duration
+		expectError   bool
+	}{
+		{
+			name:          "no spec",
+			expectPolicy:  "",
+			expectTimeout: DefaultPreUpgradeSnapshotTimeout,
+		},
+		{
+			name:          "disabled in spec",
+			spec:          &kotsv1beta1.PreUpgradeSnapshot{OnFailure: "warn"},
+			expectPolicy:  "",
+			expectTimeout: DefaultPreUpgradeSnapshotTimeout,
+		},
+		{
+			name:          "enabled in spec blocks by default",
+			spec:          &kotsv1beta1.PreUpgradeSnapshot{Enabled: true, Timeout: "30m"},
+			expectPolicy:  PreUpgradeSnapshotBlock,
+			expectTimeout: 30 * time.Minute,
+		},
+		{
+			name:          "enabled in spec warns",
+			spec:          &kotsv1beta1.PreUpgradeSnapshot{Enabled: true, OnFailure: "warn"},
+			expectPolicy:  PreUpgradeSnapshotWarn,
+			expectTimeout: DefaultPreUpgradeSnapshotTimeout,
+		},
+		{
+			name:          "app enables it",
+			appPolicy:     PreUpgradeSnapshotWarn,
+			expectPolicy:  PreUpgradeSnapshotWarn,
+			expectTimeout: DefaultPreUpgradeSnapshotTimeout,
+		},
+		{
+			name:          "app disables it",
+			appPolicy:     PreUpgradeSnapshotDisabled,
+			spec:          &kotsv1beta1.PreUpgradeSnapshot{Enabled: true, Timeout: "10m"},
+			expectPolicy:  "",
+			expectTimeout: 10 * time.Minute,
+		},
+		{
+			name:        "unknown policy",
+			spec:        &kotsv1beta1.PreUpgradeSnapshot{Enabled: true, OnFailure: "ignore"},
+			expectError: true,
+		},
+		{
+			name:        "bad timeout",
+			spec:        &kotsv1beta1.PreUpgradeSnapshot{Enabled: true, Timeout: "an hour"},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, timeout, err := PreUpgradeSnapshotPolicy(test.appPolicy, test.spec)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectPolicy, policy)
+			assert.Equal(t, test.expectTimeout, timeout)
+		})
+	}
+}
@@ -6,19 +6,29 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	kotstypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
 	veleroapiv1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
 	veleroclientv1 "github.com/vmware-tanzu/velero/pkg/generated/clientset/versioned/typed/velero/v1"
 	"go.uber.org/zap"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
-func CreateRestore(snapshotName string) error {
+// CreateRestore creates a velero restore of the backup with the same name. Restore options limit
+// what's restored, the zero value restores everything.
+func CreateRestore(snapshotName string, options types.RestoreOptions) error {
 	// Reference https://github.com/vmware-tanzu/velero/blob/42b612645863c2b3e451b447f9bf798295dd7dba/pkg/cmd/cli/restore/create.go#L222
 
 	logger.Debug("creating restore",
 		zap.String("snapshotName", snapshotName))
 
+	spec, err := restoreSpec(snapshotName, options)
+	if err != nil {
+		return errors.Wrap(err, "failed to get restore spec")
+	}
+
 	bsl, err := findBackupStoreLocation()
 	if err != nil {
 		return errors.Wrap(err, "failed to get velero namespace")
@@ -42,25 +52,12 @@ func CreateRestore(snapshotName string) error {
 		return errors.Wrap(err, "failed to find backup")
 	}
 
-	trueVal := true
 	restore := &veleroapiv1.Restore{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: veleroNamespace,
 			Name:      snapshotName, // restore name same as snapshot name
-			// Labels:    o.Labels.Data(),
-		},
-		Spec: veleroapiv1.RestoreSpec{
-			BackupName: snapshotName,
-			// ScheduleName: o.ScheduleName,
-			// IncludedNamespaces:      o.IncludeNamespaces,
-			// ExcludedNamespaces:      o.ExcludeNamespaces,
-			// IncludedResources:       o.IncludeResources,
-			// ExcludedResources:       o.ExcludeResources,
-			// NamespaceMapping:        o.NamespaceMappings.Data(),
-			// LabelSelector:           o.Selector.LabelSelector,
-			RestorePVs:              &trueVal,
-			IncludeClusterResources: &trueVal,
 		},
+		Spec: *spec,
 	}
 
 	_, err = veleroClient.Restores(veleroNamespace).Create(context.TODO(), restore, metav1.CreateOptions{})
@@ -71,6 +68,65 @@ func CreateRestore(snapshotName string) error {
 	return nil
 }
 
+// ValidateRestoreOptions returns an error if the restore options can't be combined
+func ValidateRestoreOptions(options types.RestoreOptions) error {
+	_, err := restoreSpec("", options)
+	return err
+}
+
+// restoreSpec returns the spec of a restore of the backup. Cluster scoped resources are only
+// restored in full when the whole backup is restored in place, otherwise velero restores the ones
+// that the restored namespaces use.
+func restoreSpec(snapshotName string, options types.RestoreOptions) (*veleroapiv1.RestoreSpec, error) {
+	trueVal := true
+	spec := &veleroapiv1.RestoreSpec{
+		BackupName:       snapshotName,
+		RestorePVs:       &trueVal,
+		NamespaceMapping: options.NamespaceMapping,
+	}
+
+	if !options.IsPartial() {
+		// the cluster resources of the deployed app are still in place when restoring into other namespaces
+		if len(options.NamespaceMapping) == 0 {
+			spec.IncludeClusterResources = &trueVal
+		}
+		return spec, nil
+	}
+
+	if options.KotsadmOnly {
+		if len(options.Namespaces) > 0 || options.LabelSelector != "" || len(options.Resources) > 0 || options.VolumesOnly || len(options.NamespaceMapping) > 0 {
+			return nil, errors.New("kotsadm only restores can not be combined with other restore options")
+		}
+		spec.LabelSelector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				kotstypes.VeleroKey: kotstypes.VeleroLabelConsoleValue,
+			},
+		}
+		return spec, nil
+	}
+
+	if options.VolumesOnly {
+		if len(options.Resources) > 0 {
+			return nil, errors.New("volumes only restores can not be combined with resources")
+		}
+		spec.IncludedResources = []string{"persistentvolumeclaims", "persistentvolumes"}
+	} else {
+		spec.IncludedResources = options.Resources
+	}
+
+	spec.IncludedNamespaces = options.Namespaces
+
+	if options.LabelSelector != "" {
+		labelSelector, err := metav1.ParseToLabelSelector(options.LabelSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse label selector %q", options.LabelSelector)
+		}
+		spec.LabelSelector = labelSelector
+	}
+
+	return spec, nil
+}
+
 func DeleteRestore(snapshotName string) error {
 	bsl, err := findBackupStoreLocation()
 	if err != nil {
@@ -123,3 +179,32 @@ func GetBackup(snapshotName string) (*veleroapiv1.Backup, error) {
 
 	return backup, nil
 }
+
+// GetRestore returns the restore with the snapshot's name, or nil if it does not exist
+func GetRestore(snapshotName string) (*veleroapiv1.Restore, error) {
+	bsl, err := findBackupStoreLocation()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get velero namespace")
+	}
+
+	veleroNamespace := bsl.Namespace
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster config")
+	}
+
+	veleroClient, err := veleroclientv1.NewForConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create clientset")
+	}
+
+	restore, err := veleroClient.Restores(veleroNamespace).Get(context.TODO(), snapshotName, metav1.GetOptions{})
+	if kuberneteserrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get restore")
+	}
+
+	return restore, nil
+}
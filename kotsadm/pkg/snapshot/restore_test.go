@@ -0,0 +1,132 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	kotstypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/stretchr/testify/assert"
+	veleroapiv1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_restoreSpec(t *testing.T) {
+	trueVal := true
+
+	tests := []struct {
+		name        string
+		options     types.RestoreOptions
+		expect      *veleroapiv1.RestoreSpec
+		expectError bool
+	}{
+		{
+			name:    "everything",
+			options: types.RestoreOptions{},
+			expect: &veleroapiv1.RestoreSpec{
+				BackupName:              "backup",
+				RestorePVs:              &trueVal,
+				IncludeClusterResources: &trueVal,
+			},
+		},
+		{
+			name: "everything into another namespace",
+			options: types.RestoreOptions{
+				NamespaceMapping: map[string]string{"app": "app-restored"},
+			},
+			expect: &veleroapiv1.RestoreSpec{
+				BackupName:       "backup",
+				RestorePVs:       &trueVal,
+				NamespaceMapping: map[string]string{"app": "app-restored"},
+			},
+		},
+		{
+			name: "namespace with a label selector",
+			options: types.RestoreOptions{
+				Namespaces:    []string{"app"},
+				LabelSelector: "app=postgres,tier in (db)",
+			},
+			expect: &veleroapiv1.RestoreSpec{
+				BackupName:         "backup",
+				RestorePVs:         &trueVal,
+				IncludedNamespaces: []string{"app"},
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "postgres"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"db"}},
+					},
+				},
+			},
+		},
+		{
+			name: "resources",
+			options: types.RestoreOptions{
+				Resources: []string{"configmaps", "secrets"},
+			},
+			expect: &veleroapiv1.RestoreSpec{
+				BackupName:        "backup",
+				RestorePVs:        &trueVal,
+				IncludedResources: []string{"configmaps", "secrets"},
+			},
+		},
+		{
+			name: "volumes only",
+			options: types.RestoreOptions{
+				VolumesOnly: true,
+			},
+			expect: &veleroapiv1.RestoreSpec{
+				BackupName:        "backup",
+				RestorePVs:        &trueVal,
+				IncludedResources: []string{"persistentvolumeclaims", "persistentvolumes"},
+			},
+		},
+		{
+			name: "kotsadm only",
+			options: types.RestoreOptions{
+				KotsadmOnly: true,
+			},
+			expect: &veleroapiv1.RestoreSpec{
+				BackupName: "backup",
+				RestorePVs: &trueVal,
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{kotstypes.VeleroKey: kotstypes.VeleroLabelConsoleValue},
+				},
+			},
+		},
+		{
+			name: "volumes only with resources",
+			options: types.RestoreOptions{
+				VolumesOnly: true,
+				Resources:   []string{"configmaps"},
+			},
+			expectError: true,
+		},
+		{
+			name: "kotsadm only with namespaces",
+			options: types.RestoreOptions{
+				KotsadmOnly: true,
+				Namespaces:  []string{"app"},
+			},
+			expectError: true,
+		},
+		{
+			name: "bad label selector",
+			options: types.RestoreOptions{
+				LabelSelector: "app in postgres",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec, err := restoreSpec("backup", test.options)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expect, spec)
+		})
+	}
+}
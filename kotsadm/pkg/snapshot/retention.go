@@ -0,0 +1,209 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	veleroclientv1 "github.com/vmware-tanzu/velero/pkg/generated/clientset/versioned/typed/velero/v1"
+	velerolabel "github.com/vmware-tanzu/velero/pkg/label"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// ExpiredBackups returns the finished backups that no retention rule keeps. Keep last counts every
+// finished backup, and the daily, weekly and monthly rules keep the newest completed backup of each
+// of the most recent periods.
+func ExpiredBackups(backups []*types.Backup, retention types.Retention) []*types.Backup {
+	expired := []*types.Backup{}
+	if retention.IsEmpty() {
+		return expired
+	}
+
+	finished := []*types.Backup{}
+	for _, backup := range backups {
+		if backup.StartedAt != nil && isBackupFinished(backup.Status) {
+			finished = append(finished, backup)
+		}
+	}
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finished[i].StartedAt.After(*finished[j].StartedAt)
+	})
+
+	kept := map[string]bool{}
+	for i, backup := range finished {
+		if i < retention.KeepLast {
+			kept[backup.Name] = true
+		}
+	}
+	keepPeriods(finished, retention.KeepDaily, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	}, kept)
+	keepPeriods(finished, retention.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	}, kept)
+	keepPeriods(finished, retention.KeepMonthly, func(t time.Time) string {
+		return t.Format("2006-01")
+	}, kept)
+
+	for _, backup := range finished {
+		if !kept[backup.Name] {
+			expired = append(expired, backup)
+		}
+	}
+
+	return expired
+}
+
+// keepPeriods keeps the newest completed backup of each of the n most recent periods, backups must
+// be sorted newest first
+func keepPeriods(backups []*types.Backup, n int, period func(time.Time) string, kept map[string]bool) {
+	lastPeriod := ""
+	count := 0
+	for _, backup := range backups {
+		if count >= n {
+			return
+		}
+		if backup.Status != string(velerov1.BackupPhaseCompleted) {
+			continue
+		}
+		p := period(backup.StartedAt.UTC())
+		if p == lastPeriod {
+			continue
+		}
+		kept[backup.Name] = true
+		lastPeriod = p
+		count++
+	}
+}
+
+func isBackupFinished(status string) bool {
+	switch velerov1.BackupPhase(status) {
+	case velerov1.BackupPhaseCompleted, velerov1.BackupPhasePartiallyFailed, velerov1.BackupPhaseFailed, velerov1.BackupPhaseFailedValidation:
+		return true
+	}
+	return false
+}
+
+// HasUnfinishedScheduledBackup returns true if a backup taken by the schedule has not finished yet
+func HasUnfinishedScheduledBackup(scheduleID string) (bool, error) {
+	_, veleroBackups, err := listScheduledBackups(scheduleID)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list scheduled backups")
+	}
+
+	for _, veleroBackup := range veleroBackups {
+		switch veleroBackup.Status.Phase {
+		case "", velerov1.BackupPhaseNew, velerov1.BackupPhaseInProgress:
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// PruneScheduledBackups deletes the backups taken by the schedule that its retention rules no longer
+// keep. The rules apply to the backups of each app, and of the admin console, separately. Manual
+// backups are never pruned.
+func PruneScheduledBackups(schedule *types.SnapshotSchedule) error {
+	if schedule.Retention.IsEmpty() {
+		return nil
+	}
+
+	veleroClient, veleroBackups, err := listScheduledBackups(schedule.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list scheduled backups")
+	}
+
+	backupsByApp := map[string][]*types.Backup{}
+	veleroBackupsByName := map[string]velerov1.Backup{}
+	for _, veleroBackup := range veleroBackups {
+		backup := types.Backup{
+			Name:   veleroBackup.Name,
+			Status: string(veleroBackup.Status.Phase),
+			AppID:  veleroBackup.Annotations["kots.io/app-id"],
+		}
+		if veleroBackup.Status.StartTimestamp != nil {
+			backup.StartedAt = &veleroBackup.Status.StartTimestamp.Time
+		}
+
+		backupsByApp[backup.AppID] = append(backupsByApp[backup.AppID], &backup)
+		veleroBackupsByName[veleroBackup.Name] = veleroBackup
+	}
+
+	for _, backups := range backupsByApp {
+		for _, expired := range ExpiredBackups(backups, schedule.Retention) {
+			logger.Debug("pruning expired backup",
+				zap.String("scheduleID", schedule.ID),
+				zap.String("backup", expired.Name))
+
+			if err := deleteBackup(veleroClient, veleroBackupsByName[expired.Name]); err != nil {
+				return errors.Wrapf(err, "failed to delete backup %s", expired.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func listScheduledBackups(scheduleID string) (veleroclientv1.VeleroV1Interface, []velerov1.Backup, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get cluster config")
+	}
+
+	veleroClient, err := veleroclientv1.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create clientset")
+	}
+
+	backendStorageLocation, err := findBackupStoreLocation()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to find backupstoragelocations")
+	}
+
+	veleroBackups, err := veleroClient.Backups(backendStorageLocation.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list velero backups")
+	}
+
+	scheduled := []velerov1.Backup{}
+	for _, veleroBackup := range veleroBackups.Items {
+		if veleroBackup.Annotations[ScheduleAnnotation] == scheduleID {
+			scheduled = append(scheduled, veleroBackup)
+		}
+	}
+
+	return veleroClient, scheduled, nil
+}
+
+// deleteBackup asks velero to delete the backup along with its data in the object store
+func deleteBackup(veleroClient veleroclientv1.VeleroV1Interface, veleroBackup velerov1.Backup) error {
+	deleteBackupRequest := &velerov1.DeleteBackupRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: veleroBackup.Name + "-",
+			Namespace:    veleroBackup.Namespace,
+			Labels: map[string]string{
+				velerov1.BackupNameLabel: velerolabel.GetValidName(veleroBackup.Name),
+				velerov1.BackupUIDLabel:  string(veleroBackup.UID),
+			},
+		},
+		Spec: velerov1.DeleteBackupRequestSpec{
+			BackupName: veleroBackup.Name,
+		},
+	}
+
+	_, err := veleroClient.DeleteBackupRequests(veleroBackup.Namespace).Create(context.TODO(), deleteBackupRequest, metav1.CreateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to create delete backup request")
+	}
+
+	return nil
+}
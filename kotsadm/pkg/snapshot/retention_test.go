@@ -0,0 +1,116 @@
+package snapshot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+	"github.com/stretchr/testify/assert"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_ExpiredBackups(t *testing.T) {
+	// a monday
+	now := time.Date(2020, 7, 6, 12, 0, 0, 0, time.UTC)
+	backup := func(name string, status string, hoursAgo int) *types.Backup {
+		startedAt := now.Add(-time.Hour * time.Duration(hoursAgo))
+		return &types.Backup{
+			Name:      name,
+			Status:    status,
+			StartedAt: &startedAt,
+		}
+	}
+
+	// one backup every 12 hours for 5 weeks, newest first
+	everyTwelveHours := []*types.Backup{}
+	for i := 0; i < 70; i++ {
+		everyTwelveHours = append(everyTwelveHours, backup(fmt.Sprintf("backup-%d", i), "Completed", i*12))
+	}
+	names := func(backups []*types.Backup) []string {
+		n := []string{}
+		for _, b := range backups {
+			n = append(n, b.Name)
+		}
+		return n
+	}
+
+	tests := []struct {
+		name      string
+		backups   []*types.Backup
+		retention types.Retention
+		expect    []string
+	}{
+		{
+			name: "no rules",
+			backups: []*types.Backup{
+				backup("a", "Completed", 1),
+				backup("b", "Completed", 2),
+			},
+			retention: types.Retention{},
+			expect:    []string{},
+		},
+		{
+			name: "keep last",
+			backups: []*types.Backup{
+				backup("c", "Completed", 3),
+				backup("a", "Completed", 1),
+				backup("b", "Failed", 2),
+			},
+			retention: types.Retention{KeepLast: 2},
+			expect:    []string{"c"},
+		},
+		{
+			name: "unfinished backups are not pruned",
+			backups: []*types.Backup{
+				backup("a", "InProgress", 1),
+				backup("b", "New", 2),
+				backup("c", "Completed", 3),
+				backup("d", "Completed", 4),
+				{Name: "e", Status: "New"},
+			},
+			retention: types.Retention{KeepLast: 1},
+			expect:    []string{"d"},
+		},
+		{
+			name: "daily keeps the newest completed backup of each day",
+			backups: []*types.Backup{
+				backup("today-failed", "Failed", 1),
+				backup("today", "Completed", 2),
+				backup("today-older", "Completed", 3),
+				backup("yesterday", "Completed", 24),
+				backup("two-days-ago", "Completed", 48),
+			},
+			retention: types.Retention{KeepDaily: 2},
+			expect:    []string{"today-failed", "today-older", "two-days-ago"},
+		},
+		{
+			name:      "daily, weekly and monthly",
+			backups:   everyTwelveHours,
+			retention: types.Retention{KeepLast: 1, KeepDaily: 3, KeepWeekly: 3, KeepMonthly: 2},
+			// keep last and daily keep 0, 2 and 4 (today, yesterday and the day before), weekly keeps
+			// 0, and the newest backups of the weeks starting on the 29th and the 22nd, which are 2
+			// and 16, and monthly keeps 0 and the newest backup of june, which is 12
+			expect: func() []string {
+				kept := map[string]bool{}
+				for _, i := range []int{0, 2, 4, 12, 16} {
+					kept[fmt.Sprintf("backup-%d", i)] = true
+				}
+				expect := []string{}
+				for _, b := range everyTwelveHours {
+					if !kept[b.Name] {
+						expect = append(expect, b.Name)
+					}
+				}
+				return expect
+			}(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expired := ExpiredBackups(test.backups, test.retention)
+			assert.Equal(t, test.expect, names(expired))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package snapshot
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot/types"
+)
+
+// InstanceScheduleID is the id of the schedule that backs up every installed app and the admin
+// console. App schedules use the id of the app.
+const InstanceScheduleID = "kotsadm"
+
+// GetSchedule returns the schedule with the id, or nil if scheduled snapshots are not enabled for it
+func GetSchedule(id string) (*types.SnapshotSchedule, error) {
+	db := persistence.MustGetPGSession()
+	query := `select id, schedule, keep_last, keep_daily, keep_weekly, keep_monthly, updated_at from snapshot_schedule where id = $1`
+	row := db.QueryRow(query, id)
+
+	schedule, err := scanSchedule(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to scan schedule")
+	}
+
+	return schedule, nil
+}
+
+func ListSchedules() ([]*types.SnapshotSchedule, error) {
+	db := persistence.MustGetPGSession()
+	query := `select id, schedule, keep_last, keep_daily, keep_weekly, keep_monthly, updated_at from snapshot_schedule`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query db")
+	}
+	defer rows.Close()
+
+	schedules := []*types.SnapshotSchedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan schedule")
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSchedule(row rowScanner) (*types.SnapshotSchedule, error) {
+	schedule := types.SnapshotSchedule{}
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.Schedule,
+		&schedule.Retention.KeepLast,
+		&schedule.Retention.KeepDaily,
+		&schedule.Retention.KeepWeekly,
+		&schedule.Retention.KeepMonthly,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// SetSchedule creates or replaces the schedule with the id
+func SetSchedule(id string, cronSpec string, retention types.Retention) error {
+	db := persistence.MustGetPGSession()
+	query := `insert into snapshot_schedule (id, schedule, keep_last, keep_daily, keep_weekly, keep_monthly, updated_at)
+values ($1, $2, $3, $4, $5, $6, $7)
+on conflict (id) do update set
+  schedule = EXCLUDED.schedule,
+  keep_last = EXCLUDED.keep_last,
+  keep_daily = EXCLUDED.keep_daily,
+  keep_weekly = EXCLUDED.keep_weekly,
+  keep_monthly = EXCLUDED.keep_monthly,
+  updated_at = EXCLUDED.updated_at`
+	_, err := db.Exec(query, id, cronSpec, retention.KeepLast, retention.KeepDaily, retention.KeepWeekly, retention.KeepMonthly, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert schedule")
+	}
+
+	return nil
+}
+
+// DeleteSchedule disables scheduled snapshots. Backups that were already taken are kept.
+func DeleteSchedule(id string) error {
+	db := persistence.MustGetPGSession()
+	query := `delete from snapshot_schedule where id = $1`
+	_, err := db.Exec(query, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete schedule")
+	}
+
+	return nil
+}
+
+// MigrateAppSchedules moves the schedules that were saved on the app table into snapshot_schedule.
+// Apps that already have a schedule keep it.
+func MigrateAppSchedules() error {
+	db := persistence.MustGetPGSession()
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	query := `insert into snapshot_schedule (id, schedule, updated_at)
+select id, snapshot_schedule, $1 from app where snapshot_schedule is not null and snapshot_schedule != ''
+on conflict (id) do nothing`
+	if _, err := tx.Exec(query, time.Now()); err != nil {
+		return errors.Wrap(err, "failed to copy app schedules")
+	}
+
+	query = `update app set snapshot_schedule = null where snapshot_schedule is not null`
+	if _, err := tx.Exec(query); err != nil {
+		return errors.Wrap(err, "failed to clear app schedules")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
@@ -92,135 +94,55 @@ func UpdateGlobalStore(store *types.Store) (*velerov1.BackupStorageLocation, err
 				}
 			}
 		} else {
-			awsCfg := ini.Empty()
-			section, err := awsCfg.NewSection("default")
+			awsCredentials, err := renderCredentialsFile(store.AWS.AccessKeyID, store.AWS.SecretAccessKey)
 			if err != nil {
-				return nil, errors.Wrap(err, "failed to create default section in aws creds")
+				return nil, errors.Wrap(err, "failed to render aws credentials")
 			}
-			_, err = section.NewKey("aws_access_key_id", store.AWS.AccessKeyID)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to create access key")
-			}
-
-			_, err = section.NewKey("aws_secret_access_key", store.AWS.SecretAccessKey)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to create secret access key")
-			}
-
-			var awsCredentials bytes.Buffer
-			writer := bufio.NewWriter(&awsCredentials)
-			_, err = awsCfg.WriteTo(writer)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to write ini")
-			}
-			if err := writer.Flush(); err != nil {
-				return nil, errors.Wrap(err, "failed to flush buffer")
-			}
-
-			// create or update the secret
-			if kuberneteserrors.IsNotFound(currentSecretErr) {
-				// create
-				toCreate := corev1.Secret{
-					TypeMeta: metav1.TypeMeta{
-						APIVersion: "v1",
-						Kind:       "Secret",
-					},
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "cloud-credentials",
-						Namespace: kotsadmVeleroBackendStorageLocation.Namespace,
-					},
-					Data: map[string][]byte{
-						"cloud": awsCredentials.Bytes(),
-					},
-				}
-				_, err = clientset.CoreV1().Secrets(kotsadmVeleroBackendStorageLocation.Namespace).Create(context.TODO(), &toCreate, metav1.CreateOptions{})
-				if err != nil {
-					return nil, errors.Wrap(err, "failed to create aws secret")
-				}
-			} else {
-				// update
-				if currentSecret.Data == nil {
-					currentSecret.Data = map[string][]byte{}
-				}
-
-				currentSecret.Data["cloud"] = awsCredentials.Bytes()
-				_, err = clientset.CoreV1().Secrets(kotsadmVeleroBackendStorageLocation.Namespace).Update(context.TODO(), currentSecret, metav1.UpdateOptions{})
-				if err != nil {
-					return nil, errors.Wrap(err, "failed to update aws secret")
-				}
+			if err := writeCloudCredentials(clientset, kotsadmVeleroBackendStorageLocation.Namespace, currentSecret, currentSecretErr, awsCredentials); err != nil {
+				return nil, errors.Wrap(err, "failed to write aws secret")
 			}
 		}
 	} else if store.Other != nil {
 		kotsadmVeleroBackendStorageLocation.Spec.Config = map[string]string{
 			"region": store.Other.Region,
-			"s3Url": store.Other.Endpoint,
+			"s3Url":  store.Other.Endpoint,
 		}
 
-		// create or update the secret
+		otherCredentials, err := renderCredentialsFile(store.Other.AccessKeyID, store.Other.SecretAccessKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render other credentials")
+		}
+		if err := writeCloudCredentials(clientset, kotsadmVeleroBackendStorageLocation.Namespace, currentSecret, currentSecretErr, otherCredentials); err != nil {
+			return nil, errors.Wrap(err, "failed to write other secret")
+		}
 	} else if store.Internal != nil {
 		kotsadmVeleroBackendStorageLocation.Spec.Config = map[string]string{
-			"region": store.Internal.Region,
-			"s3Url": store.Internal.Endpoint,
-			"publicUrl": fmt.Sprintf("http://%s", store.Internal.ObjectStoreClusterIP),
+			"region":           store.Internal.Region,
+			"s3Url":            store.Internal.Endpoint,
+			"publicUrl":        fmt.Sprintf("http://%s", store.Internal.ObjectStoreClusterIP),
 			"s3ForcePathStyle": "true",
 		}
 
-		internalCfg := ini.Empty()
-		section, err := internalCfg.NewSection("default")
+		internalCredentials, err := renderCredentialsFile(store.Internal.AccessKeyID, store.Internal.SecretAccessKey)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create default section in internal creds")
+			return nil, errors.Wrap(err, "failed to render internal credentials")
 		}
-		_, err = section.NewKey("aws_access_key_id", store.Internal.AccessKeyID)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create internal access key id")
+		if err := writeCloudCredentials(clientset, kotsadmVeleroBackendStorageLocation.Namespace, currentSecret, currentSecretErr, internalCredentials); err != nil {
+			return nil, errors.Wrap(err, "failed to write internal secret")
 		}
-
-		_, err = section.NewKey("aws_secret_access_key", store.Internal.SecretAccessKey)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create internal secret access key")
+	} else if store.FileSystem != nil {
+		kotsadmVeleroBackendStorageLocation.Spec.Config = map[string]string{
+			"region":           FileSystemMinioRegion,
+			"s3Url":            store.FileSystem.Endpoint,
+			"s3ForcePathStyle": "true",
 		}
 
-		var internalCredentials bytes.Buffer
-		writer := bufio.NewWriter(&internalCredentials)
-		_, err = internalCfg.WriteTo(writer)
+		fileSystemCredentials, err := renderCredentialsFile(store.FileSystem.AccessKeyID, store.FileSystem.SecretAccessKey)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to write ini")
+			return nil, errors.Wrap(err, "failed to render file system credentials")
 		}
-		if err := writer.Flush(); err != nil {
-			return nil, errors.Wrap(err, "failed to flush buffer")
-		}
-
-		// create or update the secret
-		if kuberneteserrors.IsNotFound(currentSecretErr) {
-			// create
-			toCreate := corev1.Secret{
-				TypeMeta: metav1.TypeMeta{
-					APIVersion: "v1",
-					Kind:       "Secret",
-				},
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "cloud-credentials",
-					Namespace: kotsadmVeleroBackendStorageLocation.Namespace,
-				},
-				Data: map[string][]byte{
-					"cloud": internalCredentials.Bytes(),
-				},
-			}
-			_, err = clientset.CoreV1().Secrets(kotsadmVeleroBackendStorageLocation.Namespace).Create(context.TODO(), &toCreate, metav1.CreateOptions{})
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to create internal secret")
-			}
-		} else {
-			// update
-			if currentSecret.Data == nil {
-				currentSecret.Data = map[string][]byte{}
-			}
-
-			currentSecret.Data["cloud"] = internalCredentials.Bytes()
-			_, err = clientset.CoreV1().Secrets(kotsadmVeleroBackendStorageLocation.Namespace).Update(context.TODO(), currentSecret, metav1.UpdateOptions{})
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to update internal secret")
-			}
+		if err := writeCloudCredentials(clientset, kotsadmVeleroBackendStorageLocation.Namespace, currentSecret, currentSecretErr, fileSystemCredentials); err != nil {
+			return nil, errors.Wrap(err, "failed to write file system secret")
 		}
 	} else if store.Google != nil {
 		if store.Google.UseInstanceRole {
@@ -325,6 +247,72 @@ func UpdateGlobalStore(store *types.Store) (*velerov1.BackupStorageLocation, err
 	return updated, nil
 }
 
+// renderCredentialsFile renders the aws credentials file that velero uses for S3-compatible stores
+func renderCredentialsFile(accessKeyID string, secretAccessKey string) ([]byte, error) {
+	credentialsCfg := ini.Empty()
+	section, err := credentialsCfg.NewSection("default")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create default section in creds")
+	}
+	_, err = section.NewKey("aws_access_key_id", accessKeyID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create access key id")
+	}
+
+	_, err = section.NewKey("aws_secret_access_key", secretAccessKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create secret access key")
+	}
+
+	var credentialsFile bytes.Buffer
+	writer := bufio.NewWriter(&credentialsFile)
+	_, err = credentialsCfg.WriteTo(writer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write ini")
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, errors.Wrap(err, "failed to flush buffer")
+	}
+
+	return credentialsFile.Bytes(), nil
+}
+
+// writeCloudCredentials creates the cloud-credentials secret, or updates currentSecret when it exists
+func writeCloudCredentials(clientset kubernetes.Interface, namespace string, currentSecret *corev1.Secret, currentSecretErr error, cloud []byte) error {
+	if kuberneteserrors.IsNotFound(currentSecretErr) {
+		toCreate := corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Secret",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cloud-credentials",
+				Namespace: namespace,
+			},
+			Data: map[string][]byte{
+				"cloud": cloud,
+			},
+		}
+		_, err := clientset.CoreV1().Secrets(namespace).Create(context.TODO(), &toCreate, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to create secret")
+		}
+		return nil
+	}
+
+	if currentSecret.Data == nil {
+		currentSecret.Data = map[string][]byte{}
+	}
+
+	currentSecret.Data["cloud"] = cloud
+	_, err := clientset.CoreV1().Secrets(namespace).Update(context.TODO(), currentSecret, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to update secret")
+	}
+
+	return nil
+}
+
 // GetGlobalStore will return the global store from kotsadmVeleroBackupStorageLocation
 // or will find it, is the param is nil
 func GetGlobalStore(kotsadmVeleroBackendStorageLocation *velerov1.BackupStorageLocation) (*types.Store, error) {
@@ -382,7 +370,17 @@ func GetGlobalStore(kotsadmVeleroBackendStorageLocation *velerov1.BackupStorageL
 			if err != nil {
 				return nil, errors.Wrap(err, "failed to get s3 secret")
 			}
-			if s3Secret != nil && string(s3Secret.Data["endpoint"]) == endpoint {
+			if endpoint == FileSystemMinioEndpoint(os.Getenv("POD_NAMESPACE")) {
+				fileSystem, err := getFileSystemConfig(clientset, os.Getenv("POD_NAMESPACE"))
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to get file system config")
+				}
+				if fileSystem == nil {
+					fileSystem = &types.StoreFileSystem{}
+				}
+				fileSystem.Endpoint = endpoint
+				store.FileSystem = fileSystem
+			} else if s3Secret != nil && string(s3Secret.Data["endpoint"]) == endpoint {
 				store.Internal = &types.StoreInternal{
 					Region:               kotsadmVeleroBackendStorageLocation.Spec.Config["region"],
 					Endpoint:             endpoint,
@@ -417,7 +415,10 @@ func GetGlobalStore(kotsadmVeleroBackendStorageLocation *velerov1.BackupStorageL
 
 			for _, section := range awsCfg.Sections() {
 				if section.Name() == "default" {
-					if store.Internal != nil {
+					if store.FileSystem != nil {
+						store.FileSystem.AccessKeyID = section.Key("aws_access_key_id").Value()
+						store.FileSystem.SecretAccessKey = section.Key("aws_secret_access_key").Value()
+					} else if store.Internal != nil {
 						store.Internal.AccessKeyID = section.Key("aws_access_key_id").Value()
 						store.Internal.SecretAccessKey = section.Key("aws_secret_access_key").Value()
 					} else if store.Other != nil {
@@ -547,6 +548,13 @@ func ValidateStore(store *types.Store) error {
 		return nil
 	}
 
+	if store.FileSystem != nil {
+		if err := validateFileSystem(store.FileSystem, store.Bucket); err != nil {
+			return errors.Wrap(err, "failed to validate file system configuration")
+		}
+		return nil
+	}
+
 	return errors.New("no valid configuration found")
 }
 
@@ -724,6 +732,45 @@ func validateInternal(storeInternal *types.StoreInternal, bucket string) error {
 	return nil
 }
 
+// validateFileSystem checks that the minio server in front of the file system is reachable,
+// and creates the bucket the first time a share is used
+func validateFileSystem(storeFileSystem *types.StoreFileSystem, bucket string) error {
+	if err := ValidateFileSystemConfig(storeFileSystem); err != nil {
+		return errors.Wrap(err, "invalid file system")
+	}
+
+	s3Config := &aws.Config{
+		Region:           aws.String(FileSystemMinioRegion),
+		Endpoint:         aws.String(storeFileSystem.Endpoint),
+		DisableSSL:       aws.Bool(true),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials(storeFileSystem.AccessKeyID, storeFileSystem.SecretAccessKey, ""),
+	}
+
+	newSession := session.New(s3Config)
+	s3Client := s3.New(newSession)
+
+	_, err := s3Client.HeadBucket(&s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err == nil {
+		return nil
+	}
+
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NotFound" {
+		return errors.Wrap(err, "failed to access file system")
+	}
+
+	_, err = s3Client.CreateBucket(&s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create bucket on file system")
+	}
+
+	return nil
+}
+
 func Redact(store *types.Store) error {
 	if store == nil {
 		return nil
@@ -759,5 +806,11 @@ func Redact(store *types.Store) error {
 		}
 	}
 
+	if store.FileSystem != nil {
+		if store.FileSystem.SecretAccessKey != "" {
+			store.FileSystem.SecretAccessKey = "--- REDACTED ---"
+		}
+	}
+
 	return nil
 }
@@ -40,15 +40,70 @@ type StoreInternal struct {
 	ObjectStoreClusterIP string `json:"objectStoreClusterIP"`
 }
 
+// StoreFileSystem is an NFS share or a directory on the nodes that a minio server
+// in the kotsadm namespace serves to velero as an S3-compatible store
+type StoreFileSystem struct {
+	NFS             *StoreNFS `json:"nfs,omitempty"`
+	HostPath        string    `json:"hostPath,omitempty"`
+	AccessKeyID     string    `json:"accessKeyID"`
+	SecretAccessKey string    `json:"secretAccessKey"` // added for unmarshaling, redacted on marshaling
+	Endpoint        string    `json:"endpoint"`
+}
+
+type StoreNFS struct {
+	Server string `json:"server"`
+	Path   string `json:"path"`
+}
+
 type Store struct {
-	Provider string         `json:"provider"`
-	Bucket   string         `json:"bucket"`
-	Path     string         `json:"path"`
-	AWS      *StoreAWS      `json:"aws,omitempty"`
-	Azure    *StoreAzure    `json:"azure,omitempty"`
-	Google   *StoreGoogle   `json:"gcp,omitempty"`
-	Other    *StoreOther    `json:"other,omitempty"`
-	Internal *StoreInternal `json:"internal,omitempty"`
+	Provider   string           `json:"provider"`
+	Bucket     string           `json:"bucket"`
+	Path       string           `json:"path"`
+	AWS        *StoreAWS        `json:"aws,omitempty"`
+	Azure      *StoreAzure      `json:"azure,omitempty"`
+	Google     *StoreGoogle     `json:"gcp,omitempty"`
+	Other      *StoreOther      `json:"other,omitempty"`
+	Internal   *StoreInternal   `json:"internal,omitempty"`
+	FileSystem *StoreFileSystem `json:"fileSystem,omitempty"`
+}
+
+// DeepCopy returns a copy of the store that shares no pointers with it
+func (s *Store) DeepCopy() *Store {
+	if s == nil {
+		return nil
+	}
+
+	out := *s
+	if s.AWS != nil {
+		aws := *s.AWS
+		out.AWS = &aws
+	}
+	if s.Azure != nil {
+		azure := *s.Azure
+		out.Azure = &azure
+	}
+	if s.Google != nil {
+		google := *s.Google
+		out.Google = &google
+	}
+	if s.Other != nil {
+		other := *s.Other
+		out.Other = &other
+	}
+	if s.Internal != nil {
+		internal := *s.Internal
+		out.Internal = &internal
+	}
+	if s.FileSystem != nil {
+		fileSystem := *s.FileSystem
+		if s.FileSystem.NFS != nil {
+			nfs := *s.FileSystem.NFS
+			fileSystem.NFS = &nfs
+		}
+		out.FileSystem = &fileSystem
+	}
+
+	return &out
 }
 
 type Backup struct {
@@ -67,6 +122,64 @@ type Backup struct {
 	SupportBundleID    string     `json:"supportBundleId,omitempty"`
 }
 
+// SnapshotSchedule is a cron schedule for backups of an app, or of every app and the admin console
+type SnapshotSchedule struct {
+	ID        string    `json:"id"`
+	Schedule  string    `json:"schedule"`
+	Retention Retention `json:"retention"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Retention rules decide which scheduled backups are kept. A backup is kept when any rule keeps it,
+// and backups are never pruned when no rule is set.
+type Retention struct {
+	KeepLast    int `json:"keepLast"`
+	KeepDaily   int `json:"keepDaily"`
+	KeepWeekly  int `json:"keepWeekly"`
+	KeepMonthly int `json:"keepMonthly"`
+}
+
+func (r Retention) IsEmpty() bool {
+	return r.KeepLast == 0 && r.KeepDaily == 0 && r.KeepWeekly == 0 && r.KeepMonthly == 0
+}
+
+// PreUpgradeSnapshot is the backup of the deployed version of an app that was taken before the
+// version with the sequence was deployed over it
+type PreUpgradeSnapshot struct {
+	AppID            string     `json:"appId"`
+	Sequence         int64      `json:"sequence"`
+	PreviousSequence int64      `json:"previousSequence"`
+	BackupName       string     `json:"backupName,omitempty"`
+	Status           string     `json:"status"`
+	Error            string     `json:"error,omitempty"`
+	OnFailure        string     `json:"onFailure"`
+	StartedAt        time.Time  `json:"startedAt"`
+	Deadline         time.Time  `json:"deadline"`
+	FinishedAt       *time.Time `json:"finishedAt,omitempty"`
+}
+
+// RestoreOptions limit what's restored from a backup, the zero value restores everything
+type RestoreOptions struct {
+	// Namespaces restores only the resources in these namespaces
+	Namespaces []string `json:"namespaces,omitempty"`
+	// LabelSelector restores only the resources with matching labels, e.g. "app=postgres"
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// Resources restores only these kinds, e.g. "configmaps" or "deployments.apps"
+	Resources []string `json:"resources,omitempty"`
+	// NamespaceMapping restores the resources of a namespace into another one, leaving the deployed
+	// app in place
+	NamespaceMapping map[string]string `json:"namespaceMapping,omitempty"`
+	// VolumesOnly restores only the persistent volume claims and their volumes
+	VolumesOnly bool `json:"volumesOnly,omitempty"`
+	// KotsadmOnly restores only the admin console
+	KotsadmOnly bool `json:"kotsadmOnly,omitempty"`
+}
+
+// IsPartial returns true if the options restore less than the whole backup
+func (o RestoreOptions) IsPartial() bool {
+	return len(o.Namespaces) > 0 || o.LabelSelector != "" || len(o.Resources) > 0 || o.VolumesOnly || o.KotsadmOnly
+}
+
 type BackupDetail struct {
 	Name            string           `json:"name"`
 	Status          string           `json:"status"`
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "go.undefinedlabs.com/scopeagent/autoinstrument"
+)
+
+func Test_StoreDeepCopy(t *testing.T) {
+	store := &Store{
+		Provider: "aws",
+		Bucket:   "velero",
+		AWS:      &StoreAWS{Region: "us-east-1"},
+		FileSystem: &StoreFileSystem{
+			NFS: &StoreNFS{Server: "10.0.0.5", Path: "/exports/backups"},
+		},
+	}
+
+	copied := store.DeepCopy()
+	assert.Equal(t, store, copied)
+
+	copied.AWS.Region = "us-west-2"
+	copied.FileSystem.NFS.Path = "/exports/other"
+	assert.Equal(t, "us-east-1", store.AWS.Region)
+	assert.Equal(t, "/exports/backups", store.FileSystem.NFS.Path)
+
+	assert.Nil(t, (*Store)(nil).DeepCopy())
+}
@@ -0,0 +1,171 @@
+package snapshotscheduler
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/app"
+	"github.com/replicatedhq/kots/kotsadm/pkg/logger"
+	"github.com/replicatedhq/kots/kotsadm/pkg/snapshot"
+	cron "github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// jobs maps schedule ids to their cron jobs
+var jobs = make(map[string]*cron.Cron)
+var mtx sync.Mutex
+
+// Start will start a cron job for every snapshot schedule
+func Start() error {
+	logger.Debug("starting snapshot scheduler")
+
+	if err := snapshot.MigrateAppSchedules(); err != nil {
+		return errors.Wrap(err, "failed to migrate app schedules")
+	}
+
+	schedules, err := snapshot.ListSchedules()
+	if err != nil {
+		return errors.Wrap(err, "failed to list schedules")
+	}
+
+	for _, schedule := range schedules {
+		if err := Configure(schedule.ID); err != nil {
+			logger.Error(errors.Wrapf(err, "failed to configure snapshot schedule %s", schedule.ID))
+		}
+	}
+
+	return nil
+}
+
+// Configure will check if the schedule exists and:
+// if it exists, and cron job was NOT found: add a new cron job to take snapshots
+// if it exists, and a cron job was found, update the existing cron job with the latest cron spec
+// if it does not exist: stop the current running cron job (if exists)
+func Configure(scheduleID string) error {
+	schedule, err := snapshot.GetSchedule(scheduleID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get schedule")
+	}
+
+	logger.Debug("configure snapshot scheduler",
+		zap.String("scheduleID", scheduleID))
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if schedule == nil || schedule.Schedule == "" {
+		Stop(scheduleID)
+		return nil
+	}
+
+	job, ok := jobs[scheduleID]
+	if ok {
+		// job already exists, remove entries
+		entries := job.Entries()
+		for _, entry := range entries {
+			job.Remove(entry.ID)
+		}
+	} else {
+		// job does not exist, create a new one
+		job = cron.New(cron.WithChain(
+			cron.Recover(cron.DefaultLogger),
+			cron.SkipIfStillRunning(cron.DefaultLogger),
+		))
+	}
+
+	jobScheduleID := scheduleID
+	_, err = job.AddFunc(schedule.Schedule, func() {
+		if err := takeSnapshot(jobScheduleID); err != nil {
+			logger.Error(errors.Wrapf(err, "failed to take scheduled snapshot %s", jobScheduleID))
+		}
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to add func")
+	}
+
+	job.Start()
+	jobs[scheduleID] = job
+
+	return nil
+}
+
+// Stop will stop a running cron job (if exists) for a specific schedule
+func Stop(scheduleID string) {
+	if jobs == nil {
+		logger.Debug("no cron jobs found")
+		return
+	}
+	if job, ok := jobs[scheduleID]; ok {
+		job.Stop()
+	} else {
+		logger.Debug("cron job not found for snapshot schedule", zap.String("scheduleID", scheduleID))
+	}
+}
+
+// takeSnapshot backs up the apps of the schedule and then prunes the backups that its retention
+// rules no longer keep. It's postponed while a restore is in progress or a previous backup of the
+// schedule has not finished.
+func takeSnapshot(scheduleID string) error {
+	// the retention rules may have changed since the job was configured
+	schedule, err := snapshot.GetSchedule(scheduleID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get schedule")
+	}
+	if schedule == nil {
+		return nil
+	}
+
+	restoring, err := isRestoreInProgress(scheduleID)
+	if err != nil {
+		return errors.Wrap(err, "failed to check for restore in progress")
+	}
+	if restoring {
+		logger.Debug("postponing scheduled snapshot because a restore is in progress",
+			zap.String("scheduleID", scheduleID))
+		return nil
+	}
+
+	unfinished, err := snapshot.HasUnfinishedScheduledBackup(scheduleID)
+	if err != nil {
+		return errors.Wrap(err, "failed to check for unfinished backups")
+	}
+	if unfinished {
+		logger.Debug("postponing scheduled snapshot because one is in progress",
+			zap.String("scheduleID", scheduleID))
+		return nil
+	}
+
+	logger.Debug("taking scheduled snapshot", zap.String("scheduleID", scheduleID))
+
+	if err := snapshot.CreateScheduledBackup(schedule); err != nil {
+		return errors.Wrap(err, "failed to create backup")
+	}
+
+	if err := snapshot.PruneScheduledBackups(schedule); err != nil {
+		return errors.Wrap(err, "failed to prune backups")
+	}
+
+	return nil
+}
+
+func isRestoreInProgress(scheduleID string) (bool, error) {
+	if scheduleID != snapshot.InstanceScheduleID {
+		a, err := app.Get(scheduleID)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get app")
+		}
+		return a.RestoreInProgressName != "", nil
+	}
+
+	apps, err := app.ListInstalled()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list installed apps")
+	}
+	for _, a := range apps {
+		if a.RestoreInProgressName != "" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,41 @@
+package supportbundle
+
+import (
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/kotsadm/pkg/persistence"
+	"github.com/replicatedhq/kots/kotsadm/pkg/supportbundle/types"
+)
+
+// ListPendingForCluster lists the support bundles that were requested from the admin console,
+// but not yet sent to the cluster's operator to collect
+func ListPendingForCluster(clusterID string) ([]*types.PendingSupportBundle, error) {
+	db := persistence.MustGetPGSession()
+	query := `select id, app_id, cluster_id from pending_supportbundle where cluster_id = $1`
+	rows, err := db.Query(query, clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query pending support bundles")
+	}
+	defer rows.Close()
+
+	pendingSupportBundles := []*types.PendingSupportBundle{}
+	for rows.Next() {
+		pendingSupportBundle := types.PendingSupportBundle{}
+		if err := rows.Scan(&pendingSupportBundle.ID, &pendingSupportBundle.AppID, &pendingSupportBundle.ClusterID); err != nil {
+			return nil, errors.Wrap(err, "failed to scan pending support bundle")
+		}
+		pendingSupportBundles = append(pendingSupportBundles, &pendingSupportBundle)
+	}
+
+	return pendingSupportBundles, nil
+}
+
+func ClearPending(id string) error {
+	db := persistence.MustGetPGSession()
+	query := `delete from pending_supportbundle where id = $1`
+	_, err := db.Exec(query, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete pending support bundle")
+	}
+
+	return nil
+}
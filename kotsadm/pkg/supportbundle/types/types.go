@@ -13,3 +13,9 @@ type FileTreeNode struct {
 	Path     string         `json:"path"`
 	Children []FileTreeNode `json:"children,omitempty"`
 }
+
+type PendingSupportBundle struct {
+	ID        string
+	AppID     string
+	ClusterID string
+}
@@ -61,3 +61,21 @@ func GetTaskStatus(id string) (string, error) {
 
 	return status, nil
 }
+
+func GetTaskStatusWithMessage(id string) (string, string, error) {
+	db := persistence.MustGetPGSession()
+	query := `select status, current_message from api_task_status where id = $1 AND updated_at > ($2::timestamp - '10 seconds'::interval)`
+
+	row := db.QueryRow(query, id, time.Now())
+	status := ""
+	message := sql.NullString{}
+	if err := row.Scan(&status, &message); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", nil
+		}
+
+		return "", "", errors.Wrap(err, "failed to scan task status")
+	}
+
+	return status, message.String, nil
+}
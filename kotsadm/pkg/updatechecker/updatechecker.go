@@ -98,10 +98,11 @@ func Configure(appID string) error {
 
 	jobAppID := a.ID
 	jobAppSlug := a.Slug
+	jobVersionConstraint := a.UpdateCheckerVersionConstraint
 	_, err = job.AddFunc(cronSpec, func() {
 		logger.Debug("checking updates for app", zap.String("slug", jobAppSlug))
 
-		availableUpdates, err := CheckForUpdates(jobAppID, false)
+		availableUpdates, err := CheckForUpdates(jobAppID, false, jobVersionConstraint)
 		if err != nil {
 			logger.Error(errors.Wrapf(err, "failed to check updates for app %s", jobAppSlug))
 			return
@@ -140,8 +141,9 @@ func Stop(appID string) {
 
 // CheckForUpdates checks (and downloads) latest updates for a specific app
 // if "deploy" is set to true, the latest version/update will be deployed
+// if "versionConstraint" is set, only updates with a version label in that semver range are downloaded
 // returns the number of available updates
-func CheckForUpdates(appID string, deploy bool) (int64, error) {
+func CheckForUpdates(appID string, deploy bool, versionConstraint string) (int64, error) {
 	currentStatus, err := task.GetTaskStatus("update-download")
 	if err != nil {
 		return 0, errors.Wrap(err, "failed to get task status")
@@ -186,10 +188,11 @@ func CheckForUpdates(appID string, deploy bool) (int64, error) {
 	}
 
 	getUpdatesOptions := kotspull.GetUpdatesOptions{
-		LicenseFile:    filepath.Join(archiveDir, "upstream", "userdata", "license.yaml"),
-		CurrentCursor:  kotsKinds.Installation.Spec.UpdateCursor,
-		CurrentChannel: kotsKinds.Installation.Spec.ChannelName,
-		Silent:         false,
+		LicenseFile:       filepath.Join(archiveDir, "upstream", "userdata", "license.yaml"),
+		CurrentCursor:     kotsKinds.Installation.Spec.UpdateCursor,
+		CurrentChannel:    kotsKinds.Installation.Spec.ChannelName,
+		VersionConstraint: versionConstraint,
+		Silent:            false,
 	}
 
 	updates, err := kotspull.GetUpdates(fmt.Sprintf("replicated://%s", kotsKinds.License.Spec.AppSlug), getUpdatesOptions)
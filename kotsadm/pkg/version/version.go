@@ -1,6 +1,7 @@
 package version
 
 import (
+	"database/sql"
 	"encoding/json"
 	"time"
 
@@ -275,6 +276,16 @@ func GetVersions(appID string) ([]types.AppVersion, error) {
 
 // DeployVersion deploys the version for the given sequence
 func DeployVersion(appID string, sequence int64) error {
+	return deployVersion(appID, sequence, false)
+}
+
+// DeployVersionWithoutSnapshot deploys the sequence without gating it on a pre-upgrade snapshot,
+// this is used to redeploy a version that was just restored
+func DeployVersionWithoutSnapshot(appID string, sequence int64) error {
+	return deployVersion(appID, sequence, true)
+}
+
+func deployVersion(appID string, sequence int64, skipPreUpgradeSnapshot bool) error {
 	db := persistence.MustGetPGSession()
 
 	tx, err := db.Begin()
@@ -283,6 +294,17 @@ func DeployVersion(appID string, sequence int64) error {
 	}
 	defer tx.Rollback()
 
+	if skipPreUpgradeSnapshot {
+		_, err := tx.Exec(`delete from pre_upgrade_snapshot where app_id = $1 and sequence = $2`, appID, sequence)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete pre-upgrade snapshot")
+		}
+	} else {
+		if err := recordPendingPreUpgradeSnapshot(tx, appID, sequence); err != nil {
+			return errors.Wrap(err, "failed to record pending pre-upgrade snapshot")
+		}
+	}
+
 	query := `update app_downstream set current_sequence = $1 where app_id = $2`
 	_, err = tx.Exec(query, sequence, appID)
 	if err != nil {
@@ -301,3 +323,42 @@ func DeployVersion(appID string, sequence int64) error {
 
 	return nil
 }
+
+// recordPendingPreUpgradeSnapshot records that the deployed version may need to be backed up before the
+// sequence is deployed over it, the operator checks the pre-upgrade snapshot policy of the app. Deploying
+// a version whose snapshot failed again retries the snapshot.
+func recordPendingPreUpgradeSnapshot(tx *sql.Tx, appID string, sequence int64) error {
+	var deployedSequence sql.NullInt64
+	row := tx.QueryRow(`select max(current_sequence) from app_downstream where app_id = $1`, appID)
+	if err := row.Scan(&deployedSequence); err != nil {
+		return errors.Wrap(err, "failed to scan deployed sequence")
+	}
+
+	if deployedSequence.Valid && deployedSequence.Int64 != sequence {
+		query := `insert into pre_upgrade_snapshot (app_id, sequence, previous_sequence, status, started_at)
+values ($1, $2, $3, 'pending', $4)
+on conflict (app_id, sequence) do update set
+  previous_sequence = EXCLUDED.previous_sequence,
+  backup_name = null,
+  status = EXCLUDED.status,
+  error = null,
+  on_failure = null,
+  started_at = EXCLUDED.started_at,
+  deadline = null,
+  finished_at = null`
+		_, err := tx.Exec(query, appID, sequence, deployedSequence.Int64, time.Now())
+		if err != nil {
+			return errors.Wrap(err, "failed to insert pre-upgrade snapshot")
+		}
+		return nil
+	}
+
+	query := `update pre_upgrade_snapshot set backup_name = null, status = 'pending', error = null, on_failure = null, started_at = $3, deadline = null, finished_at = null
+where app_id = $1 and sequence = $2 and status = 'failed'`
+	_, err := tx.Exec(query, appID, sequence, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "failed to reset pre-upgrade snapshot")
+	}
+
+	return nil
+}
@@ -88,6 +88,19 @@ class AppConfig extends Component {
     this.setState({ configGroups });
   }
 
+  markInvalidItems = validationErrors => {
+    const configGroups = this.state.configGroups;
+    validationErrors.forEach(validationError => {
+      configGroups.forEach(configGroup => {
+        const item = configGroup.items.find(item => item.name === validationError.name);
+        if (item) {
+          item.error = validationError.message;
+        }
+      });
+    });
+    this.setState({ configGroups });
+  }
+
   handleSave = async () => {
     this.setState({ savingConfig: true, savingConfigError: "" });
 
@@ -116,6 +129,9 @@ class AppConfig extends Component {
           if (result.requiredItems?.length) {
             this.markRequiredItems(result.requiredItems);
           }
+          if (result.validationErrors?.length) {
+            this.markInvalidItems(result.validationErrors);
+          }
           if (result.error) {
             this.setState({ savingConfigError: result.error });
           }
@@ -2,7 +2,7 @@ import React, { Component } from "react";
 import Select from "react-select";
 import { graphql, compose, withApollo } from "react-apollo";
 import { Link, withRouter } from "react-router-dom"
-import { getCronFrequency, getCronInterval, getReadableCronDescriptor } from "../../utilities/utilities";
+import { Utilities, getCronFrequency, getCronInterval, getReadableCronDescriptor } from "../../utilities/utilities";
 import { snapshotConfig } from "../../queries/SnapshotQueries";
 import { saveSnapshotConfig } from "../../mutations/SnapshotMutations";
 import find from "lodash/find";
@@ -50,6 +50,7 @@ class AppSnapshotSchedule extends Component {
   state = {
     retentionInput: "",
     autoEnabled: false,
+    retention: {},
     selectedSchedule: {},
     selectedRetentionUnit: {},
     frequency: "",
@@ -61,22 +62,62 @@ class AppSnapshotSchedule extends Component {
     const { snapshotConfig } = this.props;
     if (snapshotConfig.snapshotConfig) {
       this.setState({
-        autoEnabled: snapshotConfig.snapshotConfig.autoEnabled,
         retentionInput: snapshotConfig.snapshotConfig.ttl.inputValue,
         selectedRetentionUnit: find(RETENTION_UNITS, ["value", snapshotConfig.snapshotConfig.ttl.inputTimeUnit]),
-        selectedSchedule: find(SCHEDULES, ["value", getCronInterval(snapshotConfig.snapshotConfig.autoSchedule.schedule)]),
-        frequency: snapshotConfig.snapshotConfig.autoSchedule.schedule,
-      }, () => this.getReadableCronExpression());
+      });
     } else {
       this.setState({
         retentionInput: "4",
         selectedRetentionUnit: find(RETENTION_UNITS, ["value", "weeks"]),
-        selectedSchedule: find(SCHEDULES, ["value", "weekly"]),
-        frequency: "0 0 * * MON",
-      }, () => this.getReadableCronExpression())
+      });
     }
   }
 
+  fetchSchedule = () => {
+    const { match } = this.props;
+    fetch(`${window.env.API_ENDPOINT}/app/${match.params.slug}/snapshot/schedule`, {
+      headers: {
+        "Authorization": Utilities.getToken(),
+        "Content-Type": "application/json",
+      },
+      method: "GET",
+    })
+      .then(res => res.json())
+      .then(response => {
+        const frequency = response.schedule || "0 0 * * MON";
+        this.setState({
+          autoEnabled: !!response.schedule,
+          retention: response.retention || {},
+          selectedSchedule: find(SCHEDULES, ["value", getCronInterval(frequency)]),
+          frequency,
+        }, () => this.getReadableCronExpression());
+      })
+      .catch(err => {
+        console.log(err);
+      });
+  }
+
+  saveSchedule = () => {
+    const { match } = this.props;
+    return fetch(`${window.env.API_ENDPOINT}/app/${match.params.slug}/snapshot/schedule`, {
+      headers: {
+        "Authorization": Utilities.getToken(),
+        "Content-Type": "application/json",
+      },
+      method: "PUT",
+      body: JSON.stringify({
+        schedule: this.state.autoEnabled ? this.state.frequency : "",
+        retention: this.state.retention,
+      }),
+    })
+      .then(async res => {
+        const response = await res.json();
+        if (!response.success) {
+          throw new Error(response.error);
+        }
+      });
+  }
+
   handleFormChange = (field, e) => {
     let nextState = {};
     if (field === "autoEnabled") {
@@ -125,7 +166,7 @@ class AppSnapshotSchedule extends Component {
   }
 
   componentDidMount = () => {
-    this.getReadableCronExpression();
+    this.fetchSchedule();
     if (this.props.snapshotConfig.snapshotConfig) {
       this.setFields();
     }
@@ -137,9 +178,8 @@ class AppSnapshotSchedule extends Component {
       this.props.app.id,
       this.state.retentionInput,
       this.state.selectedRetentionUnit?.value,
-      this.state.frequency,
-      this.state.autoEnabled,
-    ).then(() => {
+    ).then(() => this.saveSchedule())
+    .then(() => {
       this.setState({ updatingSchedule: false, updateConfirm: true });
       setTimeout(() => {
         this.setState({ updateConfirm: false })
@@ -147,6 +187,14 @@ class AppSnapshotSchedule extends Component {
     })
     .catch(err => {
       console.log(err);
+      if (!err.graphQLErrors) {
+        this.setState({
+          message: err.message,
+          messageType: "error",
+          updatingSchedule: false
+        });
+        return;
+      }
       err.graphQLErrors.map(({ msg }) => {
         this.setState({
           message: msg,
@@ -281,7 +329,7 @@ export default compose(
   }),
   graphql(saveSnapshotConfig, {
     props: ({ mutate }) => ({
-      saveSnapshotConfig: (appId, inputValue, inputTimeUnit, schedule, autoEnabled) => mutate({ variables: { appId, inputValue, inputTimeUnit, schedule, autoEnabled } })
+      saveSnapshotConfig: (appId, inputValue, inputTimeUnit) => mutate({ variables: { appId, inputValue, inputTimeUnit } })
     })
   })
 )(AppSnapshotSchedule);
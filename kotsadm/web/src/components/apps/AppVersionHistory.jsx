@@ -251,6 +251,19 @@ class AppVersionHistory extends Component {
     );
   }
 
+  renderPreUpgradeSnapshot = version => {
+    const snapshot = version.preUpgradeSnapshot;
+    if (!snapshot) {
+      return null;
+    }
+    const status = snapshot.backupName && snapshot.status === "completed" ?
+      <Link to={`/app/${this.props.match.params.slug}/snapshots/${snapshot.backupName}`} className="replicated-link">{snapshot.status}</Link> :
+      <span className="u-fontWeight--bold u-color--tuna" data-tip={snapshot.error || undefined}>{snapshot.status}</span>;
+    return (
+      <p className="u-fontSize--normal u-color--dustyGray u-marginTop--10">Pre-upgrade snapshot: {status}</p>
+    );
+  }
+
   renderVersionStatus = version => {
     const { app, match } = this.props;
     const downstream = app.downstreams?.length && app.downstreams[0];
@@ -1009,6 +1022,7 @@ class AppVersionHistory extends Component {
                             {this.renderVersionAction(version)}
                           </div>
                           <p className="u-fontSize--normal u-color--dustyGray u-marginTop--15">Deployed: <span className="u-fontWeight--bold u-color--tuna">{version.deployedAt ? moment(version.deployedAt).format("MM/DD/YY @ hh:mm a") : "N/A"}</span></p>
+                          {this.renderPreUpgradeSnapshot(version)}
                         </div>
                       </div>
                     );
@@ -1192,6 +1206,7 @@ class AppVersionHistory extends Component {
             isOpen={showUpdateCheckerModal}
             onRequestClose={this.hideUpdateCheckerModal}
             updateCheckerSpec={app.updateCheckerSpec}
+            updateCheckerVersionConstraint={app.updateCheckerVersionConstraint}
             appSlug={app.slug}
             gitopsEnabled={gitopsEnabled}
             onUpdateCheckerSpecSubmitted={() => {
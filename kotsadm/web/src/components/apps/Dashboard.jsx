@@ -655,6 +655,7 @@ class Dashboard extends Component {
             isOpen={this.state.showUpdateCheckerModal}
             onRequestClose={this.hideUpdateCheckerModal}
             updateCheckerSpec={app.updateCheckerSpec}
+            updateCheckerVersionConstraint={app.updateCheckerVersionConstraint}
             appSlug={app.slug}
             gitopsEnabled={downstream?.gitops?.enabled}
             onUpdateCheckerSpecSubmitted={() => {
@@ -38,13 +38,14 @@ export default class UpdateCheckerModal extends React.Component {
 
     this.state = {
       updateCheckerSpec: props.updateCheckerSpec,
+      versionConstraint: props.updateCheckerVersionConstraint || "",
       submitUpdateCheckerSpecErr: "",
       selectedSchedule,
     };
   }
 
   onSubmitUpdateCheckerSpec = () => {
-    const { updateCheckerSpec } = this.state;
+    const { updateCheckerSpec, versionConstraint } = this.state;
     const { appSlug } = this.props;
 
     this.setState({
@@ -59,6 +60,7 @@ export default class UpdateCheckerModal extends React.Component {
       method: "PUT",
       body: JSON.stringify({
         updateCheckerSpec: updateCheckerSpec,
+        versionConstraint: versionConstraint,
       })
     })
       .then(async (res) => {
@@ -114,7 +116,7 @@ export default class UpdateCheckerModal extends React.Component {
 
   render() {
     const { isOpen, onRequestClose, gitopsEnabled } = this.props;
-    const { updateCheckerSpec, selectedSchedule, submitUpdateCheckerSpecErr } = this.state;
+    const { updateCheckerSpec, versionConstraint, selectedSchedule, submitUpdateCheckerSpecErr } = this.state;
 
     const humanReadableCron = this.getReadableCronExpression(updateCheckerSpec);
 
@@ -180,6 +182,15 @@ export default class UpdateCheckerModal extends React.Component {
                 }
               </div>
             </div>
+            <p className="u-fontSize--normal u-color--tuna u-fontWeight--bold u-lineHeight--normal u-marginTop--20 u-marginBottom--10">Version constraint</p>
+            <input
+              type="text"
+              className="Input u-marginBottom--5"
+              placeholder=">=2.0 <3.0"
+              value={versionConstraint}
+              onChange={(e) => this.setState({ versionConstraint: e.target.value })}
+            />
+            <span className="u-fontSize--small u-fontWeight--medium u-color--dustyGray">Only download updates with a semver version in this range. Leave empty to download all updates.</span>
             {submitUpdateCheckerSpecErr && <span className="u-color--chestnut u-fontSize--small u-fontWeight--bold u-marginTop--15">Error: {submitUpdateCheckerSpecErr}</span>}
           </div>
           <div className="flex u-marginTop--20">
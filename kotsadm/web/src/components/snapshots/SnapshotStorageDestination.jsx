@@ -29,6 +29,10 @@ const DESTINATIONS = [
   {
     value: "internal",
     label: "Internal Storage (Default)",
+  },
+  {
+    value: "filesystem",
+    label: "NFS or Host Path",
   }
 ];
 
@@ -87,6 +91,11 @@ class SnapshotStorageDestination extends Component {
     s3CompatibleKeySecret: "",
     s3CompatibleEndpoint: "",
     s3CompatibleRegion: "",
+
+    fsUseNfs: true,
+    fsNfsServer: "",
+    fsNfsPath: "",
+    fsHostPath: "",
   };
 
   componentDidMount() {
@@ -265,6 +274,17 @@ class SnapshotStorageDestination extends Component {
       });
     }
 
+    if (store?.fileSystem) {
+      return this.setState({
+        determiningDestination: false,
+        selectedDestination: find(DESTINATIONS, ["value", "filesystem"]),
+        fsUseNfs: !store?.fileSystem?.hostPath,
+        fsNfsServer: store?.fileSystem?.nfs?.server || "",
+        fsNfsPath: store?.fileSystem?.nfs?.path || "",
+        fsHostPath: store?.fileSystem?.hostPath || ""
+      });
+    }
+
     // if nothing exists yet, we've determined default state is good
     this.setState({
       determiningDestination: false,
@@ -274,7 +294,7 @@ class SnapshotStorageDestination extends Component {
 
   handleFormChange = (field, e) => {
     let nextState = {};
-    if (field === "useIamAws" || field === "gcsUseIam") {
+    if (field === "useIamAws" || field === "gcsUseIam" || field === "fsUseNfs") {
       nextState[field] = e.target.checked;
     } else {
       nextState[field] = e.target.value;
@@ -312,6 +332,9 @@ class SnapshotStorageDestination extends Component {
       case "internal":
         await this.snapshotProviderInternal();
         break;
+      case "filesystem":
+        await this.snapshotProviderFileSystem();
+        break;
     }
   }
 
@@ -348,6 +371,14 @@ class SnapshotStorageDestination extends Component {
     this.props.updateSettings(payload);
   }
 
+  snapshotProviderFileSystem = async () => {
+    const fileSystem = this.state.fsUseNfs ?
+      { nfs: { server: this.state.fsNfsServer, path: this.state.fsNfsPath } } :
+      { hostPath: this.state.fsHostPath };
+    const payload = { fileSystem };
+    this.props.updateSettings(payload);
+  }
+
   renderIcons = (destination) => {
     if (destination) {
       return <span className={`icon snapshotDestination--${destination.value}`} />;
@@ -595,6 +626,49 @@ class SnapshotStorageDestination extends Component {
           null
         )
 
+      case "filesystem":
+        return (
+          <div>
+            <div className="flex u-marginBottom--30">
+              <div className="BoxedCheckbox-wrapper flex1 u-textAlign--left">
+                <div className={`BoxedCheckbox flex-auto flex alignItems--center ${this.state.fsUseNfs ? "is-active" : ""}`}>
+                  <input
+                    type="checkbox"
+                    className="u-cursor--pointer u-marginLeft--10"
+                    id="fsUseNfs"
+                    checked={this.state.fsUseNfs}
+                    onChange={(e) => { this.handleFormChange("fsUseNfs", e) }}
+                  />
+                  <label htmlFor="fsUseNfs" className="flex1 flex u-width--full u-position--relative u-cursor--pointer u-userSelect--none">
+                    <div className="flex1">
+                      <p className="u-color--tuna u-fontSize--normal u-fontWeight--medium">Use an NFS share</p>
+                    </div>
+                  </label>
+                </div>
+              </div>
+            </div>
+            {this.state.fsUseNfs ?
+              <div className="flex u-marginBottom--30">
+                <div className="flex1 u-paddingRight--5">
+                  <p className="u-fontSize--normal u-color--tuna u-fontWeight--bold u-lineHeight--normal u-marginBottom--10">Server</p>
+                  <input type="text" className="Input" placeholder="nfs.example.com" value={this.state.fsNfsServer} onChange={(e) => { this.handleFormChange("fsNfsServer", e) }} />
+                </div>
+                <div className="flex1 u-paddingLeft--5">
+                  <p className="u-fontSize--normal u-color--tuna u-fontWeight--bold u-lineHeight--normal u-marginBottom--10">Path</p>
+                  <input type="text" className="Input" placeholder="/path/to/share" value={this.state.fsNfsPath} onChange={(e) => { this.handleFormChange("fsNfsPath", e) }} />
+                </div>
+              </div>
+              :
+              <div className="flex u-marginBottom--30">
+                <div className="flex1 u-paddingRight--5">
+                  <p className="u-fontSize--normal u-color--tuna u-fontWeight--bold u-lineHeight--normal u-marginBottom--10">Host Path</p>
+                  <input type="text" className="Input" placeholder="/path/on/the/node" value={this.state.fsHostPath} onChange={(e) => { this.handleFormChange("fsHostPath", e) }} />
+                </div>
+              </div>
+            }
+          </div>
+        )
+
       default:
         return (
           <div>No snapshot destination is selected</div>
@@ -624,6 +698,10 @@ class SnapshotStorageDestination extends Component {
               value: "other",
               label: "Other S3-Compatible Storage",
             });
+            availableDestinations.push({
+              value: "filesystem",
+              label: "NFS or Host Path",
+            });
             if (snapshotSettings.isKurl) {
               availableDestinations.push({
                 value: "internal",
@@ -706,7 +784,7 @@ class SnapshotStorageDestination extends Component {
                 {this.renderDestinationFields()}
                 <div className="flex u-marginBottom--30">
                   {isLicenseUpload ? <Link to="/restore" className="btn secondary blue u-marginRight--10">Cancel</Link> : null}
-                  <button className="btn primary blue" disabled={updatingSettings} onClick={this.onSubmit}>{updatingSettings ? "Updating" : isLicenseUpload ? "Use bucket" : "Update settings"}</button>
+                  <button className="btn primary blue" disabled={updatingSettings || snapshotSettings?.migrationStatus === "running"} onClick={this.onSubmit}>{updatingSettings ? "Updating" : isLicenseUpload ? "Use bucket" : "Update settings"}</button>
                   {updateConfirm &&
                     <div className="u-marginLeft--10 flex alignItems--center">
                       <span className="icon checkmark-icon" />
@@ -714,6 +792,16 @@ class SnapshotStorageDestination extends Component {
                     </div>
                   }
                 </div>
+                {snapshotSettings?.migrationStatus === "running" &&
+                  <p className="u-fontSize--small u-fontWeight--medium u-color--dustyGray u-lineHeight--normal u-marginBottom--30">
+                    Updating the storage destination and copying existing snapshots to it. {snapshotSettings?.migrationMessage}
+                  </p>
+                }
+                {snapshotSettings?.migrationStatus === "failed" &&
+                  <p className="u-fontSize--small u-fontWeight--medium u-color--red u-lineHeight--normal u-marginBottom--30">
+                    Failed to update the storage destination or copy existing snapshots to it: {snapshotSettings?.migrationMessage}
+                  </p>
+                }
               </div>
             }
           </form>
@@ -9,6 +9,7 @@ import ConfigureSnapshots from "./ConfigureSnapshots";
 
 import "../../scss/components/shared/SnapshotForm.scss";
 import { Utilities } from "../../utilities/utilities";
+import { Repeater } from "../../utilities/repeater";
 
 
 class Snapshots extends Component {
@@ -21,7 +22,8 @@ class Snapshots extends Component {
     hideCheckVeleroButton: false,
     updateConfirm: false,
     updatingSettings: false,
-    updateErrorMsg: ""
+    updateErrorMsg: "",
+    migrationChecker: new Repeater(),
   };
 
   fetchSnapshotSettings = (isCheckForVelero) => {
@@ -47,6 +49,9 @@ class Snapshots extends Component {
           snapshotSettingsErr: false,
           snapshotSettingsErrMsg: "",
         })
+        if (result.migrationStatus === "running") {
+          this.state.migrationChecker.start(this.updateMigrationStatus, 1000);
+        }
         if (result.veleroVersion === "") {
           setTimeout(() => {
             this.setState({ hideCheckVeleroButton: false });
@@ -68,6 +73,43 @@ class Snapshots extends Component {
     this.fetchSnapshotSettings();
   }
 
+  componentWillUnmount() {
+    this.state.migrationChecker.stop();
+  }
+
+  updateMigrationStatus = () => {
+    return new Promise((resolve, reject) => {
+      fetch(`${window.env.API_ENDPOINT}/snapshots/settings`, {
+        method: "GET",
+        headers: {
+          "Authorization": Utilities.getToken(),
+          "Content-Type": "application/json",
+        }
+      })
+        .then(res => res.json())
+        .then(result => {
+          if (result.migrationStatus !== "running") {
+            // the store may have changed when it was updated in the background
+            this.setState({ snapshotSettings: result });
+            this.state.migrationChecker.stop();
+          } else {
+            this.setState({
+              snapshotSettings: {
+                ...this.state.snapshotSettings,
+                migrationStatus: result.migrationStatus,
+                migrationMessage: result.migrationMessage,
+              }
+            });
+          }
+          resolve();
+        })
+        .catch(err => {
+          console.log("failed to get store migration status", err);
+          reject();
+        });
+    });
+  }
+
   toggleSnapshotView = (isEmptyView) => {
     this.setState({ toggleSnapshotView: !this.state.toggleSnapshotView, isEmptyView: isEmptyView ? isEmptyView : false });
   }
@@ -104,6 +146,9 @@ class Snapshots extends Component {
           setTimeout(() => {
             this.setState({ updateConfirm: false })
           }, 3000);
+          if (settingsResponse.migrationStatus === "running") {
+            this.state.migrationChecker.start(this.updateMigrationStatus, 1000);
+          }
         } else {
           this.setState({
             updatingSettings: false,
@@ -24,8 +24,8 @@ export const cancelRestore = gql(cancelRestoreRaw);
 
 
 export const saveSnapshotConfigRaw = `	
-  mutation saveSnapshotConfig($appId: String!, $inputValue: Int!, $inputTimeUnit: String!, $schedule: String!, $autoEnabled: Boolean!) {	
-    saveSnapshotConfig(appId: $appId, inputValue: $inputValue, inputTimeUnit: $inputTimeUnit, schedule: $schedule, autoEnabled: $autoEnabled)	
+  mutation saveSnapshotConfig($appId: String!, $inputValue: Int!, $inputTimeUnit: String!) {	
+    saveSnapshotConfig(appId: $appId, inputValue: $inputValue, inputTimeUnit: $inputTimeUnit)	
   }	
 `;	
 export const saveSnapshotConfig = gql(saveSnapshotConfigRaw);
\ No newline at end of file
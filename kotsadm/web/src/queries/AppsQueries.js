@@ -21,6 +21,7 @@ export const listAppsRaw = `
         allowSnapshots
         licenseType
         updateCheckerSpec
+        updateCheckerVersionConstraint
         currentVersion {
           title
           status
@@ -126,6 +127,7 @@ export const getKotsAppRaw = `
       allowRollback
       allowSnapshots
       updateCheckerSpec
+      updateCheckerVersionConstraint
       currentVersion {
         title
         status
@@ -269,6 +271,11 @@ export const getKotsDownstreamHistoryRaw = `
       preflightResultCreatedAt
       commitUrl
       gitDeployable
+      preUpgradeSnapshot {
+        backupName
+        status
+        error
+      }
     }
   }
 `;
@@ -9,10 +9,6 @@ export const isVeleroInstalled = gql(`
 export const snapshotConfigRaw = `
   query snapshotConfig($slug: String!) {
     snapshotConfig(slug: $slug) {
-      autoEnabled
-      autoSchedule {
-        schedule
-      }
       ttl {
         inputValue
         inputTimeUnit
@@ -44,19 +44,83 @@ type ApplicationList struct {
 
 // ApplicationSpec defines the desired state of ApplicationSpec
 type ApplicationSpec struct {
-	Title                        string            `json:"title"`
-	Icon                         string            `json:"icon,omitempty"`
-	ApplicationPorts             []ApplicationPort `json:"ports,omitempty"`
-	ReleaseNotes                 string            `json:"releaseNotes,omitempty"`
-	AllowRollback                bool              `json:"allowRollback,omitempty"`
-	StatusInformers              []string          `json:"statusInformers,omitempty"`
-	Graphs                       []MetricGraph     `json:"graphs,omitempty"`
-	KubectlVersion               string            `json:"kubectlVersion,omitempty"`
-	KustomizeVersion             string            `json:"kustomizeVersion,omitempty"`
-	AdditionalImages             []string          `json:"additionalImages,omitempty"`
-	AdditionalNamespaces         []string          `json:"additionalNamespaces,omitempty"`
-	RequireMinimalRBACPrivileges bool              `json:"requireMinimalRBACPrivileges,omitempty"`
-	ProxyPublicImages            bool              `json:"proxyPublicImages,omitempty"`
+	Title                        string              `json:"title"`
+	Icon                         string              `json:"icon,omitempty"`
+	ApplicationPorts             []ApplicationPort   `json:"ports,omitempty"`
+	ReleaseNotes                 string              `json:"releaseNotes,omitempty"`
+	AllowRollback                bool                `json:"allowRollback,omitempty"`
+	StatusInformers              []string            `json:"statusInformers,omitempty"`
+	Graphs                       []MetricGraph       `json:"graphs,omitempty"`
+	KubectlVersion               string              `json:"kubectlVersion,omitempty"`
+	Applier                      string              `json:"applier,omitempty"`
+	AutoRollback                 *AutoRollback       `json:"autoRollback,omitempty"`
+	DriftDetection               *DriftDetection     `json:"driftDetection,omitempty"`
+	Rollout                      *Rollout            `json:"rollout,omitempty"`
+	PreUpgradeSnapshot           *PreUpgradeSnapshot `json:"preUpgradeSnapshot,omitempty"`
+	KustomizeVersion             string              `json:"kustomizeVersion,omitempty"`
+	AdditionalImages             []string            `json:"additionalImages,omitempty"`
+	AdditionalNamespaces         []string            `json:"additionalNamespaces,omitempty"`
+	RequireMinimalRBACPrivileges bool                `json:"requireMinimalRBACPrivileges,omitempty"`
+	ProxyPublicImages            bool                `json:"proxyPublicImages,omitempty"`
+}
+
+// AutoRollback re-applies the previously deployed version when a deploy fails to apply,
+// or when the status informers are not ready by the ready timeout
+type AutoRollback struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// ReadyTimeout is a duration such as "10m". The default is 10 minutes.
+	ReadyTimeout string `json:"readyTimeout,omitempty"`
+}
+
+// DriftDetection controls what happens when the live objects of the app no longer match the
+// manifests that were last applied. Drift is always reported.
+type DriftDetection struct {
+	// Reapply re-applies the manifests when drift is found, reverting changes made to the live objects
+	Reapply bool `json:"reapply,omitempty"`
+}
+
+// Rollout controls how a new version replaces the Deployments of the version that is deployed
+type Rollout struct {
+	// Strategy is "canary", or empty to update every replica at once
+	Strategy string         `json:"strategy,omitempty"`
+	Canary   *CanaryRollout `json:"canary,omitempty"`
+}
+
+// CanaryRollout runs the new version of each changed Deployment in a canary Deployment next to it,
+// which receives traffic from the same Services. The Deployments are only updated when the canary
+// stays ready and the checks pass for the bake period, and the canary is removed either way.
+// The canary uses copies of the new version's ConfigMaps and Secrets. Other objects that the new
+// version adds or changes, such as ServiceAccounts or PersistentVolumeClaims, are only applied once
+// the canary is promoted, so a canary that needs them is aborted.
+type CanaryRollout struct {
+	// Replicas is the number of replicas of each canary Deployment. The default is 1.
+	Replicas int32 `json:"replicas,omitempty"`
+	// BakePeriod is a duration such as "10m". The default is 5 minutes.
+	BakePeriod string        `json:"bakePeriod,omitempty"`
+	Checks     []CanaryCheck `json:"checks,omitempty"`
+}
+
+// CanaryCheck is a Prometheus query that must stay within bounds during the bake period. Every
+// value that the query returns is checked.
+type CanaryCheck struct {
+	// Graph is the title of one of the application's graphs, whose queries are checked
+	Graph string `json:"graph,omitempty"`
+	// Query is checked when there is no graph
+	Query string `json:"query,omitempty"`
+	// Min and Max are numbers such as "0.05". Either can be left out.
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+}
+
+// PreUpgradeSnapshot takes a snapshot of the deployed version of the app before a new version is
+// deployed over it. The new version is not deployed until the snapshot completes.
+type PreUpgradeSnapshot struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// OnFailure is "block" to fail the deploy when the snapshot fails, or "warn" to deploy anyway.
+	// The default is "block".
+	OnFailure string `json:"onFailure,omitempty"`
+	// Timeout is a duration such as "30m". The default is 1 hour.
+	Timeout string `json:"timeout,omitempty"`
 }
 
 type ApplicationPort struct {
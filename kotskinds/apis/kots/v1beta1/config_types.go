@@ -50,17 +50,46 @@ type ConfigItem struct {
 	Affix       string                 `json:"affix,omitempty"`
 	Required    bool                   `json:"required,omitempty"`
 	Items       []ConfigChildItem      `json:"items,omitempty"`
+	Validation  *ConfigItemValidation  `json:"validation,omitempty"`
+	DefaultCmd  *ConfigItemCmd         `json:"default_cmd,omitempty"`
+	ValueCmd    *ConfigItemCmd         `json:"value_cmd,omitempty"`
+	DataCmd     *ConfigItemCmd         `json:"data_cmd,omitempty"`
 	// Props       map[string]interface{} `json:"props,omitempty"`
-	// DefaultCmd  *ConfigItemCmd         `json:"default_cmd,omitempty"`
-	// ValueCmd    *ConfigItemCmd         `json:"value_cmd,omitempty"`
-	// DataCmd     *ConfigItemCmd         `json:"data_cmd,omitempty"`
 }
 
+// ConfigItemCmd generates the default, value or data of a config item. Name is one of the
+// built in generators (random, cert, file or template), and ValueAt selects the output to use
+// for generators that have more than one. The result is generated once and kept in ConfigValues.
+type ConfigItemCmd struct {
+	Name    string   `json:"name"`
+	Args    []string `json:"args,omitempty"`
+	ValueAt int      `json:"value_at,omitempty"`
+}
+
+// ConfigItemValidation holds the rules a config item value has to satisfy before it is saved.
+// Empty values are not validated, use Required for that.
+type ConfigItemValidation struct {
+	Regex         *ConfigItemRegexValidation `json:"regex,omitempty"`
+	Min           *int64                     `json:"min,omitempty"`
+	Max           *int64                     `json:"max,omitempty"`
+	MinLength     *int                       `json:"min_length,omitempty"`
+	MaxLength     *int                       `json:"max_length,omitempty"`
+	AllowedValues []string                   `json:"allowed_values,omitempty"`
+}
+
+type ConfigItemRegexValidation struct {
+	Pattern string `json:"pattern"`
+	Message string `json:"message,omitempty"`
+}
+
+// ConfigGroup is shown once, unless it's Repeatable. The items of a repeatable group hold
+// a list of values, and the values at the same index make up one instance of the group.
 type ConfigGroup struct {
 	Name        string       `json:"name"`
 	Title       string       `json:"title"`
 	Description string       `json:"description,omitempty"`
 	When        string       `json:"when,omitempty"`
+	Repeatable  bool         `json:"repeatable,omitempty"`
 	Items       []ConfigItem `json:"items,omitempty"`
 }
 
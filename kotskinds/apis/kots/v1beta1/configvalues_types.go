@@ -21,11 +21,12 @@ import (
 )
 
 type ConfigValue struct {
-	Default        string `json:"default,omitempty"`
-	Value          string `json:"value,omitempty"`
-	Data           string `json:"data,omitempty"`
-	ValuePlaintext string `json:"valuePlaintext,omitempty"`
-	DataPlaintext  string `json:"dataPlaintext,omitempty"`
+	Default        string   `json:"default,omitempty"`
+	Value          string   `json:"value,omitempty"`
+	Data           string   `json:"data,omitempty"`
+	ValuePlaintext string   `json:"valuePlaintext,omitempty"`
+	DataPlaintext  string   `json:"dataPlaintext,omitempty"`
+	MultiValue     []string `json:"multiValue,omitempty"`
 }
 
 // ConfigValuesSpec defines the desired state of ConfigValue
@@ -235,10 +235,106 @@ func (h *HelmChartSpec) RenderValues(values map[string]MappedChartValue, updater
 
 type OptionalValue struct {
 	When string `json:"when"`
+	// RepeatFor is the name of a repeatable config group. When set, the values are rendered once for
+	// each instance of the group, and arrays are appended in order.
+	RepeatFor string `json:"repeatFor,omitempty"`
 
 	Values map[string]MappedChartValue `json:"values,omitempty"`
 }
 
+// ExpandValues renders the values once with each updater, and merges the results in order.
+// Arrays from each rendering are appended, and any other value is taken from the last rendering.
+func (o *OptionalValue) ExpandValues(updaters []func(string) (string, error)) (map[string]MappedChartValue, error) {
+	expanded := map[string]MappedChartValue{}
+	for i, updater := range updaters {
+		for k, v := range o.Values {
+			rendered, err := v.renderedCopy(updater)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to render %s for instance %d", k, i)
+			}
+
+			existing, ok := expanded[k]
+			if !ok {
+				expanded[k] = *rendered
+				continue
+			}
+			expanded[k] = *mergeMappedChartValues(&existing, rendered)
+		}
+	}
+
+	return expanded, nil
+}
+
+// renderedCopy returns a copy of the value with every string passed through updater
+func (m *MappedChartValue) renderedCopy(updater func(string) (string, error)) (*MappedChartValue, error) {
+	out := &MappedChartValue{
+		Value:      m.Value,
+		valueType:  m.valueType,
+		strValue:   m.strValue,
+		boolValue:  m.boolValue,
+		floatValue: m.floatValue,
+	}
+
+	switch m.valueType {
+	case "string":
+		updated, err := updater(m.strValue)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to update string value")
+		}
+		out.strValue = updated
+	case "children":
+		out.children = map[string]*MappedChartValue{}
+		for k, v := range m.children {
+			if v == nil {
+				out.children[k] = nil
+				continue
+			}
+			child, err := v.renderedCopy(updater)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to render child %s", k)
+			}
+			out.children[k] = child
+		}
+	case "array":
+		out.array = []*MappedChartValue{}
+		for i, v := range m.array {
+			element, err := v.renderedCopy(updater)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to render element %d", i)
+			}
+			out.array = append(out.array, element)
+		}
+	}
+
+	return out, nil
+}
+
+func mergeMappedChartValues(existing *MappedChartValue, next *MappedChartValue) *MappedChartValue {
+	if existing.valueType == "array" && next.valueType == "array" {
+		merged := *existing
+		merged.array = append(append([]*MappedChartValue{}, existing.array...), next.array...)
+		return &merged
+	}
+
+	if existing.valueType == "children" && next.valueType == "children" {
+		merged := *existing
+		merged.children = map[string]*MappedChartValue{}
+		for k, v := range existing.children {
+			merged.children[k] = v
+		}
+		for k, v := range next.children {
+			if prev, ok := merged.children[k]; ok && prev != nil && v != nil {
+				merged.children[k] = mergeMappedChartValues(prev, v)
+			} else {
+				merged.children[k] = v
+			}
+		}
+		return &merged
+	}
+
+	return next
+}
+
 // HelmChartSpec defines the desired state of HelmChartSpec
 type HelmChartSpec struct {
 	Chart          ChartIdentifier             `json:"chart"`
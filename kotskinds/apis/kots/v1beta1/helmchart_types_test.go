@@ -431,3 +431,57 @@ func Test_MappedChartValueGetValue(t *testing.T) {
 		})
 	}
 }
+
+func Test_OptionalValueExpandValues(t *testing.T) {
+	req := require.New(t)
+
+	optionalValue := OptionalValue{
+		RepeatFor: "hosts",
+		Values: map[string]MappedChartValue{
+			"ingress": MappedChartValue{
+				valueType: "children",
+				children: map[string]*MappedChartValue{
+					"hosts": &MappedChartValue{
+						valueType: "array",
+						array: []*MappedChartValue{
+							{
+								valueType: "string",
+								strValue:  "hostname",
+							},
+						},
+					},
+					"enabled": &MappedChartValue{
+						valueType: "bool",
+						boolValue: true,
+					},
+				},
+			},
+		},
+	}
+
+	instanceUpdater := func(host string) func(string) (string, error) {
+		return func(s string) (string, error) {
+			return strings.Replace(s, "hostname", host, -1), nil
+		}
+	}
+	updaters := []func(string) (string, error){
+		instanceUpdater("a.example.com"),
+		instanceUpdater("b.example.com"),
+	}
+
+	expanded, err := optionalValue.ExpandValues(updaters)
+	req.NoError(err)
+
+	spec := HelmChartSpec{}
+	actual, err := spec.RenderValues(expanded, func(s string) (string, error) { return s, nil })
+	req.NoError(err)
+
+	assert.ElementsMatch(t, []string{
+		"ingress.enabled=true",
+		"ingress.hosts[0]=a.example.com",
+		"ingress.hosts[1]=b.example.com",
+	}, actual)
+
+	// the optional value itself is not changed
+	req.Equal("hostname", optionalValue.Values["ingress"].children["hosts"].array[0].strValue)
+}
@@ -26,7 +26,8 @@ func RenderUpstream(u *upstreamtypes.Upstream, renderOptions *RenderOptions) (*B
 		return RenderHelm(u, renderOptions)
 	}
 
-	if u.Type == "replicated" {
+	// git, http and oci upstreams are plain yaml and kots kinds, rendered the same as a release
+	if u.Type == "replicated" || u.Type == "git" || u.Type == "http" || u.Type == "oci" {
 		return renderReplicated(u, renderOptions)
 	}
 
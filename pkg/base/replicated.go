@@ -42,8 +42,9 @@ func renderReplicated(u *upstreamtypes.Upstream, renderOptions *RenderOptions) (
 		ctx := map[string]template.ItemValue{}
 		for k, v := range configValues.Spec.Values {
 			ctx[k] = template.ItemValue{
-				Value:   v.Value,
-				Default: v.Default,
+				Value:      v.Value,
+				Default:    v.Default,
+				MultiValue: v.MultiValue,
 			}
 		}
 		templateContext = ctx
@@ -88,6 +89,11 @@ func renderReplicated(u *upstreamtypes.Upstream, renderOptions *RenderOptions) (
 			return nil, errors.Wrapf(err, "failed to convert upstream file %s to base", upstreamFile.Path)
 		}
 
+		// a manifest that is repeated for a group with no instances is left out
+		if len(baseFile.Content) == 0 && repeatForGroup(upstreamFile.Content) != "" {
+			continue
+		}
+
 		baseFiles := convertToSingleDocs([]BaseFile{baseFile})
 		for _, f := range baseFiles {
 			include, err := f.ShouldBeIncludedInBaseKustomization(renderOptions.ExcludeKotsKinds)
@@ -162,7 +168,29 @@ func renderReplicated(u *upstreamtypes.Upstream, renderOptions *RenderOptions) (
 				continue
 			}
 
-			for k, v := range optionalValues.Values {
+			if optionalValues.RepeatFor == "" {
+				for k, v := range optionalValues.Values {
+					mergedValues[k] = v
+				}
+				continue
+			}
+
+			instanceBuilders, err := builder.InstanceBuilders(optionalValues.RepeatFor)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get config group instances for optional values")
+			}
+			updaters := []func(string) (string, error){}
+			for i := range instanceBuilders {
+				instanceBuilder := instanceBuilders[i]
+				updaters = append(updaters, func(s string) (string, error) {
+					return instanceBuilder.RenderTemplate(s, s)
+				})
+			}
+			expandedValues, err := optionalValues.ExpandValues(updaters)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to expand optional values")
+			}
+			for k, v := range expandedValues {
 				mergedValues[k] = v
 			}
 		}
@@ -236,6 +264,10 @@ func renderReplicated(u *upstreamtypes.Upstream, renderOptions *RenderOptions) (
 }
 
 func upstreamFileToBaseFile(upstreamFile types.UpstreamFile, builder template.Builder, log *logger.Logger) (BaseFile, error) {
+	if groupName := repeatForGroup(upstreamFile.Content); groupName != "" {
+		return repeatedUpstreamFileToBaseFile(upstreamFile, groupName, builder, log)
+	}
+
 	rendered, err := builder.RenderTemplate(upstreamFile.Path, string(upstreamFile.Content))
 	if err != nil {
 		log.Error(errors.Errorf("Failed to render file %s. Contents are %s", upstreamFile.Path, upstreamFile.Content))
@@ -291,8 +323,9 @@ func UnmarshalConfigValuesContent(content []byte) (map[string]template.ItemValue
 	ctx := map[string]template.ItemValue{}
 	for k, v := range values.Spec.Values {
 		ctx[k] = template.ItemValue{
-			Value:   v.Value,
-			Default: v.Default,
+			Value:      v.Value,
+			Default:    v.Default,
+			MultiValue: v.MultiValue,
 		}
 	}
 
@@ -506,3 +539,43 @@ func chartArchiveToSparseUpstream(chartArchivePath string) (*upstreamtypes.Upstr
 
 	return upstream, nil
 }
+
+// repeatForGroup returns the repeatable config group that a single document manifest should be
+// rendered for, from the kots.io/repeat-for annotation
+func repeatForGroup(content []byte) string {
+	if len(bytes.Split(content, []byte("\n---\n"))) > 1 {
+		return ""
+	}
+
+	o := OverlySimpleGVK{}
+	if err := yaml.Unmarshal(content, &o); err != nil {
+		return ""
+	}
+
+	groupName, _ := o.Metadata.Annotations["kots.io/repeat-for"].(string)
+	return groupName
+}
+
+// repeatedUpstreamFileToBaseFile renders the file once for each instance of the config group,
+// and joins the results into a multi document file
+func repeatedUpstreamFileToBaseFile(upstreamFile types.UpstreamFile, groupName string, builder template.Builder, log *logger.Logger) (BaseFile, error) {
+	instanceBuilders, err := builder.InstanceBuilders(groupName)
+	if err != nil {
+		return BaseFile{}, errors.Wrapf(err, "failed to get config group instances for %s", upstreamFile.Path)
+	}
+
+	docs := [][]byte{}
+	for i, instanceBuilder := range instanceBuilders {
+		rendered, err := instanceBuilder.RenderTemplate(upstreamFile.Path, string(upstreamFile.Content))
+		if err != nil {
+			log.Error(errors.Errorf("Failed to render file %s for instance %d. Contents are %s", upstreamFile.Path, i, upstreamFile.Content))
+			return BaseFile{}, errors.Wrap(err, "failed to render file template")
+		}
+		docs = append(docs, []byte(rendered))
+	}
+
+	return BaseFile{
+		Path:    upstreamFile.Path,
+		Content: bytes.Join(docs, []byte("\n---\n")),
+	}, nil
+}
@@ -79,6 +79,9 @@ func ApplyValuesToConfig(config *kotsv1beta1.Config, values map[string]template.
 			if ok {
 				config.Spec.Groups[idxG].Items[idxI].Value = multitype.FromString(value.ValueStr())
 				config.Spec.Groups[idxG].Items[idxI].Default = multitype.FromString(value.DefaultStr())
+				if len(value.MultiValue) > 0 {
+					config.Spec.Groups[idxG].Items[idxI].MultiValue = value.MultiValue
+				}
 			}
 			for idxC, c := range i.Items {
 				value, ok := values[c.Name]
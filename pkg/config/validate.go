@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
+// ItemValidationError is returned for a config item value that does not satisfy the
+// validation rules of the item
+type ItemValidationError struct {
+	Name    string `json:"name"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ItemValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Message)
+}
+
+// ValidateConfigValues checks values, keyed by item name, against the validation rules in the config spec.
+// Items that are hidden or not shown, and items without a value, are not validated.
+func ValidateConfigValues(config *kotsv1beta1.Config, values map[string]string) []ItemValidationError {
+	validationErrors := []ItemValidationError{}
+	if config == nil {
+		return validationErrors
+	}
+
+	for _, group := range config.Spec.Groups {
+		if group.When == "false" {
+			continue
+		}
+		for _, item := range group.Items {
+			if item.Validation == nil || item.Hidden || item.When == "false" {
+				continue
+			}
+
+			value, ok := values[item.Name]
+			if !ok || value == "" {
+				continue
+			}
+
+			title := item.Title
+			if title == "" {
+				title = item.Name
+			}
+			for _, message := range ValidateItemValue(item.Validation, value) {
+				validationErrors = append(validationErrors, ItemValidationError{
+					Name:    item.Name,
+					Title:   title,
+					Message: message,
+				})
+			}
+		}
+	}
+
+	return validationErrors
+}
+
+// ValidateItemValue returns a message for each validation rule that value breaks
+func ValidateItemValue(validation *kotsv1beta1.ConfigItemValidation, value string) []string {
+	messages := []string{}
+	if validation == nil {
+		return messages
+	}
+
+	if validation.Regex != nil && validation.Regex.Pattern != "" {
+		re, err := regexp.Compile(validation.Regex.Pattern)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("validation pattern %q is invalid", validation.Regex.Pattern))
+		} else if !re.MatchString(value) {
+			if validation.Regex.Message != "" {
+				messages = append(messages, validation.Regex.Message)
+			} else {
+				messages = append(messages, fmt.Sprintf("must match the pattern %q", validation.Regex.Pattern))
+			}
+		}
+	}
+
+	if validation.Min != nil || validation.Max != nil {
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			messages = append(messages, "must be a whole number")
+		} else {
+			if validation.Min != nil && n < *validation.Min {
+				messages = append(messages, fmt.Sprintf("must be at least %d", *validation.Min))
+			}
+			if validation.Max != nil && n > *validation.Max {
+				messages = append(messages, fmt.Sprintf("must be at most %d", *validation.Max))
+			}
+		}
+	}
+
+	length := utf8.RuneCountInString(value)
+	if validation.MinLength != nil && length < *validation.MinLength {
+		messages = append(messages, fmt.Sprintf("must be at least %d characters", *validation.MinLength))
+	}
+	if validation.MaxLength != nil && length > *validation.MaxLength {
+		messages = append(messages, fmt.Sprintf("must be at most %d characters", *validation.MaxLength))
+	}
+
+	if len(validation.AllowedValues) > 0 {
+		allowed := false
+		for _, allowedValue := range validation.AllowedValues {
+			if value == allowedValue {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			messages = append(messages, fmt.Sprintf("must be one of: %s", strings.Join(validation.AllowedValues, ", ")))
+		}
+	}
+
+	return messages
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/require"
+	"go.undefinedlabs.com/scopeagent"
+)
+
+func TestValidateItemValue(t *testing.T) {
+	min := int64(1)
+	max := int64(65535)
+	minLength := 3
+	maxLength := 5
+
+	tests := []struct {
+		name       string
+		validation *kotsv1beta1.ConfigItemValidation
+		value      string
+		want       []string
+	}{
+		{
+			name:       "no validation",
+			validation: nil,
+			value:      "anything",
+			want:       []string{},
+		},
+		{
+			name: "regex match",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				Regex: &kotsv1beta1.ConfigItemRegexValidation{Pattern: `^[a-z]+$`, Message: "must be lowercase letters"},
+			},
+			value: "abc",
+			want:  []string{},
+		},
+		{
+			name: "regex mismatch with message",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				Regex: &kotsv1beta1.ConfigItemRegexValidation{Pattern: `^[a-z]+$`, Message: "must be lowercase letters"},
+			},
+			value: "ABC",
+			want:  []string{"must be lowercase letters"},
+		},
+		{
+			name: "regex mismatch without message",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				Regex: &kotsv1beta1.ConfigItemRegexValidation{Pattern: `^[a-z]+$`},
+			},
+			value: "ABC",
+			want:  []string{`must match the pattern "^[a-z]+$"`},
+		},
+		{
+			name: "in range",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				Min: &min,
+				Max: &max,
+			},
+			value: "8080",
+			want:  []string{},
+		},
+		{
+			name: "out of range",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				Min: &min,
+				Max: &max,
+			},
+			value: "70000",
+			want:  []string{"must be at most 65535"},
+		},
+		{
+			name: "not a number",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				Min: &min,
+			},
+			value: "eighty",
+			want:  []string{"must be a whole number"},
+		},
+		{
+			name: "too short",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				MinLength: &minLength,
+				MaxLength: &maxLength,
+			},
+			value: "ab",
+			want:  []string{"must be at least 3 characters"},
+		},
+		{
+			name: "too long",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				MinLength: &minLength,
+				MaxLength: &maxLength,
+			},
+			value: "abcdef",
+			want:  []string{"must be at most 5 characters"},
+		},
+		{
+			name: "allowed value",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				AllowedValues: []string{"small", "large"},
+			},
+			value: "large",
+			want:  []string{},
+		},
+		{
+			name: "multiple rules broken",
+			validation: &kotsv1beta1.ConfigItemValidation{
+				MaxLength:     &maxLength,
+				AllowedValues: []string{"small", "large"},
+			},
+			value: "medium",
+			want:  []string{"must be at most 5 characters", "must be one of: small, large"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scopetest := scopeagent.StartTest(t)
+			defer scopetest.End()
+
+			req := require.New(t)
+			got := ValidateItemValue(tt.validation, tt.value)
+			req.Equal(tt.want, got)
+		})
+	}
+}
+
+func TestValidateConfigValues(t *testing.T) {
+	scopetest := scopeagent.StartTest(t)
+	defer scopetest.End()
+
+	req := require.New(t)
+
+	maxLength := 3
+	config := &kotsv1beta1.Config{
+		Spec: kotsv1beta1.ConfigSpec{
+			Groups: []kotsv1beta1.ConfigGroup{
+				{
+					Name: "group",
+					Items: []kotsv1beta1.ConfigItem{
+						{
+							Name:       "short",
+							Title:      "Short Value",
+							Validation: &kotsv1beta1.ConfigItemValidation{MaxLength: &maxLength},
+						},
+						{
+							Name:       "hidden",
+							Hidden:     true,
+							Validation: &kotsv1beta1.ConfigItemValidation{MaxLength: &maxLength},
+						},
+						{
+							Name:       "unset",
+							Validation: &kotsv1beta1.ConfigItemValidation{MaxLength: &maxLength},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	values := map[string]string{
+		"short":  "abcd",
+		"hidden": "abcd",
+		"unset":  "",
+	}
+
+	got := ValidateConfigValues(config, values)
+	req.Equal([]ItemValidationError{
+		{Name: "short", Title: "Short Value", Message: "must be at most 3 characters"},
+	}, got)
+}
@@ -1,8 +1,11 @@
 package cursor
 
 import (
+	"net/http"
 	"strconv"
+	"time"
 
+	semver "github.com/Masterminds/semver/v3"
 	"github.com/pkg/errors"
 )
 
@@ -17,13 +20,48 @@ type SequenceCursor struct {
 	cursor uint64
 }
 
+// SemverCursor orders versions by semver precedence, including pre-release versions
+type SemverCursor struct {
+	version *semver.Version
+}
+
+// TimestampCursor orders upstreams that only expose a modification time
+type TimestampCursor struct {
+	timestamp time.Time
+}
+
+// NewCursor will create a sequence cursor when s is an unsigned integer, a semver cursor
+// when s is a semantic version, or a timestamp cursor when s is an RFC3339 or HTTP date.
 func NewCursor(s string) (Cursor, error) {
 	if c, err := strconv.ParseUint(s, 10, 64); err == nil {
 		return SequenceCursor{cursor: c}, nil
 	}
+	if c, err := NewSemverCursor(s); err == nil {
+		return c, nil
+	}
+	if c, err := NewTimestampCursor(s); err == nil {
+		return c, nil
+	}
 	return nil, errors.Errorf("cannot use %q to construct cursor", s)
 }
 
+func NewSemverCursor(s string) (Cursor, error) {
+	v, err := semver.NewVersion(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot use %q to construct semver cursor", s)
+	}
+	return SemverCursor{version: v}, nil
+}
+
+func NewTimestampCursor(s string) (Cursor, error) {
+	for _, layout := range []string{time.RFC3339Nano, http.TimeFormat} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return TimestampCursor{timestamp: t}, nil
+		}
+	}
+	return nil, errors.Errorf("cannot use %q to construct timestamp cursor", s)
+}
+
 func (c SequenceCursor) Comparable(o Cursor) bool {
 	switch o.(type) {
 	case SequenceCursor, *SequenceCursor:
@@ -34,13 +72,80 @@ func (c SequenceCursor) Comparable(o Cursor) bool {
 }
 
 func (c SequenceCursor) Equal(o Cursor) bool {
-	return c.cursor == o.(SequenceCursor).cursor
+	return c.cursor == asSequenceCursor(o).cursor
 }
 
 func (c SequenceCursor) Before(o Cursor) bool {
-	return c.cursor < o.(SequenceCursor).cursor
+	return c.cursor < asSequenceCursor(o).cursor
 }
 
 func (c SequenceCursor) After(o Cursor) bool {
-	return c.cursor > o.(SequenceCursor).cursor
+	return c.cursor > asSequenceCursor(o).cursor
+}
+
+func (c SemverCursor) Comparable(o Cursor) bool {
+	switch o.(type) {
+	case SemverCursor, *SemverCursor:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c SemverCursor) Equal(o Cursor) bool {
+	return c.version.Equal(asSemverCursor(o).version)
+}
+
+func (c SemverCursor) Before(o Cursor) bool {
+	return c.version.LessThan(asSemverCursor(o).version)
+}
+
+func (c SemverCursor) After(o Cursor) bool {
+	return c.version.GreaterThan(asSemverCursor(o).version)
+}
+
+func (c SemverCursor) String() string {
+	return c.version.Original()
+}
+
+func (c TimestampCursor) Comparable(o Cursor) bool {
+	switch o.(type) {
+	case TimestampCursor, *TimestampCursor:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c TimestampCursor) Equal(o Cursor) bool {
+	return c.timestamp.Equal(asTimestampCursor(o).timestamp)
+}
+
+func (c TimestampCursor) Before(o Cursor) bool {
+	return c.timestamp.Before(asTimestampCursor(o).timestamp)
+}
+
+func (c TimestampCursor) After(o Cursor) bool {
+	return c.timestamp.After(asTimestampCursor(o).timestamp)
+}
+
+func asSequenceCursor(o Cursor) SequenceCursor {
+	if c, ok := o.(*SequenceCursor); ok {
+		return *c
+	}
+	return o.(SequenceCursor)
+}
+
+func asSemverCursor(o Cursor) SemverCursor {
+	if c, ok := o.(*SemverCursor); ok {
+		return *c
+	}
+	return o.(SemverCursor)
+}
+
+func asTimestampCursor(o Cursor) TimestampCursor {
+	if c, ok := o.(*TimestampCursor); ok {
+		return *c
+	}
+	return o.(TimestampCursor)
 }
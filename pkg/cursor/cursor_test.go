@@ -43,10 +43,50 @@ func Test_Comparable(t *testing.T) {
 		},
 		{
 			name:    "not valid cursor",
-			c1:      "1.0.4",
+			c1:      "abc",
 			c2:      "10",
 			c1Error: true,
 		},
+		{
+			name:         "semver comparable and before",
+			c1:           "1.0.4",
+			c2:           "1.1.0",
+			isBefore:     true,
+			isComparable: true,
+		},
+		{
+			name:         "semver pre-release is before release",
+			c1:           "2.0.0-beta.2",
+			c2:           "2.0.0",
+			isBefore:     true,
+			isComparable: true,
+		},
+		{
+			name:         "semver pre-release ordering",
+			c1:           "2.0.0-beta.10",
+			c2:           "2.0.0-beta.2",
+			isAfter:      true,
+			isComparable: true,
+		},
+		{
+			name:         "semver equal with v prefix",
+			c1:           "v1.2.3",
+			c2:           "1.2.3",
+			isEqual:      true,
+			isComparable: true,
+		},
+		{
+			name:         "timestamp comparable and after",
+			c1:           "Wed, 21 Oct 2020 07:28:00 GMT",
+			c2:           "2020-10-20T07:28:00Z",
+			isAfter:      true,
+			isComparable: true,
+		},
+		{
+			name: "sequence and semver are not comparable",
+			c1:   "10",
+			c2:   "1.0.4",
+		},
 	}
 
 	for _, test := range tests {
@@ -72,6 +112,9 @@ func Test_Comparable(t *testing.T) {
 			}
 
 			assert.Equal(t, test.isComparable, c1.Comparable(c2))
+			if !test.isComparable {
+				return
+			}
 			assert.Equal(t, test.isEqual, c1.Equal(c2))
 			assert.Equal(t, test.isBefore, c1.Before(c2))
 			assert.Equal(t, test.isAfter, c1.After(c2))
@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/docker/distribution/registry/client/auth/challenge"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/version"
+)
+
+// PullAuthorization holds what is needed to make read requests against the v2 api of a registry
+type PullAuthorization struct {
+	BaseURL       string
+	Authorization string
+}
+
+// GetPullAuthorization resolves the scheme a registry is served on and exchanges the credentials
+// for an authorization header that can pull from the repository
+func GetPullAuthorization(endpoint, username, password, repository string) (*PullAuthorization, error) {
+	endpoint = sanitizeEndpoint(endpoint)
+
+	basicAuthToken := ""
+	if username != "" || password != "" {
+		basicAuthToken = makeBasicAuthToken(username, password)
+	}
+
+	if IsECREndpoint(endpoint) && username != "" {
+		token, err := GetECRBasicAuthToken(endpoint, username, password)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get ecr token")
+		}
+		basicAuthToken = token
+	}
+
+	baseURL := fmt.Sprintf("https://%s", endpoint)
+	resp, err := insecureClient.Get(baseURL + "/v2/")
+	if err != nil {
+		// attempt with http
+		baseURL = fmt.Sprintf("http://%s", endpoint)
+		resp, err = insecureClient.Get(baseURL + "/v2/")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to ping registry")
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		// Anonymous registry that does not require authentication
+		return &PullAuthorization{BaseURL: baseURL}, nil
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil, errors.Errorf("unexpected status code: %v", resp.StatusCode)
+	}
+
+	challenges := challenge.ResponseChallenges(resp)
+	if len(challenges) == 0 {
+		return nil, errors.New("no auth challenges found for endpoint")
+	}
+
+	if challenges[0].Scheme == "basic" {
+		return &PullAuthorization{
+			BaseURL:       baseURL,
+			Authorization: fmt.Sprintf("Basic %s", basicAuthToken),
+		}, nil
+	}
+
+	host := challenges[0].Parameters["realm"]
+	v := url.Values{}
+	v.Set("service", challenges[0].Parameters["service"])
+	v.Set("scope", fmt.Sprintf("repository:%s:pull", repository))
+
+	authURL := host + "?" + v.Encode()
+
+	req, err := http.NewRequest("GET", authURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create auth request")
+	}
+
+	req.Header.Add("User-Agent", fmt.Sprintf("KOTS/%s", version.Version()))
+	if basicAuthToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Basic %s", basicAuthToken))
+	}
+
+	authResp, err := insecureClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute auth request")
+	}
+	defer authResp.Body.Close()
+
+	authBody, err := ioutil.ReadAll(authResp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load auth response")
+	}
+
+	if authResp.StatusCode != http.StatusOK {
+		return nil, errors.New(errorResponseToString(authBody))
+	}
+
+	bearerToken, err := newBearerTokenFromJSONBlob(authBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse registry auth bearer token")
+	}
+
+	return &PullAuthorization{
+		BaseURL:       baseURL,
+		Authorization: fmt.Sprintf("Bearer %s", bearerToken.Token),
+	}, nil
+}
+
+// Get executes a request against the registry, relative to the v2 api root
+func (a *PullAuthorization) Get(path string, accept ...string) ([]byte, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v2/%s", a.BaseURL, path), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+
+	req.Header.Add("User-Agent", fmt.Sprintf("KOTS/%s", version.Version()))
+	if a.Authorization != "" {
+		req.Header.Set("Authorization", a.Authorization)
+	}
+	for _, a := range accept {
+		req.Header.Add("Accept", a)
+	}
+
+	resp, err := insecureClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d: %s", resp.StatusCode, errorResponseToString(body))
+	}
+
+	return body, nil
+}
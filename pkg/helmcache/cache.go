@@ -0,0 +1,263 @@
+package helmcache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cache is a content addressed store of helm repository indexes and chart archives.
+// Blobs are stored by their sha256 digest, and small ref files map a repo index
+// or a chart repo, name and version to the blob that holds it.
+type Cache struct {
+	Dir string
+}
+
+type IndexRef struct {
+	RepoURI   string    `json:"repoUri"`
+	Digest    string    `json:"digest"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+type ChartRef struct {
+	RepoURI   string    `json:"repoUri"`
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	Digest    string    `json:"digest"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// DirForRoot returns the location of the cache under the kots root dir
+func DirForRoot(rootDir string) string {
+	return filepath.Join(rootDir, ".kots", "helm-cache")
+}
+
+func New(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+func (c *Cache) PutIndex(repoURI string, content []byte) error {
+	digest, err := c.putBlob(content)
+	if err != nil {
+		return errors.Wrap(err, "failed to write index blob")
+	}
+
+	ref := IndexRef{
+		RepoURI:   repoURI,
+		Digest:    digest,
+		FetchedAt: time.Now(),
+	}
+	if err := writeRef(c.indexRefPath(repoURI), ref); err != nil {
+		return errors.Wrap(err, "failed to write index ref")
+	}
+
+	return nil
+}
+
+// GetIndex returns nil when the repo index has not been cached
+func (c *Cache) GetIndex(repoURI string) ([]byte, error) {
+	ref := IndexRef{}
+	found, err := readRef(c.indexRefPath(repoURI), &ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read index ref")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return c.getBlob(ref.Digest)
+}
+
+func (c *Cache) PutChart(repoURI, name, version string, content []byte) error {
+	digest, err := c.putBlob(content)
+	if err != nil {
+		return errors.Wrap(err, "failed to write chart blob")
+	}
+
+	ref := ChartRef{
+		RepoURI:   repoURI,
+		Name:      name,
+		Version:   version,
+		Digest:    digest,
+		FetchedAt: time.Now(),
+	}
+	refPath, err := c.chartRefPath(repoURI, name, version)
+	if err != nil {
+		return errors.Wrap(err, "failed to get chart ref path")
+	}
+	if err := writeRef(refPath, ref); err != nil {
+		return errors.Wrap(err, "failed to write chart ref")
+	}
+
+	return nil
+}
+
+// GetChart returns nil when the chart version has not been cached from the repo
+func (c *Cache) GetChart(repoURI, name, version string) ([]byte, error) {
+	refPath, err := c.chartRefPath(repoURI, name, version)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get chart ref path")
+	}
+
+	ref := ChartRef{}
+	found, err := readRef(refPath, &ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read chart ref")
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return c.getBlob(ref.Digest)
+}
+
+// ListCharts returns the refs of all cached chart archives
+func (c *Cache) ListCharts() ([]ChartRef, error) {
+	chartsDir := filepath.Join(c.Dir, "charts")
+	if _, err := os.Stat(chartsDir); os.IsNotExist(err) {
+		return []ChartRef{}, nil
+	}
+
+	refs := []ChartRef{}
+	err := filepath.Walk(chartsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		ref := ChartRef{}
+		if _, err := readRef(path, &ref); err != nil {
+			return errors.Wrapf(err, "failed to read chart ref %s", path)
+		}
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk charts")
+	}
+
+	return refs, nil
+}
+
+func (c *Cache) putBlob(content []byte) (string, error) {
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+
+	blobPath := c.blobPath(digest)
+	if _, err := os.Stat(blobPath); err == nil {
+		return digest, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return "", errors.Wrap(err, "failed to create blobs dir")
+	}
+
+	// write to a temp file first so that a partial blob is never read
+	tmpFile, err := ioutil.TempFile(filepath.Dir(blobPath), "blob")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temp file")
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return "", errors.Wrap(err, "failed to write temp file")
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", errors.Wrap(err, "failed to close temp file")
+	}
+
+	if err := os.Rename(tmpFile.Name(), blobPath); err != nil {
+		return "", errors.Wrap(err, "failed to move blob")
+	}
+
+	return digest, nil
+}
+
+func (c *Cache) getBlob(digest string) ([]byte, error) {
+	content, err := ioutil.ReadFile(c.blobPath(digest))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read blob")
+	}
+
+	if fmt.Sprintf("sha256:%x", sha256.Sum256(content)) != digest {
+		return nil, errors.Errorf("blob %s is corrupt", digest)
+	}
+
+	return content, nil
+}
+
+func (c *Cache) blobPath(digest string) string {
+	return filepath.Join(c.Dir, "blobs", "sha256", digest[len("sha256:"):])
+}
+
+func (c *Cache) indexRefPath(repoURI string) string {
+	return filepath.Join(c.Dir, "indexes", fmt.Sprintf("%s.json", repoKey(repoURI)))
+}
+
+// chartRefPath is keyed by the repo too, different repos can have charts with the same name and version.
+// The name and version come from the repo's index, so they can't be trusted to stay in the cache dir.
+func (c *Cache) chartRefPath(repoURI, name, version string) (string, error) {
+	if !isSafePathElement(name) {
+		return "", errors.Errorf("invalid chart name %q", name)
+	}
+	if !isSafePathElement(version) {
+		return "", errors.Errorf("invalid chart version %q", version)
+	}
+	return filepath.Join(c.Dir, "charts", repoKey(repoURI), name, fmt.Sprintf("%s.json", version)), nil
+}
+
+func isSafePathElement(element string) bool {
+	if element == "" || element == "." || strings.Contains(element, "..") {
+		return false
+	}
+	return !strings.ContainsAny(element, `/\`)
+}
+
+func repoKey(repoURI string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(repoURI)))
+}
+
+func writeRef(path string, ref interface{}) error {
+	b, err := json.Marshal(ref)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal ref")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "failed to create ref dir")
+	}
+
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return errors.Wrap(err, "failed to write ref")
+	}
+
+	return nil
+}
+
+func readRef(path string, ref interface{}) (bool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "failed to read ref")
+	}
+
+	if err := json.Unmarshal(b, ref); err != nil {
+		return false, errors.Wrap(err, "failed to unmarshal ref")
+	}
+
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package helmcache
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.undefinedlabs.com/scopeagent"
+)
+
+func Test_Cache(t *testing.T) {
+	scopetest := scopeagent.StartTest(t)
+	defer scopetest.End()
+	req := require.New(t)
+
+	dir, err := ioutil.TempDir("", "helmcache")
+	req.NoError(err)
+	defer os.RemoveAll(dir)
+
+	cache := New(dir)
+
+	index, err := cache.GetIndex("https://charts.example.com")
+	req.NoError(err)
+	assert.Nil(t, index)
+
+	req.NoError(cache.PutIndex("https://charts.example.com", []byte("apiVersion: v1\n")))
+	index, err = cache.GetIndex("https://charts.example.com")
+	req.NoError(err)
+	assert.Equal(t, []byte("apiVersion: v1\n"), index)
+
+	chart, err := cache.GetChart("https://charts.example.com", "mysql", "1.3.1")
+	req.NoError(err)
+	assert.Nil(t, chart)
+
+	req.NoError(cache.PutChart("https://charts.example.com", "mysql", "1.3.1", []byte("chart")))
+	req.NoError(cache.PutChart("https://charts.example.com", "mysql", "1.4.0", []byte("chart")))
+
+	chart, err = cache.GetChart("https://charts.example.com", "mysql", "1.3.1")
+	req.NoError(err)
+	assert.Equal(t, []byte("chart"), chart)
+
+	// the same chart version in another repo is a different chart
+	chart, err = cache.GetChart("https://other.example.com", "mysql", "1.3.1")
+	req.NoError(err)
+	assert.Nil(t, chart)
+
+	req.NoError(cache.PutChart("https://other.example.com", "mysql", "1.3.1", []byte("other chart")))
+	chart, err = cache.GetChart("https://other.example.com", "mysql", "1.3.1")
+	req.NoError(err)
+	assert.Equal(t, []byte("other chart"), chart)
+	chart, err = cache.GetChart("https://charts.example.com", "mysql", "1.3.1")
+	req.NoError(err)
+	assert.Equal(t, []byte("chart"), chart)
+
+	// names and versions from a repo index must not escape the cache dir
+	req.Error(cache.PutChart("https://charts.example.com", "../../mysql", "1.3.1", []byte("chart")))
+	req.Error(cache.PutChart("https://charts.example.com", "mysql", "../1.3.1", []byte("chart")))
+	_, err = cache.GetChart("https://charts.example.com", "mysql/..", "1.3.1")
+	req.Error(err)
+
+	refs, err := cache.ListCharts()
+	req.NoError(err)
+	req.Len(refs, 3)
+	// identical content is stored once
+	digests := map[string]bool{}
+	for _, ref := range refs {
+		digests[ref.Digest] = true
+	}
+	assert.Len(t, digests, 2)
+}
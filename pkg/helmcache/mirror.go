@@ -0,0 +1,196 @@
+package helmcache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+// MirrorHandler serves the cached charts as a read only, ChartMuseum compatible repository
+type MirrorHandler struct {
+	cache *Cache
+	log   *logger.Logger
+}
+
+func NewMirrorHandler(cache *Cache, log *logger.Logger) http.Handler {
+	h := &MirrorHandler{
+		cache: cache,
+		log:   log,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", h.health)
+	mux.HandleFunc("/index.yaml", h.index)
+	mux.HandleFunc("/charts/", h.chart)
+	mux.HandleFunc("/api/charts", h.apiCharts)
+	mux.HandleFunc("/api/charts/", h.apiCharts)
+
+	return mux
+}
+
+func (h *MirrorHandler) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]bool{"healthy": true})
+}
+
+func (h *MirrorHandler) index(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	index, _, err := h.buildIndex()
+	if err != nil {
+		h.log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	b, err := yaml.Marshal(index)
+	if err != nil {
+		h.log.Error(errors.Wrap(err, "failed to marshal index"))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+func (h *MirrorHandler) chart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, filenames, err := h.buildIndex()
+	if err != nil {
+		h.log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ref, ok := filenames[strings.TrimPrefix(r.URL.Path, "/")]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "chart not found"})
+		return
+	}
+
+	content, err := h.cache.GetChart(ref.RepoURI, ref.Name, ref.Version)
+	if err != nil {
+		h.log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if content == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "chart not found"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// apiCharts implements the read endpoints of the ChartMuseum api:
+// /api/charts, /api/charts/<name> and /api/charts/<name>/<version>
+func (h *MirrorHandler) apiCharts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	index, _, err := h.buildIndex()
+	if err != nil {
+		h.log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/charts"), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		writeJSON(w, http.StatusOK, index.Entries)
+	case len(parts) == 1:
+		versions, ok := index.Entries[parts[0]]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "chart not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, versions)
+	case len(parts) == 2:
+		chartVersion, err := index.Get(parts[0], parts[1])
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "chart version not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, chartVersion)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// buildIndex returns the repo index of all cached charts, and a map of the chart archive
+// urls in that index to the cached chart
+func (h *MirrorHandler) buildIndex() (*repo.IndexFile, map[string]ChartRef, error) {
+	refs, err := h.cache.ListCharts()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list cached charts")
+	}
+
+	// the mirror is a single repo, so when a chart version was cached from more than one repo
+	// the last one fetched is served
+	latestRefs := map[string]ChartRef{}
+	for _, ref := range refs {
+		key := fmt.Sprintf("%s-%s", ref.Name, ref.Version)
+		if latest, ok := latestRefs[key]; ok && latest.FetchedAt.After(ref.FetchedAt) {
+			continue
+		}
+		latestRefs[key] = ref
+	}
+
+	index := repo.NewIndexFile()
+	filenames := map[string]ChartRef{}
+	for _, ref := range latestRefs {
+		content, err := h.cache.GetChart(ref.RepoURI, ref.Name, ref.Version)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to get chart %s-%s", ref.Name, ref.Version)
+		}
+		if content == nil {
+			continue
+		}
+
+		c, err := loader.LoadArchive(bytes.NewReader(content))
+		if err != nil {
+			h.log.Error(errors.Wrapf(err, "failed to load chart %s-%s", ref.Name, ref.Version))
+			continue
+		}
+
+		filename := fmt.Sprintf("charts/%s-%s.tgz", ref.Name, ref.Version)
+		index.Add(c.Metadata, filename, "", strings.TrimPrefix(ref.Digest, "sha256:"))
+		filenames[filename] = ref
+	}
+	index.SortEntries()
+
+	return index, filenames, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
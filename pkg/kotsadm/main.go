@@ -244,7 +244,7 @@ func CreateRestoreJob(options *types.RestoreJobOptions) error {
 		return errors.Wrap(err, "failed to get kotsadm options from cluster")
 	}
 
-	job := restoreJob(options.BackupName, namespace, isOpenShift, kotsadmOptions)
+	job := restoreJob(options, namespace, isOpenShift, kotsadmOptions)
 	_, err = clientset.BatchV1().Jobs(namespace).Create(context.TODO(), job, metav1.CreateOptions{})
 	if err != nil {
 		return errors.Wrap(err, "failed to create restore job")
@@ -10,7 +10,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func restoreJob(backupName string, namespace string, isOpenShift bool, kotsadmOptions types.KotsadmOptions) *batchv1.Job {
+func restoreJob(options *types.RestoreJobOptions, namespace string, isOpenShift bool, kotsadmOptions types.KotsadmOptions) *batchv1.Job {
 	var securityContext corev1.PodSecurityContext
 	if !isOpenShift {
 		securityContext = corev1.PodSecurityContext{
@@ -19,6 +19,15 @@ func restoreJob(backupName string, namespace string, isOpenShift bool, kotsadmOp
 		}
 	}
 
+	command := []string{
+		"/kotsadm",
+		"restore",
+		options.BackupName,
+	}
+	if options.KotsadmOnly {
+		command = append(command, "--kotsadm-only")
+	}
+
 	var pullSecrets []corev1.LocalObjectReference
 	if s := kotsadmPullSecret(namespace, kotsadmOptions); s != nil {
 		pullSecrets = []corev1.LocalObjectReference{
@@ -55,11 +64,7 @@ func restoreJob(backupName string, namespace string, isOpenShift bool, kotsadmOp
 							Image:           fmt.Sprintf("%s/kotsadm:%s", kotsadmRegistry(kotsadmOptions), kotsadmTag(kotsadmOptions)),
 							ImagePullPolicy: corev1.PullAlways,
 							Name:            "kotsadm-restore",
-							Command: []string{
-								"/kotsadm",
-								"restore",
-								backupName,
-							},
+							Command:         command,
 							Env: []corev1.EnvVar{
 								{
 									Name: "POD_NAMESPACE",
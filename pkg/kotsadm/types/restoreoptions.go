@@ -2,4 +2,6 @@ package types
 
 type RestoreJobOptions struct {
 	BackupName string
+	// KotsadmOnly restores only the admin console from a backup that includes other resources
+	KotsadmOnly bool
 }
@@ -7,13 +7,14 @@ import (
 )
 
 type GetUpdatesOptions struct {
-	HelmRepoURI    string
-	Namespace      string
-	LocalPath      string
-	LicenseFile    string
-	CurrentCursor  string
-	CurrentChannel string
-	Silent         bool
+	HelmRepoURI       string
+	Namespace         string
+	LocalPath         string
+	LicenseFile       string
+	CurrentCursor     string
+	CurrentChannel    string
+	VersionConstraint string
+	Silent            bool
 }
 
 // GetUpdates will retrieve all later versions of the application specified in upstreamURI
@@ -32,6 +33,7 @@ func GetUpdates(upstreamURI string, getUpdatesOptions GetUpdatesOptions) ([]upst
 	fetchOptions.LocalPath = getUpdatesOptions.LocalPath
 	fetchOptions.CurrentCursor = getUpdatesOptions.CurrentCursor
 	fetchOptions.CurrentChannel = getUpdatesOptions.CurrentChannel
+	fetchOptions.VersionConstraint = getUpdatesOptions.VersionConstraint
 
 	if getUpdatesOptions.LicenseFile != "" {
 		license, err := ParseLicenseFromFile(getUpdatesOptions.LicenseFile)
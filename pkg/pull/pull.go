@@ -1,17 +1,21 @@
 package pull
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
 	"github.com/replicatedhq/kots/pkg/base"
+	kotsconfig "github.com/replicatedhq/kots/pkg/config"
+	"github.com/replicatedhq/kots/pkg/crypto"
 	"github.com/replicatedhq/kots/pkg/docker/registry"
 	"github.com/replicatedhq/kots/pkg/downstream"
 	"github.com/replicatedhq/kots/pkg/k8sdoc"
@@ -27,6 +31,8 @@ import (
 
 type PullOptions struct {
 	HelmRepoURI         string
+	Checksum            string
+	VersionConstraint   string
 	RootDir             string
 	Namespace           string
 	Downstreams         []string
@@ -102,6 +108,8 @@ func Pull(upstreamURI string, pullOptions PullOptions) (string, error) {
 
 	fetchOptions := upstream.FetchOptions{}
 	fetchOptions.HelmRepoURI = pullOptions.HelmRepoURI
+	fetchOptions.Checksum = pullOptions.Checksum
+	fetchOptions.VersionConstraint = pullOptions.VersionConstraint
 	fetchOptions.RootDir = pullOptions.RootDir
 	fetchOptions.UseAppDir = pullOptions.CreateAppDir
 	fetchOptions.LocalPath = pullOptions.LocalPath
@@ -199,6 +207,13 @@ func Pull(upstreamURI string, pullOptions PullOptions) (string, error) {
 		return "", errors.Wrap(err, "failed to fetch upstream")
 	}
 
+	if pullOptions.ConfigFile != "" {
+		if err := validateConfigValues(u, fetchOptions.ConfigValues, fetchOptions.EncryptionKey); err != nil {
+			log.FinishSpinnerWithError()
+			return "", err
+		}
+	}
+
 	includeAdminConsole := uri.Scheme == "replicated" && !pullOptions.ExcludeAdminConsole
 
 	writeUpstreamOptions := upstreamtypes.WriteOptions{
@@ -660,6 +675,60 @@ func LicenseIsExpired(license *kotsv1beta1.License) (bool, error) {
 	return partsed.Before(time.Now()), nil
 }
 
+// validateConfigValues checks the config values against the validation rules of the config in the upstream
+func validateConfigValues(u *upstreamtypes.Upstream, configValues *kotsv1beta1.ConfigValues, encryptionKey string) error {
+	if configValues == nil {
+		return nil
+	}
+
+	var configSpec *kotsv1beta1.Config
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	for _, file := range u.Files {
+		obj, gvk, err := decode(file.Content, nil, nil)
+		if err != nil {
+			continue
+		}
+		if gvk.Group == "kots.io" && gvk.Version == "v1beta1" && gvk.Kind == "Config" {
+			configSpec = obj.(*kotsv1beta1.Config)
+			break
+		}
+	}
+	if configSpec == nil {
+		return nil
+	}
+
+	// passwords may already be encrypted if the values were written by kots
+	cipher, _ := crypto.AESCipherFromString(encryptionKey)
+
+	values := map[string]string{}
+	for name, configValue := range configValues.Spec.Values {
+		value := configValue.Value
+		if configValue.ValuePlaintext != "" {
+			value = configValue.ValuePlaintext
+		} else if cipher != nil {
+			if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+				if decrypted, err := cipher.Decrypt(decoded); err == nil {
+					value = string(decrypted)
+				}
+			}
+		}
+		values[name] = value
+	}
+
+	validationErrors := kotsconfig.ValidateConfigValues(configSpec, values)
+	if len(validationErrors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, validationError := range validationErrors {
+		messages = append(messages, fmt.Sprintf("  %s: %s", validationError.Name, validationError.Message))
+	}
+	return util.ActionableError{
+		Message: fmt.Sprintf("Config values are invalid:\n%s", strings.Join(messages, "\n")),
+	}
+}
+
 func findConfig(localPath string) (*kotsv1beta1.Config, *kotsv1beta1.ConfigValues, *kotsv1beta1.License, *kotsv1beta1.Installation, error) {
 	if localPath == "" {
 		return nil, nil, nil, nil, nil
@@ -37,6 +37,60 @@ func NewBuilder(configGroups []kotsv1beta1.ConfigGroup, existingValues map[strin
 	return b, configCtx.ItemValues, nil
 }
 
+// InstanceBuilders returns a builder for each instance of a repeatable config group. In each builder,
+// ConfigOption returns the values of that instance and ConfigGroupInstanceIndex returns its index.
+func (b *Builder) InstanceBuilders(groupName string) ([]Builder, error) {
+	var configCtx *ConfigCtx
+	for _, ctx := range b.Ctx {
+		switch c := ctx.(type) {
+		case *ConfigCtx:
+			configCtx = c
+		case ConfigCtx:
+			configCtx = &c
+		}
+	}
+	if configCtx == nil {
+		return nil, errors.New("builder has no config context")
+	}
+	if _, ok := configCtx.repeatableGroups[groupName]; !ok {
+		return nil, errors.Errorf("%s is not a repeatable config group", groupName)
+	}
+
+	builders := []Builder{}
+	for i, instance := range configCtx.configGroupInstances(groupName) {
+		ctxs := make([]Ctx, 0, len(b.Ctx)+1)
+		for _, ctx := range b.Ctx {
+			switch ctx.(type) {
+			case *ConfigCtx, ConfigCtx:
+				ctxs = append(ctxs, configCtx.instanceCtx(groupName, instance))
+			default:
+				ctxs = append(ctxs, ctx)
+			}
+		}
+		ctxs = append(ctxs, instanceIndexCtx{index: i})
+
+		// the func map is written to when building templates, so it can't be shared
+		functs := template.FuncMap{}
+		for name, fn := range b.Functs {
+			functs[name] = fn
+		}
+
+		builders = append(builders, Builder{Ctx: ctxs, Functs: functs})
+	}
+
+	return builders, nil
+}
+
+type instanceIndexCtx struct {
+	index int
+}
+
+func (ctx instanceIndexCtx) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"ConfigGroupInstanceIndex": func() int { return ctx.index },
+	}
+}
+
 func (b *Builder) AddCtx(ctx Ctx) {
 	b.Ctx = append(b.Ctx, ctx)
 }
@@ -0,0 +1,198 @@
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+)
+
+const (
+	maxCmdFileSize = 1024 * 1024
+)
+
+// CmdFileRoots are the only directories that the file generator can read from
+var CmdFileRoots = []string{
+	"/etc/kotsadm",
+}
+
+// CmdRunner evaluates the default_cmd, value_cmd and data_cmd generators of config items.
+// Generators run in process, there is no shell. Outputs are kept per command and args, so items
+// that select different outputs of the same command (a cert and its key) get a matching pair.
+type CmdRunner struct {
+	builder Builder
+	outputs map[string][]string
+}
+
+func NewCmdRunner(builder Builder) *CmdRunner {
+	return &CmdRunner{
+		builder: builder,
+		outputs: map[string][]string{},
+	}
+}
+
+// HasCmd returns true if any part of the config item is generated
+func HasCmd(item kotsv1beta1.ConfigItem) bool {
+	return item.DefaultCmd != nil || item.ValueCmd != nil || item.DataCmd != nil
+}
+
+func (r *CmdRunner) Run(cmd *kotsv1beta1.ConfigItemCmd) (string, error) {
+	if cmd == nil {
+		return "", nil
+	}
+
+	key := fmt.Sprintf("%s:%s", cmd.Name, strings.Join(cmd.Args, "\x00"))
+	outputs, ok := r.outputs[key]
+	if !ok {
+		var err error
+		outputs, err = r.run(cmd.Name, cmd.Args)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to run %s", cmd.Name)
+		}
+		r.outputs[key] = outputs
+	}
+
+	if cmd.ValueAt < 0 || cmd.ValueAt >= len(outputs) {
+		return "", errors.Errorf("%s has no output at %d", cmd.Name, cmd.ValueAt)
+	}
+
+	return outputs[cmd.ValueAt], nil
+}
+
+func (r *CmdRunner) run(name string, args []string) ([]string, error) {
+	switch name {
+	case "random":
+		return cmdRandom(args)
+	case "cert":
+		return cmdCert(args)
+	case "file":
+		return cmdFile(args)
+	case "template":
+		return r.cmdTemplate(args)
+	default:
+		return nil, errors.Errorf("unknown generator %q", name)
+	}
+}
+
+// cmdRandom takes an optional length (16 by default) and charset
+func cmdRandom(args []string) ([]string, error) {
+	length := uint64(16)
+	if len(args) > 0 && args[0] != "" {
+		l, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse length")
+		}
+		length = l
+	}
+
+	charset := DefaultCharset
+	if len(args) > 1 && args[1] != "" {
+		charset = args[1]
+	}
+
+	ctx := &StaticCtx{}
+	value := ctx.RandomString(length, charset)
+	if length > 0 && value == "" {
+		return nil, errors.Errorf("invalid charset %q", charset)
+	}
+
+	return []string{value}, nil
+}
+
+// cmdCert takes a common name and an optional number of days the cert is valid for (365 by default).
+// The cert is output at 0 and the key at 1.
+func cmdCert(args []string) ([]string, error) {
+	if len(args) < 1 || args[0] == "" {
+		return nil, errors.New("common name is required")
+	}
+
+	daysValid := 365
+	if len(args) > 1 && args[1] != "" {
+		d, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse days valid")
+		}
+		daysValid = d
+	}
+
+	pair := genSelfSignedCert(args[0], nil, nil, daysValid)
+	if pair.Cert == "" || pair.Key == "" {
+		return nil, errors.New("failed to generate cert")
+	}
+
+	return []string{pair.Cert, pair.Key}, nil
+}
+
+// cmdFile reads a file from the kotsadm pod. Only files under one of CmdFileRoots can be read.
+func cmdFile(args []string) ([]string, error) {
+	if len(args) < 1 || args[0] == "" {
+		return nil, errors.New("path is required")
+	}
+
+	filename, err := filepath.EvalSymlinks(filepath.Clean(args[0]))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve path")
+	}
+
+	if !isInCmdFileRoot(filename) {
+		return nil, errors.Errorf("%s is not in an allowed directory", args[0])
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat file")
+	}
+	if info.IsDir() {
+		return nil, errors.Errorf("%s is a directory", args[0])
+	}
+	if info.Size() > maxCmdFileSize {
+		return nil, errors.Errorf("%s is larger than %d bytes", args[0], maxCmdFileSize)
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read file")
+	}
+
+	return []string{string(content)}, nil
+}
+
+// cmdTemplate renders each arg as a template, with one output per arg
+func (r *CmdRunner) cmdTemplate(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return nil, errors.New("expression is required")
+	}
+
+	outputs := []string{}
+	for _, arg := range args {
+		rendered, err := r.builder.RenderTemplate("cmd", arg)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render expression")
+		}
+		outputs = append(outputs, rendered)
+	}
+
+	return outputs, nil
+}
+
+func isInCmdFileRoot(filename string) bool {
+	for _, root := range CmdFileRoots {
+		resolvedRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(resolvedRoot, filename)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
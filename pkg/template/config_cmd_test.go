@@ -0,0 +1,107 @@
+package template
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/require"
+	"go.undefinedlabs.com/scopeagent"
+)
+
+func TestCmdRunnerRandom(t *testing.T) {
+	scopetest := scopeagent.StartTest(t)
+	defer scopetest.End()
+
+	req := require.New(t)
+	runner := NewCmdRunner(Builder{Ctx: []Ctx{StaticCtx{}}})
+
+	value, err := runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "random", Args: []string{"24", "[a-f]"}})
+	req.NoError(err)
+	req.Len(value, 24)
+	req.Regexp("^[a-f]+$", value)
+
+	// the same command is only run once
+	again, err := runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "random", Args: []string{"24", "[a-f]"}})
+	req.NoError(err)
+	req.Equal(value, again)
+
+	defaultLength, err := runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "random"})
+	req.NoError(err)
+	req.Len(defaultLength, 16)
+}
+
+func TestCmdRunnerCert(t *testing.T) {
+	scopetest := scopeagent.StartTest(t)
+	defer scopetest.End()
+
+	req := require.New(t)
+	runner := NewCmdRunner(Builder{Ctx: []Ctx{StaticCtx{}}})
+
+	cert, err := runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "cert", Args: []string{"example.com"}, ValueAt: 0})
+	req.NoError(err)
+	key, err := runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "cert", Args: []string{"example.com"}, ValueAt: 1})
+	req.NoError(err)
+
+	_, err = tls.X509KeyPair([]byte(cert), []byte(key))
+	req.NoError(err)
+
+	_, err = runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "cert", Args: []string{"example.com"}, ValueAt: 2})
+	req.Error(err)
+}
+
+func TestCmdRunnerFile(t *testing.T) {
+	scopetest := scopeagent.StartTest(t)
+	defer scopetest.End()
+
+	req := require.New(t)
+
+	root, err := ioutil.TempDir("", "kots-cmd")
+	req.NoError(err)
+	defer os.RemoveAll(root)
+
+	outside, err := ioutil.TempDir("", "kots-cmd")
+	req.NoError(err)
+	defer os.RemoveAll(outside)
+
+	req.NoError(ioutil.WriteFile(filepath.Join(root, "allowed"), []byte("contents"), 0644))
+	req.NoError(ioutil.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644))
+	req.NoError(os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "link")))
+
+	prevRoots := CmdFileRoots
+	CmdFileRoots = []string{root}
+	defer func() { CmdFileRoots = prevRoots }()
+
+	runner := NewCmdRunner(Builder{Ctx: []Ctx{StaticCtx{}}})
+
+	value, err := runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "file", Args: []string{filepath.Join(root, "allowed")}})
+	req.NoError(err)
+	req.Equal("contents", value)
+
+	_, err = runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "file", Args: []string{filepath.Join(outside, "secret")}})
+	req.Error(err)
+
+	_, err = runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "file", Args: []string{filepath.Join(root, "..", filepath.Base(outside), "secret")}})
+	req.Error(err)
+
+	_, err = runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "file", Args: []string{filepath.Join(root, "link")}})
+	req.Error(err)
+}
+
+func TestCmdRunnerTemplate(t *testing.T) {
+	scopetest := scopeagent.StartTest(t)
+	defer scopetest.End()
+
+	req := require.New(t)
+	runner := NewCmdRunner(Builder{Ctx: []Ctx{StaticCtx{}}})
+
+	value, err := runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "template", Args: []string{`repl{{ Base64Encode "abc" }}`}})
+	req.NoError(err)
+	req.Equal("YWJj", value)
+
+	_, err = runner.Run(&kotsv1beta1.ConfigItemCmd{Name: "exec", Args: []string{"ls"}})
+	req.Error(err)
+}
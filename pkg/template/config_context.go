@@ -29,6 +29,8 @@ type LocalRegistry struct {
 type ItemValue struct {
 	Value   interface{}
 	Default interface{}
+	// MultiValue holds the ordered values of array items and of items in repeatable groups
+	MultiValue []string
 }
 
 func (i ItemValue) HasValue() bool {
@@ -66,6 +68,8 @@ type ConfigCtx struct {
 
 	license *kotsv1beta1.License // Another agument for unifying all these contexts
 	app     *kotsv1beta1.Application
+
+	repeatableGroups map[string][]string // item names of each repeatable group, in order
 }
 
 // newConfigContext creates and returns a context for template rendering
@@ -87,6 +91,17 @@ func (b *Builder) newConfigContext(configGroups []kotsv1beta1.ConfigGroup, exist
 
 	configItemsByName := make(map[string]kotsv1beta1.ConfigItem)
 	for _, configGroup := range configGroups {
+		if configGroup.Repeatable {
+			if configCtx.repeatableGroups == nil {
+				configCtx.repeatableGroups = map[string][]string{}
+			}
+			itemNames := []string{}
+			for _, configItem := range configGroup.Items {
+				itemNames = append(itemNames, configItem.Name)
+			}
+			configCtx.repeatableGroups[configGroup.Name] = itemNames
+		}
+
 		for _, configItem := range configGroup.Items {
 			configItemsByName[configItem.Name] = configItem
 
@@ -116,10 +131,16 @@ func (b *Builder) newConfigContext(configGroups []kotsv1beta1.ConfigGroup, exist
 		for _, node := range headNodes {
 			deps.ResolveDep(node)
 
+			// cluster objects used with Lookup are not config items
+			if isLookupDep(node) {
+				continue
+			}
+
 			configItem := configItemsByName[node]
 
-			if !isReadOnly(configItem) {
+			if !isReadOnly(configItem) || HasCmd(configItem) {
 				// if item is editable and the live state is valid, skip the rest of this -
+				// generated values are also kept, because they are only generated once
 				_, ok := configCtx.ItemValues[node]
 				if ok {
 					continue
@@ -133,6 +154,10 @@ func (b *Builder) newConfigContext(configGroups []kotsv1beta1.ConfigGroup, exist
 				Value:   builtValue,
 				Default: builtDefault,
 			}
+			for _, v := range configItem.MultiValue {
+				builtMultiValue, _ := builder.String(v)
+				itemValue.MultiValue = append(itemValue.MultiValue, builtMultiValue)
+			}
 
 			configCtx.ItemValues[configItem.Name] = itemValue
 		}
@@ -157,6 +182,8 @@ func (ctx ConfigCtx) FuncMap() template.FuncMap {
 		"ConfigOptionData":             ctx.configOptionData,
 		"ConfigOptionEquals":           ctx.configOptionEquals,
 		"ConfigOptionNotEquals":        ctx.configOptionNotEquals,
+		"ConfigOptionList":             ctx.configOptionList,
+		"ConfigGroupInstances":         ctx.configGroupInstances,
 		"LocalRegistryAddress":         ctx.localRegistryAddress,
 		"LocalRegistryHost":            ctx.localRegistryHost,
 		"LocalRegistryNamespace":       ctx.localRegistryNamespace,
@@ -234,6 +261,78 @@ func (ctx ConfigCtx) configOptionNotEquals(name string, value string) bool {
 	return value != val
 }
 
+// configOptionList returns the ordered values of an array item. Items with a single value are a list of one.
+func (ctx ConfigCtx) configOptionList(name string) []string {
+	val, ok := ctx.ItemValues[name]
+	if !ok {
+		return []string{}
+	}
+
+	if len(val.MultiValue) > 0 {
+		return append([]string{}, val.MultiValue...)
+	}
+
+	v, err := ctx.getConfigOptionValue(name)
+	if err != nil || v == "" {
+		return []string{}
+	}
+
+	return []string{v}
+}
+
+// configGroupInstances returns one map of item name to value for each instance of a repeatable group
+func (ctx ConfigCtx) configGroupInstances(groupName string) []map[string]string {
+	itemNames, ok := ctx.repeatableGroups[groupName]
+	if !ok {
+		return []map[string]string{}
+	}
+
+	lists := map[string][]string{}
+	count := 0
+	for _, itemName := range itemNames {
+		list := ctx.configOptionList(itemName)
+		lists[itemName] = list
+		if len(list) > count {
+			count = len(list)
+		}
+	}
+
+	instances := make([]map[string]string, 0, count)
+	for i := 0; i < count; i++ {
+		instance := map[string]string{}
+		for _, itemName := range itemNames {
+			if i < len(lists[itemName]) {
+				instance[itemName] = lists[itemName][i]
+			} else {
+				instance[itemName] = ""
+			}
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances
+}
+
+// instanceCtx returns a copy of the context where the items of a repeatable group have the values
+// of a single instance of that group
+func (ctx ConfigCtx) instanceCtx(groupName string, instance map[string]string) *ConfigCtx {
+	itemValues := make(map[string]ItemValue, len(ctx.ItemValues))
+	for k, v := range ctx.ItemValues {
+		itemValues[k] = v
+	}
+
+	for _, itemName := range ctx.repeatableGroups[groupName] {
+		itemValue := itemValues[itemName]
+		itemValue.Value = instance[itemName]
+		itemValue.MultiValue = nil
+		itemValues[itemName] = itemValue
+	}
+
+	instanceCtx := ctx
+	instanceCtx.ItemValues = itemValues
+	return &instanceCtx
+}
+
 func (ctx ConfigCtx) localRegistryAddress() string {
 	if ctx.LocalRegistry.Namespace == "" {
 		return ctx.LocalRegistry.Host
@@ -376,6 +376,64 @@ func TestBuilder_NewConfigContext(t *testing.T) {
 	}
 }
 
+func TestConfigCtx_RepeatableGroups(t *testing.T) {
+	scopetest := scopeagent.StartTest(t)
+	defer scopetest.End()
+
+	req := require.New(t)
+
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name:       "relays",
+			Repeatable: true,
+			Items: []kotsv1beta1.ConfigItem{
+				{Name: "relay_host", Type: "text"},
+				{Name: "relay_port", Type: "text", Default: multitype.BoolOrString{Type: multitype.String, StrVal: "25"}},
+			},
+		},
+		{
+			Name: "ingress",
+			Items: []kotsv1beta1.ConfigItem{
+				{Name: "hostnames", Type: "text", Multiple: true, MultiValue: []string{"default.example.com"}},
+				{Name: "summary", Type: "label", Value: multitype.BoolOrString{Type: multitype.String, StrVal: `repl{{ len (ConfigGroupInstances "relays") }} relays`}},
+			},
+		},
+	}
+
+	templateContext := map[string]ItemValue{
+		"relay_host": {MultiValue: []string{"a.example.com", "b.example.com"}},
+		"relay_port": {MultiValue: []string{"25", "587"}},
+	}
+
+	builder, _, err := NewBuilder(configGroups, templateContext, LocalRegistry{}, nil, nil)
+	req.NoError(err)
+
+	rendered, err := builder.RenderTemplate("list", `repl{{ range ConfigOptionList "hostnames" }}repl{{ . }};repl{{ end }}`)
+	req.NoError(err)
+	req.Equal("default.example.com;", rendered)
+
+	rendered, err = builder.RenderTemplate("instances", `repl{{ range ConfigGroupInstances "relays" }}repl{{ .relay_host }}:repl{{ .relay_port }};repl{{ end }}`)
+	req.NoError(err)
+	req.Equal("a.example.com:25;b.example.com:587;", rendered)
+
+	rendered, err = builder.RenderTemplate("summary", `repl{{ ConfigOption "summary" }}`)
+	req.NoError(err)
+	req.Equal("2 relays", rendered)
+
+	instanceBuilders, err := builder.InstanceBuilders("relays")
+	req.NoError(err)
+	req.Len(instanceBuilders, 2)
+
+	for i, expected := range []string{"0 a.example.com:25", "1 b.example.com:587"} {
+		rendered, err = instanceBuilders[i].RenderTemplate("instance", `repl{{ ConfigGroupInstanceIndex }} repl{{ ConfigOption "relay_host" }}:repl{{ ConfigOption "relay_port" }}`)
+		req.NoError(err)
+		req.Equal(expected, rendered)
+	}
+
+	_, err = builder.InstanceBuilders("ingress")
+	req.Error(err)
+}
+
 func Test_localImageName(t *testing.T) {
 	ctxWithRegistry := ConfigCtx{
 		LocalRegistry: LocalRegistry{
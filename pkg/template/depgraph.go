@@ -12,6 +12,29 @@ import (
 
 type depGraph struct {
 	Dependencies map[string]map[string]struct{}
+
+	repeatableGroups map[string][]string
+}
+
+// depGraphCtx is added after the static context so that its functions replace any
+// static functions of the same name that would otherwise reach out to the cluster
+type depGraphCtx struct {
+	funcs template.FuncMap
+}
+
+func (ctx depGraphCtx) FuncMap() template.FuncMap {
+	return ctx.funcs
+}
+
+const lookupDepPrefix = "lookup:"
+
+// lookupDepName is the name of the dep graph node for a cluster object used with Lookup
+func lookupDepName(apiVersion, kind, namespace, name string) string {
+	return fmt.Sprintf("%s%s/%s/%s/%s", lookupDepPrefix, apiVersion, kind, namespace, name)
+}
+
+func isLookupDep(node string) bool {
+	return strings.HasPrefix(node, lookupDepPrefix)
 }
 
 // these config functions are used to add their dependencies to the depGraph
@@ -21,12 +44,36 @@ func (d *depGraph) funcMap(parent string) template.FuncMap {
 		return dep
 	}
 
+	// cluster objects are always available, so they are added as nodes with no dependencies of their own
+	addLookupDepFunc := func(apiVersion, kind, namespace, name string) map[string]interface{} {
+		dep := lookupDepName(apiVersion, kind, namespace, name)
+		d.AddNode(dep)
+		d.AddDep(parent, dep)
+		return map[string]interface{}{}
+	}
+
+	addListDepFunc := func(dep string) []string {
+		d.AddDep(parent, dep)
+		return []string{}
+	}
+
+	// a group's instances depend on every item in the group
+	addGroupDepFunc := func(groupName string) []map[string]string {
+		for _, dep := range d.repeatableGroups[groupName] {
+			d.AddDep(parent, dep)
+		}
+		return []map[string]string{}
+	}
+
 	return template.FuncMap{
 		"ConfigOption":          addDepFunc,
 		"ConfigOptionIndex":     addDepFunc,
 		"ConfigOptionData":      addDepFunc,
 		"ConfigOptionEquals":    addDepFunc,
 		"ConfigOptionNotEquals": addDepFunc,
+		"ConfigOptionList":      addListDepFunc,
+		"ConfigGroupInstances":  addGroupDepFunc,
+		"Lookup":                addLookupDepFunc,
 	}
 }
 
@@ -104,6 +151,18 @@ func (d *depGraph) Copy() (depGraph, error) {
 }
 
 func (d *depGraph) ParseConfigGroup(configGroups []kotsv1beta1.ConfigGroup) error {
+	for _, configGroup := range configGroups {
+		if !configGroup.Repeatable {
+			continue
+		}
+		if d.repeatableGroups == nil {
+			d.repeatableGroups = map[string][]string{}
+		}
+		for _, configItem := range configGroup.Items {
+			d.repeatableGroups[configGroup.Name] = append(d.repeatableGroups[configGroup.Name], configItem.Name)
+		}
+	}
+
 	staticCtx := &StaticCtx{}
 	for _, configGroup := range configGroups {
 		for _, configItem := range configGroup.Items {
@@ -111,14 +170,16 @@ func (d *depGraph) ParseConfigGroup(configGroups []kotsv1beta1.ConfigGroup) erro
 			d.AddNode(configItem.Name)
 
 			depBuilder := Builder{
-				Ctx:    []Ctx{staticCtx},
-				Functs: d.funcMap(configItem.Name),
+				Ctx: []Ctx{staticCtx, depGraphCtx{funcs: d.funcMap(configItem.Name)}},
 			}
 
 			// while builder is normally stateless, the functions it uses within this loop are not
 			// errors are also discarded as we do not have the full set of template functions available here, and errors from not having those functions are expected
 			_, _ = depBuilder.String(configItem.Default.String())
 			_, _ = depBuilder.String(configItem.Value.String())
+			for _, v := range configItem.MultiValue {
+				_, _ = depBuilder.String(v)
+			}
 		}
 	}
 
@@ -221,3 +221,46 @@ func runGraphTests(t *testing.T, test depGraphTestCase, graph depGraph) {
 
 	require.Equal(t, depLen, len(graphCopy.Dependencies))
 }
+
+func TestDepGraphLookup(t *testing.T) {
+	scopetest := scopeagent.StartTest(t)
+	defer scopetest.End()
+	req := require.New(t)
+
+	groups := []kotsv1beta1.ConfigGroup{
+		{
+			Items: []kotsv1beta1.ConfigItem{
+				{
+					Name: "db_namespace",
+					Type: "text",
+				},
+				{
+					Name: "db_password",
+					Type: "password",
+					Default: multitype.BoolOrString{
+						Type:   multitype.String,
+						StrVal: `{{repl index (Lookup "v1" "Secret" (ConfigOption "db_namespace") "db") "data" }}`,
+					},
+				},
+			},
+		},
+	}
+
+	graph := depGraph{}
+	err := graph.ParseConfigGroup(groups)
+	req.NoError(err)
+
+	lookupDep := lookupDepName("v1", "Secret", "db_namespace", "db")
+	req.Equal(map[string]map[string]struct{}{
+		"db_namespace": {},
+		"db_password": {
+			"db_namespace": {},
+			lookupDep:      {},
+		},
+		lookupDep: {},
+	}, graph.Dependencies)
+
+	available, err := graph.GetHeadNodes()
+	req.NoError(err)
+	req.ElementsMatch([]string{"db_namespace", lookupDep}, available)
+}
@@ -30,9 +30,14 @@ import (
 	analyze "github.com/replicatedhq/troubleshoot/pkg/analyze"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	discovery "k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
 	certUtil "k8s.io/client-go/util/cert"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
@@ -85,6 +90,7 @@ func (ctx StaticCtx) FuncMap() template.FuncMap {
 	funcMap["IsKurl"] = ctx.isKurl
 	funcMap["Distribution"] = ctx.distribution
 	funcMap["NodeCount"] = ctx.nodeCount
+	funcMap["Lookup"] = ctx.lookup
 
 	funcMap["HTTPProxy"] = ctx.httpProxy
 	funcMap["NoProxy"] = ctx.noProxy
@@ -523,6 +529,59 @@ func (ctx StaticCtx) nodeCount() int {
 	return len(nodes)
 }
 
+// lookup returns a live object from the cluster, with the same semantics as the helm lookup function.
+// If name is empty, a list of all objects of that kind in the namespace is returned.
+// If namespace is empty, the object is cluster scoped or the list is across all namespaces.
+// An empty map is returned when the object does not exist or the cluster can't be reached.
+func (ctx StaticCtx) lookup(apiVersion string, kind string, namespace string, name string) map[string]interface{} {
+	empty := map[string]interface{}{}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return empty
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return empty
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return empty
+	}
+
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return empty
+	}
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: kind}, gv.Version)
+	if err != nil {
+		return empty
+	}
+
+	var resourceClient dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && namespace != "" {
+		resourceClient = dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	if name == "" {
+		list, err := resourceClient.List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return empty
+		}
+		return list.UnstructuredContent()
+	}
+
+	obj, err := resourceClient.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return empty
+	}
+	return obj.UnstructuredContent()
+}
+
 func (ctx StaticCtx) httpProxy() string {
 	return os.Getenv("HTTP_PROXY")
 }
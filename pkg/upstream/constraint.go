@@ -0,0 +1,49 @@
+package upstream
+
+import (
+	semver "github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+// ParseVersionConstraint returns nil when there is no constraint
+func ParseVersionConstraint(versionConstraint string) (*semver.Constraints, error) {
+	if versionConstraint == "" {
+		return nil, nil
+	}
+
+	c, err := semver.NewConstraint(versionConstraint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse version constraint %q", versionConstraint)
+	}
+
+	return c, nil
+}
+
+// filterUpdatesByConstraint keeps the updates with a semver version label (or cursor) that
+// satisfies the constraint, preserving their order
+func filterUpdatesByConstraint(updates []Update, versionConstraint string) ([]Update, error) {
+	c, err := ParseVersionConstraint(versionConstraint)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return updates, nil
+	}
+
+	filtered := []Update{}
+	for _, update := range updates {
+		if versionSatisfies(c, update.VersionLabel) || (update.VersionLabel == "" && versionSatisfies(c, update.Cursor)) {
+			filtered = append(filtered, update)
+		}
+	}
+
+	return filtered, nil
+}
+
+func versionSatisfies(c *semver.Constraints, version string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
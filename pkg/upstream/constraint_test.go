@@ -0,0 +1,60 @@
+package upstream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.undefinedlabs.com/scopeagent"
+)
+
+func Test_filterUpdatesByConstraint(t *testing.T) {
+	updates := []Update{
+		{Cursor: "1.3.9", VersionLabel: "1.3.9"},
+		{Cursor: "1.4.0", VersionLabel: "1.4.0"},
+		{Cursor: "1.4.7", VersionLabel: "1.4.7"},
+		{Cursor: "2.0.0", VersionLabel: "2.0.0"},
+		{Cursor: "2.5.1", VersionLabel: "2.5.1"},
+		{Cursor: "3.0.0", VersionLabel: "3.0.0"},
+		{Cursor: "42", VersionLabel: "nightly"},
+	}
+
+	tests := []struct {
+		name              string
+		versionConstraint string
+		expectedCursors   []string
+	}{
+		{
+			name:              "no constraint",
+			versionConstraint: "",
+			expectedCursors:   []string{"1.3.9", "1.4.0", "1.4.7", "2.0.0", "2.5.1", "3.0.0", "42"},
+		},
+		{
+			name:              "tilde",
+			versionConstraint: "~1.4",
+			expectedCursors:   []string{"1.4.0", "1.4.7"},
+		},
+		{
+			name:              "range",
+			versionConstraint: ">=2.0 <3.0",
+			expectedCursors:   []string{"2.0.0", "2.5.1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scopetest := scopeagent.StartTest(t)
+			defer scopetest.End()
+			req := require.New(t)
+
+			filtered, err := filterUpdatesByConstraint(updates, test.versionConstraint)
+			req.NoError(err)
+
+			cursors := []string{}
+			for _, update := range filtered {
+				cursors = append(cursors, update.Cursor)
+			}
+			assert.Equal(t, test.expectedCursors, cursors)
+		})
+	}
+}
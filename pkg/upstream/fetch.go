@@ -2,10 +2,12 @@ package upstream
 
 import (
 	"net/url"
+	"strings"
 
 	"github.com/pkg/errors"
 	kotsv1beta1 "github.com/replicatedhq/kots/kotskinds/apis/kots/v1beta1"
 	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/replicatedhq/kots/pkg/helmcache"
 	"github.com/replicatedhq/kots/pkg/upstream/types"
 	"github.com/replicatedhq/kots/pkg/util"
 )
@@ -21,6 +23,8 @@ type FetchOptions struct {
 	ConfigValues        *kotsv1beta1.ConfigValues
 	Airgap              *kotsv1beta1.Airgap
 	EncryptionKey       string
+	Checksum            string
+	VersionConstraint   string
 	CurrentCursor       string
 	CurrentChannel      string
 	CurrentVersionLabel string
@@ -54,21 +58,32 @@ func downloadUpstream(upstreamURI string, fetchOptions *FetchOptions) (*types.Up
 		return nil, errors.Wrap(err, "parse request uri failed")
 	}
 	if u.Scheme == "helm" {
-		return downloadHelm(u, fetchOptions.HelmRepoURI)
+		return downloadHelm(u, fetchOptions.HelmRepoURI, fetchOptions.VersionConstraint, helmCacheFromOptions(fetchOptions))
 	}
 	if u.Scheme == "replicated" {
 		return downloadReplicated(u, fetchOptions.LocalPath, fetchOptions.RootDir, fetchOptions.UseAppDir, fetchOptions.License, fetchOptions.ConfigValues, pickCursor(fetchOptions), pickVersionLabel(fetchOptions), cipher)
 	}
-	if u.Scheme == "git" {
-		return downloadGit(upstreamURI)
+	if u.Scheme == "git" || strings.HasPrefix(u.Scheme, "git+") {
+		return downloadGit(u)
+	}
+	if u.Scheme == "oci" {
+		return downloadOCI(u)
 	}
 	if u.Scheme == "http" || u.Scheme == "https" {
-		return downloadHttp(upstreamURI)
+		return downloadHttp(upstreamURI, fetchOptions.Checksum)
 	}
 
 	return nil, errors.Errorf("unknown protocol scheme %q", u.Scheme)
 }
 
+// helmCacheFromOptions returns the helm cache under the root dir, or nil when there is no root dir
+func helmCacheFromOptions(fetchOptions *FetchOptions) *helmcache.Cache {
+	if fetchOptions.RootDir == "" {
+		return nil
+	}
+	return helmcache.New(helmcache.DirForRoot(fetchOptions.RootDir))
+}
+
 func pickVersionLabel(fetchOptions *FetchOptions) string {
 	if fetchOptions.Airgap != nil && fetchOptions.Airgap.Spec.VersionLabel != "" {
 		return fetchOptions.Airgap.Spec.VersionLabel
@@ -1,10 +1,255 @@
 package upstream
 
 import (
+	"net/url"
+	"path"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/upstream/types"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
 
-func downloadGit(gitURI string) (*types.Upstream, error) {
-	return nil, errors.New("downloadGit not implemented")
+type gitUpstream struct {
+	CloneURL string
+	Ref      string
+	Path     string
+	Name     string
+}
+
+func getUpdatesGit(u *url.URL, currentCursor string) ([]Update, error) {
+	g, err := parseGitURL(u)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse git uri")
+	}
+
+	repo, err := cloneGitRepo(g.CloneURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to clone git repo")
+	}
+
+	head, err := resolveGitRef(repo, g.Ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve ref %q", g.Ref)
+	}
+
+	commits, err := repo.Log(&git.LogOptions{From: *head})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list commits")
+	}
+	defer commits.Close()
+
+	// commits are walked newest first, stopping at the current cursor
+	updates := []Update{}
+	err = commits.ForEach(func(c *object.Commit) error {
+		if c.Hash.String() == currentCursor {
+			return storer.ErrStop
+		}
+		if g.Path != "" && !commitTouchesPath(c, g.Path) {
+			return nil
+		}
+
+		updates = append(updates, Update{
+			Cursor:       c.Hash.String(),
+			VersionLabel: shortGitHash(c.Hash),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk commits")
+	}
+
+	// return the updates oldest first, like the other upstreams
+	for i, j := 0, len(updates)-1; i < j; i, j = i+1, j-1 {
+		updates[i], updates[j] = updates[j], updates[i]
+	}
+
+	return updates, nil
+}
+
+func downloadGit(u *url.URL) (*types.Upstream, error) {
+	g, err := parseGitURL(u)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse git uri")
+	}
+
+	repo, err := cloneGitRepo(g.CloneURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to clone git repo")
+	}
+
+	hash, err := resolveGitRef(repo, g.Ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve ref %q", g.Ref)
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get commit")
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get commit tree")
+	}
+
+	files, err := readGitTree(tree, g.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read files from git tree")
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no files found in %q at %s", g.Path, shortGitHash(*hash))
+	}
+
+	upstream := &types.Upstream{
+		URI:          u.String(),
+		Name:         g.Name,
+		Type:         "git",
+		Files:        files,
+		UpdateCursor: hash.String(),
+		VersionLabel: shortGitHash(*hash),
+	}
+
+	return upstream, nil
+}
+
+// parseGitURL reads a uri in the form of git://host/org/repo.git?ref=v1.0.0&path=manifests.
+// The scheme can also be git+https, git+http or git+ssh to clone using that transport.
+func parseGitURL(u *url.URL) (*gitUpstream, error) {
+	scheme := "git"
+	if strings.HasPrefix(u.Scheme, "git+") {
+		scheme = strings.TrimPrefix(u.Scheme, "git+")
+	}
+
+	switch scheme {
+	case "git", "http", "https", "ssh":
+	default:
+		return nil, errors.Errorf("unsupported git transport %q", scheme)
+	}
+
+	if u.Host == "" {
+		return nil, errors.New("git uri is missing a host")
+	}
+
+	repoPath := strings.Trim(u.Path, "/")
+	if repoPath == "" {
+		return nil, errors.New("git uri is missing a repository path")
+	}
+
+	cloneURL := url.URL{
+		Scheme: scheme,
+		User:   u.User,
+		Host:   u.Host,
+		Path:   "/" + repoPath,
+	}
+
+	query := u.Query()
+
+	g := gitUpstream{
+		CloneURL: cloneURL.String(),
+		Ref:      query.Get("ref"),
+		Path:     strings.Trim(path.Clean("/"+query.Get("path")), "/"),
+		Name:     strings.TrimSuffix(path.Base(repoPath), ".git"),
+	}
+
+	return &g, nil
+}
+
+func cloneGitRepo(cloneURL string) (*git.Repository, error) {
+	repo, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:  cloneURL,
+		Tags: git.AllTags,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to clone")
+	}
+
+	return repo, nil
+}
+
+// resolveGitRef returns the commit for a branch, tag or commit sha. An empty ref
+// resolves to the default branch.
+func resolveGitRef(repo *git.Repository, ref string) (*plumbing.Hash, error) {
+	if ref == "" {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get head")
+		}
+		h := head.Hash()
+		return &h, nil
+	}
+
+	candidates := []string{
+		ref,
+		// only the default branch is checked out locally after a clone
+		"refs/remotes/origin/" + ref,
+	}
+
+	for _, candidate := range candidates {
+		hash, err := repo.ResolveRevision(plumbing.Revision(candidate))
+		if err == nil {
+			return hash, nil
+		}
+	}
+
+	return nil, errors.New("ref not found")
+}
+
+func readGitTree(tree *object.Tree, subdir string) ([]types.UpstreamFile, error) {
+	if subdir != "" {
+		t, err := tree.Tree(subdir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find path %q", subdir)
+		}
+		tree = t
+	}
+
+	upstreamFiles := []types.UpstreamFile{}
+	err := tree.Files().ForEach(func(f *object.File) error {
+		if !f.Mode.IsFile() {
+			return nil
+		}
+
+		content, err := f.Contents()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", f.Name)
+		}
+
+		upstreamFiles = append(upstreamFiles, types.UpstreamFile{
+			Path:    f.Name,
+			Content: []byte(content),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return upstreamFiles, nil
+}
+
+func commitTouchesPath(c *object.Commit, subdir string) bool {
+	stats, err := c.Stats()
+	if err != nil {
+		// be conservative and report the commit
+		return true
+	}
+
+	prefix := subdir + "/"
+	for _, stat := range stats {
+		if strings.HasPrefix(stat.Name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func shortGitHash(h plumbing.Hash) string {
+	return h.String()[:7]
 }
@@ -0,0 +1,61 @@
+package upstream
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.undefinedlabs.com/scopeagent"
+)
+
+func Test_parseGitURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		expected gitUpstream
+	}{
+		{
+			name: "git protocol",
+			uri:  "git://github.com/replicatedhq/kots-sentry.git",
+			expected: gitUpstream{
+				CloneURL: "git://github.com/replicatedhq/kots-sentry.git",
+				Name:     "kots-sentry",
+			},
+		},
+		{
+			name: "https with ref and path",
+			uri:  "git+https://github.com/replicatedhq/kots-sentry.git?ref=v1.0.0&path=/manifests/",
+			expected: gitUpstream{
+				CloneURL: "https://github.com/replicatedhq/kots-sentry.git",
+				Ref:      "v1.0.0",
+				Path:     "manifests",
+				Name:     "kots-sentry",
+			},
+		},
+		{
+			name: "ssh with user",
+			uri:  "git+ssh://git@github.com/replicatedhq/kots-sentry?ref=main",
+			expected: gitUpstream{
+				CloneURL: "ssh://git@github.com/replicatedhq/kots-sentry",
+				Ref:      "main",
+				Name:     "kots-sentry",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scopetest := scopeagent.StartTest(t)
+			defer scopetest.End()
+			req := require.New(t)
+
+			u, err := url.ParseRequestURI(test.uri)
+			req.NoError(err)
+
+			g, err := parseGitURL(u)
+			req.NoError(err)
+			assert.Equal(t, test.expected, *g)
+		})
+	}
+}
@@ -11,10 +11,13 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
-	"github.com/Masterminds/semver"
+	semver "github.com/Masterminds/semver/v3"
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/cursor"
+	"github.com/replicatedhq/kots/pkg/helmcache"
 	"github.com/replicatedhq/kots/pkg/upstream/types"
 	"github.com/replicatedhq/kots/pkg/util"
 	"helm.sh/helm/v3/cmd/helm/search"
@@ -24,7 +27,7 @@ import (
 	"helm.sh/helm/v3/pkg/repo"
 )
 
-func getUpdatesHelm(u *url.URL, repoURI string) ([]Update, error) {
+func getUpdatesHelm(u *url.URL, repoURI string, currentCursor string, cache *helmcache.Cache) ([]Update, error) {
 	repoName, chartName, _, err := parseHelmURL(u)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse helm uri")
@@ -36,23 +39,48 @@ func getUpdatesHelm(u *url.URL, repoURI string) ([]Update, error) {
 	}
 	defer os.RemoveAll(helmHome)
 
-	i, err := helmLoadRepositoriesIndex(helmHome, repoName, repoURI)
+	i, err := helmLoadRepositoriesIndex(helmHome, repoName, repoURI, cache)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to load helm repositories")
 	}
 
-	var updates []Update
+	current, err := cursor.NewSemverCursor(currentCursor)
+	if err != nil {
+		// without a comparable cursor, every chart version is an update
+		current = nil
+	}
+
+	var versions []*semver.Version
 	for _, result := range i.All() {
 		if result.Chart.Name != chartName {
 			continue
 		}
 
-		updates = append(updates, Update{Cursor: result.Chart.Version})
+		v, err := semver.NewVersion(result.Chart.Version)
+		if err != nil {
+			continue
+		}
+
+		if current != nil {
+			c, err := cursor.NewSemverCursor(result.Chart.Version)
+			if err != nil || !c.After(current) {
+				continue
+			}
+		}
+
+		versions = append(versions, v)
+	}
+
+	sort.Sort(semver.Collection(versions))
+
+	updates := []Update{}
+	for _, v := range versions {
+		updates = append(updates, Update{Cursor: v.Original(), VersionLabel: v.Original()})
 	}
 	return updates, nil
 }
 
-func downloadHelm(u *url.URL, repoURI string) (*types.Upstream, error) {
+func downloadHelm(u *url.URL, repoURI string, versionConstraint string, cache *helmcache.Cache) (*types.Upstream, error) {
 	repoName, chartName, chartVersion, err := parseHelmURL(u)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse helm uri")
@@ -68,11 +96,16 @@ func downloadHelm(u *url.URL, repoURI string) (*types.Upstream, error) {
 	}
 	defer os.RemoveAll(helmHome)
 
-	i, err := helmLoadRepositoriesIndex(helmHome, repoName, repoURI)
+	i, err := helmLoadRepositoriesIndex(helmHome, repoName, repoURI, cache)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to load helm repositories")
 	}
 
+	constraint, err := ParseVersionConstraint(versionConstraint)
+	if err != nil {
+		return nil, err
+	}
+
 	if chartVersion == "" {
 		highestChartVersion := semver.MustParse("0.0.0")
 		for _, result := range i.All() {
@@ -85,12 +118,22 @@ func downloadHelm(u *url.URL, repoURI string) (*types.Upstream, error) {
 				return nil, errors.Wrap(err, "unable to parse chart version")
 			}
 
+			if constraint != nil && !versionSatisfies(constraint, result.Chart.Version) {
+				continue
+			}
+
 			if v.GreaterThan(highestChartVersion) {
 				highestChartVersion = v
 			}
 		}
 
+		if constraint != nil && highestChartVersion.Equal(semver.MustParse("0.0.0")) {
+			return nil, errors.Errorf("no chart version satisfies %q", versionConstraint)
+		}
+
 		chartVersion = highestChartVersion.String()
+	} else if constraint != nil && !versionSatisfies(constraint, chartVersion) {
+		return nil, errors.Errorf("chart version %s does not satisfy %q", chartVersion, versionConstraint)
 	}
 
 	for _, result := range i.All() {
@@ -102,30 +145,18 @@ func downloadHelm(u *url.URL, repoURI string) (*types.Upstream, error) {
 			continue
 		}
 
-		dl := downloader.ChartDownloader{
-			Out:              os.Stdout,
-			Getters:          getter.All(&cli.EnvSettings{}),
-			RepositoryConfig: getReposFile(helmHome),
-			RepositoryCache:  getCachePath(helmHome),
-		}
-
 		archiveDir, err := ioutil.TempDir("", "archive")
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create archive directory for chart")
 		}
 		defer os.RemoveAll(archiveDir)
 
-		chartRef, err := repo.FindChartInRepoURL(repoURI, result.Chart.Name, chartVersion, "", "", "", getter.All(&cli.EnvSettings{}))
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to find chart in repo url")
-		}
-
-		_, _, err = dl.DownloadTo(chartRef, result.Chart.Version, archiveDir)
-		if err != nil {
+		archivePath := path.Join(archiveDir, fmt.Sprintf("%s-%s.tgz", chartName, chartVersion))
+		if err := helmDownloadChartArchive(helmHome, repoURI, result.Chart.Name, chartVersion, archivePath, cache); err != nil {
 			return nil, errors.Wrap(err, "failed to download chart")
 		}
 
-		upstream, err := chartArchiveToSparseUpstream(path.Join(archiveDir, fmt.Sprintf("%s-%s.tgz", chartName, chartVersion)))
+		upstream, err := chartArchiveToSparseUpstream(archivePath)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to parse chart archive as upstream")
 		}
@@ -141,6 +172,54 @@ func downloadHelm(u *url.URL, repoURI string) (*types.Upstream, error) {
 	return nil, errors.New("chart version not found")
 }
 
+// helmDownloadChartArchive writes the chart archive to archivePath, using the cache when
+// the chart version has been downloaded before
+func helmDownloadChartArchive(helmHome, repoURI, chartName, chartVersion, archivePath string, cache *helmcache.Cache) error {
+	if cache != nil {
+		content, err := cache.GetChart(repoURI, chartName, chartVersion)
+		if err != nil {
+			return errors.Wrap(err, "failed to read chart from cache")
+		}
+		if content != nil {
+			return ioutil.WriteFile(archivePath, content, 0644)
+		}
+	}
+
+	dl := downloader.ChartDownloader{
+		Out:              os.Stdout,
+		Getters:          getter.All(&cli.EnvSettings{}),
+		RepositoryConfig: getReposFile(helmHome),
+		RepositoryCache:  getCachePath(helmHome),
+	}
+
+	chartRef, err := repo.FindChartInRepoURL(repoURI, chartName, chartVersion, "", "", "", getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		return errors.Wrap(err, "failed to find chart in repo url")
+	}
+
+	downloadedPath, _, err := dl.DownloadTo(chartRef, chartVersion, path.Dir(archivePath))
+	if err != nil {
+		return errors.Wrap(err, "failed to download chart")
+	}
+	if downloadedPath != archivePath {
+		if err := os.Rename(downloadedPath, archivePath); err != nil {
+			return errors.Wrap(err, "failed to move chart archive")
+		}
+	}
+
+	if cache != nil {
+		content, err := ioutil.ReadFile(archivePath)
+		if err != nil {
+			return errors.Wrap(err, "failed to read chart archive")
+		}
+		if err := cache.PutChart(repoURI, chartName, chartVersion, content); err != nil {
+			return errors.Wrap(err, "failed to cache chart archive")
+		}
+	}
+
+	return nil
+}
+
 func chartArchiveToSparseUpstream(chartArchivePath string) (*types.Upstream, error) {
 	files, err := readTarGz(chartArchivePath)
 	if err != nil {
@@ -155,7 +234,7 @@ func chartArchiveToSparseUpstream(chartArchivePath string) (*types.Upstream, err
 	return upstream, nil
 }
 
-func helmLoadRepositoriesIndex(helmHome, repoName, repoURI string) (*search.Index, error) {
+func helmLoadRepositoriesIndex(helmHome, repoName, repoURI string, cache *helmcache.Cache) (*search.Index, error) {
 	if repoURI == "" {
 		repoURI = getKnownHelmRepoURI(repoName)
 	}
@@ -185,7 +264,7 @@ repositories: []`
 	}
 	r.CachePath = getCachePath(helmHome)
 
-	indexFilePath, err := r.DownloadIndexFile()
+	indexFilePath, err := helmDownloadIndexFile(r, helmHome, repoName, repoURI, cache)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to download index file")
 	}
@@ -210,6 +289,44 @@ repositories: []`
 	return i, nil
 }
 
+// helmDownloadIndexFile downloads the repo index, storing it in the cache. When the repo
+// can't be reached, the last cached copy of the index is used instead.
+func helmDownloadIndexFile(r *repo.ChartRepository, helmHome, repoName, repoURI string, cache *helmcache.Cache) (string, error) {
+	indexFilePath, downloadErr := r.DownloadIndexFile()
+	if cache == nil {
+		return indexFilePath, downloadErr
+	}
+
+	if downloadErr == nil {
+		content, err := ioutil.ReadFile(indexFilePath)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read index file")
+		}
+		if err := cache.PutIndex(repoURI, content); err != nil {
+			return "", errors.Wrap(err, "failed to cache index file")
+		}
+		return indexFilePath, nil
+	}
+
+	content, err := cache.GetIndex(repoURI)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read index file from cache")
+	}
+	if content == nil {
+		return "", downloadErr
+	}
+
+	indexFilePath = filepath.Join(getCachePath(helmHome), fmt.Sprintf("%s-index.yaml", repoName))
+	if err := os.MkdirAll(filepath.Dir(indexFilePath), 0755); err != nil {
+		return "", errors.Wrap(err, "failed to create cache dir")
+	}
+	if err := ioutil.WriteFile(indexFilePath, content, 0644); err != nil {
+		return "", errors.Wrap(err, "failed to write cached index file")
+	}
+
+	return indexFilePath, nil
+}
+
 func parseHelmURL(u *url.URL) (string, string, string, error) {
 	repo := u.Host
 	chartName := strings.TrimLeft(u.Path, "/")
@@ -240,7 +357,11 @@ func readTarGz(source string) ([]types.UpstreamFile, error) {
 	}
 	defer f.Close()
 
-	gzf, err := gzip.NewReader(f)
+	return readTarGzReader(f)
+}
+
+func readTarGzReader(r io.Reader) ([]types.UpstreamFile, error) {
+	gzf, err := gzip.NewReader(r)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create gzip reader")
 	}
@@ -1,10 +1,213 @@
 package upstream
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/cursor"
 	"github.com/replicatedhq/kots/pkg/upstream/types"
 )
 
-func downloadHttp(httpURI string) (*types.Upstream, error) {
-	return nil, errors.New("downloadHttp not implemented")
+func getUpdatesHttp(upstreamURI string, currentCursor string) ([]Update, error) {
+	downloadURL, _, err := parseHttpURL(upstreamURI)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse http uri")
+	}
+
+	header, err := httpHead(downloadURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get headers")
+	}
+
+	updateCursor := httpCursorFromHeaders(header)
+	if updateCursor == "" {
+		// the server doesn't give us a way to tell if the content changed without downloading it
+		_, content, err := httpGet(downloadURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to download")
+		}
+		updateCursor = sha256Hex(content)
+	}
+
+	if updateCursor == currentCursor {
+		return []Update{}, nil
+	}
+
+	// a Last-Modified cursor can tell us if the content is older than what we have
+	if c, err := cursor.NewTimestampCursor(updateCursor); err == nil {
+		if current, err := cursor.NewTimestampCursor(currentCursor); err == nil && !c.After(current) {
+			return []Update{}, nil
+		}
+	}
+
+	return []Update{{Cursor: updateCursor}}, nil
+}
+
+func downloadHttp(upstreamURI string, checksum string) (*types.Upstream, error) {
+	downloadURL, fragmentChecksum, err := parseHttpURL(upstreamURI)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse http uri")
+	}
+
+	if fragmentChecksum != "" && checksum != "" && !strings.EqualFold(fragmentChecksum, checksum) {
+		return nil, errors.New("sha256 in uri does not match the sha256 option")
+	}
+	if checksum == "" {
+		checksum = fragmentChecksum
+	}
+
+	header, content, err := httpGet(downloadURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download")
+	}
+
+	contentChecksum := sha256Hex(content)
+	if checksum != "" && !strings.EqualFold(checksum, contentChecksum) {
+		return nil, errors.Errorf("sha256 mismatch, expected %s but downloaded %s", checksum, contentChecksum)
+	}
+
+	fileName := "upstream.yaml"
+	if u, err := url.Parse(downloadURL); err == nil {
+		if base := path.Base(u.Path); strings.Contains(base, ".") {
+			fileName = base
+		}
+	}
+
+	var files []types.UpstreamFile
+	if isGzip(content) {
+		f, err := readTarGzReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read archive")
+		}
+		files = f
+	} else {
+		files = []types.UpstreamFile{
+			{
+				Path:    fileName,
+				Content: content,
+			},
+		}
+	}
+
+	cursor := httpCursorFromHeaders(header)
+	if cursor == "" {
+		cursor = contentChecksum
+	}
+
+	upstream := &types.Upstream{
+		URI:          upstreamURI,
+		Name:         httpUpstreamName(fileName),
+		Type:         "http",
+		Files:        files,
+		UpdateCursor: cursor,
+	}
+
+	return upstream, nil
+}
+
+// parseHttpURL splits an optional #sha256=<hex> fragment from the uri
+func parseHttpURL(upstreamURI string) (string, string, error) {
+	u, err := url.Parse(upstreamURI)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to parse url")
+	}
+
+	checksum := ""
+	if u.Fragment != "" {
+		parts := strings.SplitN(u.Fragment, "=", 2)
+		if len(parts) != 2 || parts[0] != "sha256" {
+			return "", "", errors.Errorf("unsupported fragment %q, expected sha256=<hex>", u.Fragment)
+		}
+		checksum = parts[1]
+		u.Fragment = ""
+	}
+
+	return u.String(), checksum, nil
+}
+
+// httpHead returns the response headers for downloadURL. Servers that don't allow HEAD requests
+// are sent a GET for the first byte instead.
+func httpHead(downloadURL string) (http.Header, error) {
+	resp, err := http.Head(downloadURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute head request")
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return resp.Header, nil
+	}
+	if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+		return nil, errors.Errorf("unexpected result from head request: %d", resp.StatusCode)
+	}
+
+	req, err := http.NewRequest("GET", downloadURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create ranged get request")
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute ranged get request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, errors.Errorf("unexpected result from ranged get request: %d", resp.StatusCode)
+	}
+
+	return resp.Header, nil
+}
+
+func httpGet(downloadURL string) (http.Header, []byte, error) {
+	resp, err := http.Get(downloadURL)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to execute get request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, errors.Errorf("unexpected result from get request: %d", resp.StatusCode)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	return resp.Header, content, nil
+}
+
+func httpCursorFromHeaders(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" {
+		return strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	}
+
+	return header.Get("Last-Modified")
+}
+
+func httpUpstreamName(fileName string) string {
+	for _, ext := range []string{".tar.gz", ".tgz", ".yaml", ".yml"} {
+		if strings.HasSuffix(fileName, ext) {
+			return strings.TrimSuffix(fileName, ext)
+		}
+	}
+
+	return fileName
+}
+
+func isGzip(content []byte) bool {
+	return len(content) > 2 && content[0] == 0x1f && content[1] == 0x8b
+}
+
+func sha256Hex(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
 }
@@ -0,0 +1,121 @@
+package upstream
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.undefinedlabs.com/scopeagent"
+)
+
+func Test_downloadHttp(t *testing.T) {
+	manifest := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc123"`)
+		w.Write(manifest)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		uri         string
+		checksum    string
+		expectError bool
+	}{
+		{
+			name: "no checksum",
+			uri:  fmt.Sprintf("%s/app.yaml", server.URL),
+		},
+		{
+			name: "checksum in fragment",
+			uri:  fmt.Sprintf("%s/app.yaml#sha256=%s", server.URL, sha256Hex(manifest)),
+		},
+		{
+			name:     "checksum option",
+			uri:      fmt.Sprintf("%s/app.yaml", server.URL),
+			checksum: sha256Hex(manifest),
+		},
+		{
+			name:        "checksum mismatch",
+			uri:         fmt.Sprintf("%s/app.yaml", server.URL),
+			checksum:    sha256Hex([]byte("other")),
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scopetest := scopeagent.StartTest(t)
+			defer scopetest.End()
+			req := require.New(t)
+
+			u, err := downloadHttp(test.uri, test.checksum)
+			if test.expectError {
+				req.Error(err)
+				return
+			}
+			req.NoError(err)
+
+			assert.Equal(t, "app", u.Name)
+			assert.Equal(t, "abc123", u.UpdateCursor)
+			req.Len(u.Files, 1)
+			assert.Equal(t, "app.yaml", u.Files[0].Path)
+			assert.Equal(t, manifest, u.Files[0].Content)
+		})
+	}
+}
+
+func Test_getUpdatesHttp(t *testing.T) {
+	manifest := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n")
+
+	tests := []struct {
+		name          string
+		allowHead     bool
+		currentCursor string
+		expect        []Update
+	}{
+		{
+			name:      "head",
+			allowHead: true,
+			expect:    []Update{{Cursor: "abc123"}},
+		},
+		{
+			name:   "ranged get when head is not allowed",
+			expect: []Update{{Cursor: "abc123"}},
+		},
+		{
+			name:          "no update",
+			currentCursor: "abc123",
+			expect:        []Update{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scopetest := scopeagent.StartTest(t)
+			defer scopetest.End()
+			req := require.New(t)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead && !test.allowHead {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				w.Header().Set("ETag", `"abc123"`)
+				http.ServeContent(w, r, "app.yaml", time.Time{}, bytes.NewReader(manifest))
+			}))
+			defer server.Close()
+
+			updates, err := getUpdatesHttp(fmt.Sprintf("%s/app.yaml", server.URL), test.currentCursor)
+			req.NoError(err)
+
+			assert.Equal(t, test.expect, updates)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package upstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	semver "github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/cursor"
+	"github.com/replicatedhq/kots/pkg/docker/registry"
+	"github.com/replicatedhq/kots/pkg/upstream/types"
+)
+
+const (
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+
+	helmChartConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+)
+
+// layer media types that contain a gzipped tar of the release or chart
+var ociArchiveMediaTypes = []string{
+	"application/gzip",
+	"application/tar+gzip",
+	"application/vnd.cncf.helm.chart.content.v1.tar+gzip",
+	"application/vnd.oci.image.layer.v1.tar+gzip",
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociTagList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+type ociReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+func getUpdatesOCI(u *url.URL, currentCursor string) ([]Update, error) {
+	ref, err := parseOCIURL(u)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse oci uri")
+	}
+
+	auth, err := getOCIPullAuthorization(ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to authenticate to registry")
+	}
+
+	tags, err := listOCISemverTags(auth, ref.Repository)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tags")
+	}
+
+	current, err := cursor.NewSemverCursor(currentCursor)
+	if err != nil {
+		// without a comparable cursor, every version is an update
+		current = nil
+	}
+
+	updates := []Update{}
+	for _, tag := range tags {
+		if current != nil {
+			c, err := cursor.NewSemverCursor(tag.Original())
+			if err != nil || !c.After(current) {
+				continue
+			}
+		}
+		updates = append(updates, Update{
+			Cursor:       tag.Original(),
+			VersionLabel: tag.Original(),
+		})
+	}
+
+	return updates, nil
+}
+
+func downloadOCI(u *url.URL) (*types.Upstream, error) {
+	ref, err := parseOCIURL(u)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse oci uri")
+	}
+
+	auth, err := getOCIPullAuthorization(ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to authenticate to registry")
+	}
+
+	if ref.Tag == "" {
+		tags, err := listOCISemverTags(auth, ref.Repository)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list tags")
+		}
+		if len(tags) == 0 {
+			return nil, errors.New("no semver tags found in repository")
+		}
+		ref.Tag = tags[len(tags)-1].Original()
+	}
+
+	manifestContent, err := auth.Get(fmt.Sprintf("%s/manifests/%s", ref.Repository, ref.Tag), ociManifestMediaType, dockerManifestMediaType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get manifest")
+	}
+
+	manifest := ociManifest{}
+	if err := json.Unmarshal(manifestContent, &manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal manifest")
+	}
+
+	var archiveLayer *ociDescriptor
+	for i, layer := range manifest.Layers {
+		if isOCIArchiveMediaType(layer.MediaType) {
+			archiveLayer = &manifest.Layers[i]
+			break
+		}
+	}
+	if archiveLayer == nil {
+		return nil, errors.Errorf("no archive layer found in %s:%s", ref.Repository, ref.Tag)
+	}
+
+	archive, err := auth.Get(fmt.Sprintf("%s/blobs/%s", ref.Repository, archiveLayer.Digest))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get archive blob")
+	}
+
+	if strings.HasPrefix(archiveLayer.Digest, "sha256:") && strings.TrimPrefix(archiveLayer.Digest, "sha256:") != sha256Hex(archive) {
+		return nil, errors.Errorf("digest mismatch for blob %s", archiveLayer.Digest)
+	}
+
+	files, err := readTarGzReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read archive")
+	}
+
+	upstreamType := "oci"
+	if manifest.Config.MediaType == helmChartConfigMediaType {
+		upstreamType = "helm"
+	}
+
+	upstream := &types.Upstream{
+		URI:          u.String(),
+		Name:         path.Base(ref.Repository),
+		Type:         upstreamType,
+		Files:        files,
+		UpdateCursor: ref.Tag,
+		VersionLabel: ref.Tag,
+	}
+
+	return upstream, nil
+}
+
+// parseOCIURL reads a uri in the form of oci://registry/repo:tag. The tag is optional,
+// and the highest semver tag is used when it's not set.
+func parseOCIURL(u *url.URL) (*ociReference, error) {
+	if u.Host == "" {
+		return nil, errors.New("oci uri is missing a registry")
+	}
+
+	repository := strings.Trim(u.Path, "/")
+	if repository == "" {
+		return nil, errors.New("oci uri is missing a repository")
+	}
+
+	tag := ""
+	lastSlash := strings.LastIndex(repository, "/")
+	if colon := strings.LastIndex(repository, ":"); colon > lastSlash {
+		tag = repository[colon+1:]
+		repository = repository[:colon]
+	}
+
+	ref := ociReference{
+		Registry:   u.Host,
+		Repository: repository,
+		Tag:        tag,
+	}
+
+	return &ref, nil
+}
+
+func getOCIPullAuthorization(ref *ociReference) (*registry.PullAuthorization, error) {
+	username, password, err := registry.LoadAuthForRegistry(ref.Registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load registry credentials")
+	}
+
+	auth, err := registry.GetPullAuthorization(ref.Registry, username, password, ref.Repository)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pull authorization")
+	}
+
+	return auth, nil
+}
+
+// listOCISemverTags returns the tags in the repository that are valid semver, lowest first
+func listOCISemverTags(auth *registry.PullAuthorization, repository string) ([]*semver.Version, error) {
+	content, err := auth.Get(fmt.Sprintf("%s/tags/list", repository))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tags")
+	}
+
+	tagList := ociTagList{}
+	if err := json.Unmarshal(content, &tagList); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal tags")
+	}
+
+	versions := []*semver.Version{}
+	for _, tag := range tagList.Tags {
+		v, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+
+	sort.Sort(semver.Collection(versions))
+
+	return versions, nil
+}
+
+func isOCIArchiveMediaType(mediaType string) bool {
+	for _, t := range ociArchiveMediaTypes {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
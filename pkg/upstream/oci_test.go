@@ -0,0 +1,60 @@
+package upstream
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.undefinedlabs.com/scopeagent"
+)
+
+func Test_parseOCIURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		expected ociReference
+	}{
+		{
+			name: "with tag",
+			uri:  "oci://registry.example.com/apps/sentry:1.2.0",
+			expected: ociReference{
+				Registry:   "registry.example.com",
+				Repository: "apps/sentry",
+				Tag:        "1.2.0",
+			},
+		},
+		{
+			name: "without tag",
+			uri:  "oci://registry.example.com/apps/sentry",
+			expected: ociReference{
+				Registry:   "registry.example.com",
+				Repository: "apps/sentry",
+			},
+		},
+		{
+			name: "registry with port",
+			uri:  "oci://localhost:5000/sentry:v2.0.0-beta.1",
+			expected: ociReference{
+				Registry:   "localhost:5000",
+				Repository: "sentry",
+				Tag:        "v2.0.0-beta.1",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scopetest := scopeagent.StartTest(t)
+			defer scopetest.End()
+			req := require.New(t)
+
+			u, err := url.ParseRequestURI(test.uri)
+			req.NoError(err)
+
+			ref, err := parseOCIURL(u)
+			req.NoError(err)
+			assert.Equal(t, test.expected, *ref)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package upstream
 
 import (
 	"net/url"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/util"
@@ -18,6 +19,11 @@ func GetUpdatesUpstream(upstreamURI string, fetchOptions *FetchOptions) ([]Updat
 		return nil, errors.Wrap(err, "download upstream failed")
 	}
 
+	versions, err = filterUpdatesByConstraint(versions, fetchOptions.VersionConstraint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to filter updates")
+	}
+
 	return versions, nil
 }
 
@@ -31,7 +37,7 @@ func getUpdatesUpstream(upstreamURI string, fetchOptions *FetchOptions) ([]Updat
 		return nil, errors.Wrap(err, "parse request uri failed")
 	}
 	if u.Scheme == "helm" {
-		return getUpdatesHelm(u, fetchOptions.HelmRepoURI)
+		return getUpdatesHelm(u, fetchOptions.HelmRepoURI, fetchOptions.CurrentCursor, helmCacheFromOptions(fetchOptions))
 	}
 	if u.Scheme == "replicated" {
 		cursor := ReplicatedCursor{
@@ -40,13 +46,14 @@ func getUpdatesUpstream(upstreamURI string, fetchOptions *FetchOptions) ([]Updat
 		}
 		return getUpdatesReplicated(u, fetchOptions.LocalPath, cursor, fetchOptions.CurrentVersionLabel, fetchOptions.License)
 	}
-	if u.Scheme == "git" {
-		// return getUpdatesGit(upstreamURI)
-		// TODO
+	if u.Scheme == "git" || strings.HasPrefix(u.Scheme, "git+") {
+		return getUpdatesGit(u, fetchOptions.CurrentCursor)
+	}
+	if u.Scheme == "oci" {
+		return getUpdatesOCI(u, fetchOptions.CurrentCursor)
 	}
 	if u.Scheme == "http" || u.Scheme == "https" {
-		// return getUpdatesHttp(upstreamURI)
-		// TODO
+		return getUpdatesHttp(upstreamURI, fetchOptions.CurrentCursor)
 	}
 
 	return nil, errors.Errorf("unknown protocol scheme %q", u.Scheme)
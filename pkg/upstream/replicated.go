@@ -494,8 +494,9 @@ func createConfigValues(applicationName string, config *kotsv1beta1.Config, exis
 	if existingConfigValues != nil {
 		for k, v := range existingConfigValues.Spec.Values {
 			templateContextValues[k] = template.ItemValue{
-				Value:   v.Value,
-				Default: v.Default,
+				Value:      v.Value,
+				Default:    v.Default,
+				MultiValue: v.MultiValue,
 			}
 		}
 		newValues = kotsv1beta1.ConfigValuesSpec{
@@ -530,6 +531,8 @@ func createConfigValues(applicationName string, config *kotsv1beta1.Config, exis
 		return nil, errors.Wrap(err, "failed to create config context")
 	}
 
+	cmdRunner := template.NewCmdRunner(builder)
+
 	for _, group := range config.Spec.Groups {
 		for _, item := range group.Items {
 			var foundValue string
@@ -548,17 +551,20 @@ func createConfigValues(applicationName string, config *kotsv1beta1.Config, exis
 				return nil, errors.Wrap(err, "failed to render config item default")
 			}
 
+			configValue := kotsv1beta1.ConfigValue{
+				Value:      renderedValue,
+				Default:    renderedDefault,
+				MultiValue: prevValue.MultiValue,
+			}
 			if foundValue != "" {
-				newValues.Values[item.Name] = kotsv1beta1.ConfigValue{
-					Value:   foundValue,
-					Default: renderedDefault,
-				}
-			} else {
-				newValues.Values[item.Name] = kotsv1beta1.ConfigValue{
-					Value:   renderedValue,
-					Default: renderedDefault,
-				}
+				configValue.Value = foundValue
 			}
+
+			if err := runConfigItemCmds(cmdRunner, item, prevValue, &configValue, cipher); err != nil {
+				return nil, errors.Wrapf(err, "failed to generate config item %s", item.Name)
+			}
+
+			newValues.Values[item.Name] = configValue
 		}
 	}
 
@@ -576,6 +582,51 @@ func createConfigValues(applicationName string, config *kotsv1beta1.Config, exis
 	return &configValues, nil
 }
 
+// runConfigItemCmds fills in the generated parts of a config item. Generators only run when there
+// is no previous value, so the result is stable once it has been saved in the config values.
+func runConfigItemCmds(cmdRunner *template.CmdRunner, item kotsv1beta1.ConfigItem, prevValue kotsv1beta1.ConfigValue, configValue *kotsv1beta1.ConfigValue, cipher *crypto.AESCipher) error {
+	if item.ValueCmd != nil {
+		if prevValue.Value != "" {
+			configValue.Value = prevValue.Value
+		} else {
+			generated, err := cmdRunner.Run(item.ValueCmd)
+			if err != nil {
+				return errors.Wrap(err, "failed to generate value")
+			}
+			if item.Type == "password" && cipher != nil {
+				generated = base64.StdEncoding.EncodeToString(cipher.Encrypt([]byte(generated)))
+			}
+			configValue.Value = generated
+		}
+	}
+
+	if item.DefaultCmd != nil {
+		if prevValue.Default != "" {
+			configValue.Default = prevValue.Default
+		} else {
+			generated, err := cmdRunner.Run(item.DefaultCmd)
+			if err != nil {
+				return errors.Wrap(err, "failed to generate default")
+			}
+			configValue.Default = generated
+		}
+	}
+
+	if item.DataCmd != nil {
+		if prevValue.Data != "" {
+			configValue.Data = prevValue.Data
+		} else {
+			generated, err := cmdRunner.Run(item.DataCmd)
+			if err != nil {
+				return errors.Wrap(err, "failed to generate data")
+			}
+			configValue.Data = generated
+		}
+	}
+
+	return nil
+}
+
 func findConfigValuesInFile(filename string) (*kotsv1beta1.ConfigValues, error) {
 	content, err := ioutil.ReadFile(filename)
 	if err != nil {